@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/pkg"
+	"github.com/vx6fid/job-crawler/trend_worker"
+)
+
+// CompanyJobsHandler lists a company's open roles. The {name} path value may be
+// any spelling of the company name.
+func (h *Handler) CompanyJobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	company, ok := h.lookupCompany(ctx, w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+
+	jobs, err := h.Store.QueryJobs(ctx, pkg.JobQuery{CompanyID: company.ID, OpenOnly: true, Limit: 500})
+	if err != nil {
+		http.Error(w, "Failed to list company jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"company": company,
+		"jobs":    jobs,
+	})
+}
+
+// CompanyHistoryHandler returns how many postings a company published per month.
+func (h *Handler) CompanyHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	company, ok := h.lookupCompany(ctx, w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+
+	jobs, err := h.Store.QueryJobs(ctx, pkg.JobQuery{CompanyID: company.ID})
+	if err != nil {
+		http.Error(w, "Failed to load company history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"company": company,
+		"history": pkg.CompanyHiringHistory(jobs),
+	})
+}
+
+// CompanyInsightsHandler reports a company's hiring: open postings month by
+// month, the roles, skills and locations of its postings, how often it
+// discloses salaries and how fast it posts. Optional query params: role,
+// source, location, since, until and limit (see trend_worker.ParseTrendQuery).
+func (h *Handler) CompanyInsightsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	q, err := trend_worker.ParseTrendQuery(r.URL.Query(), now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.Role != "" && !crawler.IsRoleAllowed(q.Role) {
+		http.Error(w, "Invalid or unsupported role", http.StatusBadRequest)
+		return
+	}
+
+	company, ok := h.lookupCompany(ctx, w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	q.Company = company.ID
+
+	err = h.serveTrend(ctx, w, r, func(ctx context.Context) (interface{}, error) {
+		insights, err := h.Analyzer.CompanyInsights(ctx, q, now)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"company":  company,
+			"insights": insights,
+		}, nil
+	})
+	if err != nil {
+		http.Error(w, "Failed to generate company insights", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) lookupCompany(ctx context.Context, w http.ResponseWriter, name string) (*pkg.Company, bool) {
+	company, err := h.Store.GetCompany(ctx, name)
+	if errors.Is(err, pkg.ErrCompanyNotFound) {
+		http.Error(w, "Company not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to look up company", http.StatusInternalServerError)
+		return nil, false
+	}
+	return company, true
+}
@@ -1,15 +1,25 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/internal/worker"
+	"github.com/vx6fid/job-crawler/pkg"
 )
 
-func CrawlHandler(w http.ResponseWriter, r *http.Request) {
+// CrawlHandler queues a crawl of each role for the crawl workers and returns
+// the tasks, so that crawls survive an API restart.
+func (h *Handler) CrawlHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
 	roles := r.URL.Query()["role"] // allows multiple ?role=dev&role=ml
 
 	var validRoles []string
@@ -25,14 +35,74 @@ func CrawlHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// run crawl in background
-	go func() {
-		_ = crawler.StartCrawling(validRoles, 50, 60*time.Second)
-	}()
+	tasks, err := worker.Enqueue(ctx, h.Store, worker.Request{Roles: validRoles, MaxJobs: crawler.DefaultMaxJobs})
+	message, status := "Crawl queued", http.StatusAccepted
+	switch {
+	case errors.Is(err, crawler.ErrAlreadyCrawling):
+		message, status = "Roles are already queued or being crawled", http.StatusOK
+	case err != nil:
+		http.Error(w, "Failed to queue crawl", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Crawling started",
+		"message": message,
 		"roles":   validRoles,
+		"tasks":   tasks,
+	})
+}
+
+// ListCrawlsHandler lists crawl tasks, newest first, optionally only those
+// with ?status=.
+func (h *Handler) ListCrawlsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", pkg.TaskQueued, pkg.TaskRunning, pkg.TaskDone, pkg.TaskFailed:
+	default:
+		http.Error(w, "status must be queued, running, done or failed", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, 500)
+	}
+
+	tasks, err := h.Store.ListCrawls(ctx, status, limit)
+	if err != nil {
+		http.Error(w, "Failed to list crawls", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"crawls": tasks,
 	})
 }
+
+func (h *Handler) GetCrawlHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	task, err := h.Store.GetCrawl(ctx, r.PathValue("id"))
+	if errors.Is(err, pkg.ErrCrawlTaskNotFound) {
+		http.Error(w, "Crawl not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load crawl", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
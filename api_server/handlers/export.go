@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/export"
+)
+
+// ExportJobsHandler streams the postings matching the filters of
+// pkg.ParseJobQuery as a download. It also takes:
+//
+//	format         csv, jsonl (default) or parquet
+//	limit          most postings to export; all of them by default
+func (h *Handler) ExportJobsHandler(w http.ResponseWriter, r *http.Request) {
+	// Large exports take a while; the client going away still stops them
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Minute)
+	defer cancel()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.JSONL
+	}
+	if err := export.CheckFormat(format); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q, ok := h.jobQuery(ctx, w, r)
+	if !ok {
+		return
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+
+	filename := fmt.Sprintf("jobs-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// Once rows are streaming the status is sent; a later failure can only
+	// cut the download short
+	n, err := export.Jobs(ctx, h.Store, q, format, w)
+	if err != nil && n == 0 {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Failed to export jobs", http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		log.Printf("[api] Export failed after %d jobs: %v", n, err)
+	}
+}
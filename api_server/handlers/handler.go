@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"github.com/vx6fid/job-crawler/internal/scheduler"
+	"github.com/vx6fid/job-crawler/pkg"
+	"github.com/vx6fid/job-crawler/trend_worker"
+)
+
+// Handler holds the dependencies shared by every API endpoint.
+type Handler struct {
+	Store    pkg.Store
+	Analyzer *trend_worker.Analyzer
+	// TrendCache serves trend results until postings change; nil computes
+	// them for every request
+	TrendCache *trend_worker.Cache
+	// Scheduler is told when schedules change; nil when none runs in
+	// this process
+	Scheduler *scheduler.Scheduler
+}
+
+func New(store pkg.Store) *Handler {
+	return &Handler{
+		Store:    store,
+		Analyzer: trend_worker.NewAnalyzer(store),
+	}
+}
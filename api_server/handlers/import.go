@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/importer"
+)
+
+// maxImportSize caps the body of an import request.
+const maxImportSize = 512 << 20
+
+// ImportJobsHandler loads postings from a JSONL or CSV body (see the importer
+// package) and reports what happened, with the errors of rejected lines. The
+// format comes from the format param, else from the Content-Type: text/csv
+// for CSV, anything else for JSONL.
+func (h *Handler) ImportJobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Minute)
+	defer cancel()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = importer.JSONL
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+			format = importer.CSV
+		}
+	}
+	if err := importer.CheckFormat(format); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := importer.Import(ctx, h.Store, http.MaxBytesReader(w, r.Body, maxImportSize), format)
+	if result.Changed() {
+		h.invalidateTrends(ctx)
+	}
+	if err != nil {
+		log.Printf("[api] Import stopped after %d lines: %v", result.Lines, err)
+		var tooLarge *http.MaxBytesError
+		status := http.StatusInternalServerError
+		switch {
+		case errors.As(err, &tooLarge):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, importer.ErrBadInput):
+			status = http.StatusBadRequest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  err.Error(),
+			"result": result,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
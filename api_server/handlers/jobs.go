@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// JobHistoryHandler shows how a posting evolved: every recorded revision with
+// the fields that changed, oldest first.
+func (h *Handler) JobHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	job, err := h.Store.GetJob(ctx, id)
+	if errors.Is(err, pkg.ErrJobNotFound) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to look up job", http.StatusInternalServerError)
+		return
+	}
+
+	revisions, err := h.Store.JobHistory(ctx, id)
+	if err != nil {
+		http.Error(w, "Failed to load job history", http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []pkg.Revision{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job":       job,
+		"revisions": revisions,
+	})
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// JobsHandler searches postings with the filters of pkg.ParseJobQuery. It also
+// takes:
+//
+//	cursor         next_cursor of the previous page
+//	limit          page size, 20 by default and at most 100
+func (h *Handler) JobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	q, ok := h.jobQuery(ctx, w, r)
+	if !ok {
+		return
+	}
+	q.Limit = defaultPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxPageSize {
+			http.Error(w, "limit must be an integer between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		cursor, err := pkg.ParseCursor(v)
+		if err != nil || cursor.Sort != sortOrDefault(q.Sort) {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		q.After = cursor
+	}
+
+	// One more than a page tells whether there is a next one
+	page := q
+	page.Limit++
+	jobs, err := h.Store.QueryJobs(ctx, page)
+	if err != nil {
+		http.Error(w, "Failed to search jobs", http.StatusInternalServerError)
+		return
+	}
+
+	var next string
+	if len(jobs) > q.Limit {
+		jobs = jobs[:q.Limit]
+		next = q.NextCursor(jobs).Encode()
+	}
+	if jobs == nil {
+		jobs = []pkg.JobPosting{}
+	}
+
+	total := q
+	total.After, total.Limit = nil, 0
+	count, err := h.Store.CountJobs(ctx, total)
+	if err != nil {
+		http.Error(w, "Failed to count jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs":        jobs,
+		"total":       count,
+		"next_cursor": next,
+	})
+}
+
+// JobHandler returns one posting by ID.
+func (h *Handler) JobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	job, err := h.Store.GetJob(ctx, r.PathValue("id"))
+	if errors.Is(err, pkg.ErrJobNotFound) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to look up job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// jobQuery reads the search filters of the request (see pkg.ParseJobQuery).
+// It writes an error and returns false when they can't be used.
+func (h *Handler) jobQuery(ctx context.Context, w http.ResponseWriter, r *http.Request) (pkg.JobQuery, bool) {
+	q, err := pkg.ParseJobQuery(ctx, h.Store, r.URL.Query())
+	if errors.Is(err, pkg.ErrInvalidQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return q, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to look up company", http.StatusInternalServerError)
+		return q, false
+	}
+	return q, true
+}
+
+func sortOrDefault(order string) string {
+	if order == "" {
+		return pkg.SortNewest
+	}
+	return order
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// ListRolesHandler lists the role taxonomy, with disabled roles unless
+// enabled=true.
+func (h *Handler) ListRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles := pkg.Roles()
+	if r.URL.Query().Get("enabled") == "true" {
+		enabled := roles[:0]
+		for _, role := range roles {
+			if role.Enabled {
+				enabled = append(enabled, role)
+			}
+		}
+		roles = enabled
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"roles": roles,
+	})
+}
+
+// CreateRoleHandler adds a role from a JSON body with slug, name and
+// optionally synonyms, titles (the name by default), skills and enabled (true
+// by default). Postings crawled from then on are classified into it.
+func (h *Handler) CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	role := pkg.Role{Enabled: true}
+	if !decodeRole(w, r, &role) {
+		return
+	}
+	if existing, ok := pkg.ResolveRole(role.Slug); ok && existing.Slug == role.Slug {
+		http.Error(w, "Role already exists", http.StatusConflict)
+		return
+	}
+	if !h.saveRole(ctx, w, role) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// UpdateRoleHandler edits a role; {"enabled": false} disables it. Fields
+// missing from the JSON body keep their current values.
+func (h *Handler) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	existing, ok := pkg.ResolveRole(r.PathValue("slug"))
+	if !ok || existing.Slug != r.PathValue("slug") {
+		http.Error(w, "Role not found", http.StatusNotFound)
+		return
+	}
+
+	role := existing
+	if !decodeRole(w, r, &role) {
+		return
+	}
+	role.Slug = existing.Slug
+	if !h.saveRole(ctx, w, role) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+// saveRole stores role if it fits the taxonomy and reloads the taxonomy. It
+// writes an error and returns false when it can't.
+func (h *Handler) saveRole(ctx context.Context, w http.ResponseWriter, role pkg.Role) bool {
+	if err := pkg.CheckRoles(pkg.MergeRoles(pkg.Roles(), []pkg.Role{role})); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := h.Store.SaveRole(ctx, role); err != nil {
+		http.Error(w, "Failed to save role", http.StatusInternalServerError)
+		return false
+	}
+	if err := pkg.LoadRoles(ctx, h.Store); err != nil {
+		http.Error(w, "Failed to reload roles", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// decodeRole reads the JSON body over role and validates the result. It
+// writes a 400 and returns false when it can't be used.
+func decodeRole(w http.ResponseWriter, r *http.Request, role *pkg.Role) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(role); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return false
+	}
+	if err := role.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
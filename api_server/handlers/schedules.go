@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/internal/crawler/sites"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// scheduleView is a schedule with its next run, before jitter.
+type scheduleView struct {
+	pkg.Schedule
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+}
+
+func viewSchedule(schedule pkg.Schedule) scheduleView {
+	view := scheduleView{Schedule: schedule}
+	if next := schedule.Next(time.Now()); schedule.Enabled && !next.IsZero() {
+		view.NextRunAt = &next
+	}
+	return view
+}
+
+// ListSchedulesHandler lists the crawl schedules with their last and next runs.
+func (h *Handler) ListSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	schedules, err := h.Store.ListSchedules(ctx)
+	if err != nil {
+		http.Error(w, "Failed to list schedules", http.StatusInternalServerError)
+		return
+	}
+	views := make([]scheduleView, 0, len(schedules))
+	for _, schedule := range schedules {
+		views = append(views, viewSchedule(schedule))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"schedules": views,
+		"sources":   sites.Sources(),
+	})
+}
+
+// GetScheduleHandler returns one schedule.
+func (h *Handler) GetScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	schedule, err := h.Store.GetSchedule(ctx, r.PathValue("id"))
+	if errors.Is(err, pkg.ErrScheduleNotFound) {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to look up schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewSchedule(*schedule))
+}
+
+// CreateScheduleHandler adds a schedule from a JSON body with name, cron,
+// roles and optionally sources, max_jobs, jitter_seconds (120 by default) and
+// enabled (true by default).
+func (h *Handler) CreateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	schedule := pkg.Schedule{JitterSeconds: pkg.DefaultScheduleJitter, Enabled: true}
+	if !decodeSchedule(w, r, &schedule) {
+		return
+	}
+
+	saved, err := h.Store.CreateSchedule(ctx, schedule)
+	if err != nil {
+		http.Error(w, "Failed to save schedule", http.StatusInternalServerError)
+		return
+	}
+	h.Scheduler.Reload()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(viewSchedule(saved))
+}
+
+// UpdateScheduleHandler edits a schedule. Fields missing from the JSON body
+// keep their current values.
+func (h *Handler) UpdateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	existing, err := h.Store.GetSchedule(ctx, r.PathValue("id"))
+	if errors.Is(err, pkg.ErrScheduleNotFound) {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to look up schedule", http.StatusInternalServerError)
+		return
+	}
+
+	schedule := *existing
+	if !decodeSchedule(w, r, &schedule) {
+		return
+	}
+	schedule.ID = existing.ID
+
+	saved, err := h.Store.UpdateSchedule(ctx, schedule)
+	if errors.Is(err, pkg.ErrScheduleNotFound) {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to save schedule", http.StatusInternalServerError)
+		return
+	}
+	h.Scheduler.Reload()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewSchedule(saved))
+}
+
+// DeleteScheduleHandler removes a schedule.
+func (h *Handler) DeleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := h.Store.DeleteSchedule(ctx, r.PathValue("id"))
+	if errors.Is(err, pkg.ErrScheduleNotFound) {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete schedule", http.StatusInternalServerError)
+		return
+	}
+	h.Scheduler.Reload()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeSchedule reads the JSON body over schedule and validates the result,
+// including that the crawler knows its roles and sources. It writes a 400 and
+// returns false when it can't be used.
+func decodeSchedule(w http.ResponseWriter, r *http.Request, schedule *pkg.Schedule) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(schedule); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return false
+	}
+	if err := schedule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	for _, role := range schedule.Roles {
+		if !crawler.IsRoleAllowed(role) {
+			http.Error(w, fmt.Sprintf("Role not allowed: %q", role), http.StatusBadRequest)
+			return false
+		}
+	}
+	if _, unknown := sites.Boards(schedule.Sources); len(unknown) > 0 {
+		http.Error(w, fmt.Sprintf("Unknown source %q; known sources are %v", unknown[0], sites.Sources()), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// ListSearchesHandler lists the saved searches.
+func (h *Handler) ListSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	searches, err := h.Store.ListSearches(ctx)
+	if err != nil {
+		http.Error(w, "Failed to list saved searches", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"searches": searches,
+	})
+}
+
+// CreateSearchHandler saves a search from a JSON body with name, any of role,
+// skills, location and salary_min, and where to send new matches: notifier
+// (webhook, email or file) and target.
+func (h *Handler) CreateSearchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var search pkg.SavedSearch
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&search); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := search.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.Store.CreateSearch(ctx, search)
+	if err != nil {
+		http.Error(w, "Failed to save search", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteSearchHandler removes a saved search.
+func (h *Handler) DeleteSearchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := h.Store.DeleteSearch(ctx, r.PathValue("id"))
+	if errors.Is(err, pkg.ErrSearchNotFound) {
+		http.Error(w, "Saved search not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete saved search", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
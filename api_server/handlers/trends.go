@@ -1,28 +1,208 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vx6fid/job-crawler/internal/crawler"
 	"github.com/vx6fid/job-crawler/trend_worker"
 )
 
-func TrendReportHandler(w http.ResponseWriter, r *http.Request) {
-	role := strings.TrimSpace(r.URL.Query().Get("role"))
+// TrendReportHandler serves the top values of each dimension over a role's
+// postings, with counts and percentages. Optional query params: source,
+// location, since, until, limit, dimensions (see trend_worker.ParseTrendQuery).
+func (h *Handler) TrendReportHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := trend_worker.ParseTrendQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if !crawler.IsRoleAllowed(role) {
+	if !crawler.IsRoleAllowed(q.Role) {
 		http.Error(w, "Invalid or unsupported role", http.StatusBadRequest)
 		return
 	}
 
-	report, err := trend_worker.AnalyzeTrendsByRole(role)
+	err = h.serveTrend(r.Context(), w, r, func(ctx context.Context) (interface{}, error) {
+		return h.Analyzer.AnalyzeTrends(ctx, q)
+	})
 	if err != nil {
 		http.Error(w, "Failed to generate trend report", http.StatusInternalServerError)
+	}
+}
+
+// CompareRolesHandler serves two or more roles side by side: top skills with
+// their share of each role's postings, shared and unique skills, location mix,
+// experience and salaries. Roles are given as repeated role params; source,
+// location, since, until and limit apply to every role.
+func (h *Handler) CompareRolesHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	roles := params["role"]
+	for _, role := range roles {
+		if role == "" || !crawler.IsRoleAllowed(role) {
+			http.Error(w, fmt.Sprintf("Invalid or unsupported role: %q", role), http.StatusBadRequest)
+			return
+		}
+	}
+	params.Del("role")
+	q, err := trend_worker.ParseTrendQuery(params, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	err = h.serveTrend(r.Context(), w, r, func(ctx context.Context) (interface{}, error) {
+		return h.Analyzer.CompareRoles(ctx, roles, q)
+	})
+	if errors.Is(err, trend_worker.ErrInvalidTrendQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to compare roles", http.StatusInternalServerError)
+	}
+}
+
+// SkillMomentumHandler serves emerging and declining skills for a role.
+// Optional query params: window_days, min_support, limit.
+func (h *Handler) SkillMomentumHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	role := strings.TrimSpace(q.Get("role"))
+
+	if !crawler.IsRoleAllowed(role) {
+		http.Error(w, "Invalid or unsupported role", http.StatusBadRequest)
+		return
+	}
+
+	opts := trend_worker.DefaultMomentumOptions()
+	if v := q.Get("window_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			http.Error(w, "window_days must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		opts.Window = time.Duration(days) * 24 * time.Hour
+	}
+	if v := q.Get("min_support"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "min_support must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		opts.MinSupport = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = n
+	}
+
+	err := h.serveTrend(r.Context(), w, r, func(ctx context.Context) (interface{}, error) {
+		return h.Analyzer.AnalyzeSkillMomentum(ctx, role, opts)
+	})
+	if err != nil {
+		http.Error(w, "Failed to generate momentum report", http.StatusInternalServerError)
+	}
+}
+
+// TimeToFillHandler serves how long a role's postings stay open, overall and
+// per company. Optional query params: company, window_days, min_closed, limit.
+func (h *Handler) TimeToFillHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	role := strings.TrimSpace(q.Get("role"))
+
+	if !crawler.IsRoleAllowed(role) {
+		http.Error(w, "Invalid or unsupported role", http.StatusBadRequest)
+		return
+	}
+
+	opts := trend_worker.DefaultTimeToFillOptions()
+	if name := strings.TrimSpace(q.Get("company")); name != "" {
+		company, ok := h.lookupCompany(r.Context(), w, name)
+		if !ok {
+			return
+		}
+		opts.CompanyID = company.ID
+	}
+	if v := q.Get("window_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			http.Error(w, "window_days must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		opts.Window = time.Duration(days) * 24 * time.Hour
+	}
+	if v := q.Get("min_closed"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "min_closed must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		opts.MinClosed = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = n
+	}
+
+	err := h.serveTrend(r.Context(), w, r, func(ctx context.Context) (interface{}, error) {
+		return h.Analyzer.AnalyzeTimeToFill(ctx, role, opts)
+	})
+	if err != nil {
+		http.Error(w, "Failed to generate time-to-fill report", http.StatusInternalServerError)
+	}
+}
+
+// serveTrend writes the JSON of what compute returns. With a trend cache it
+// is served from the cache, keyed on the request's path and query, with an
+// ETag and Last-Modified so that clients revalidate it with a 304. Errors of
+// compute are returned before anything is written.
+func (h *Handler) serveTrend(ctx context.Context, w http.ResponseWriter, r *http.Request, compute func(ctx context.Context) (interface{}, error)) error {
+	if h.TrendCache == nil {
+		v, err := compute(ctx)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+		return nil
+	}
+
+	result, err := h.TrendCache.Get(ctx, r.URL.Path+"?"+r.URL.Query().Encode(), compute)
+	if err != nil {
+		return err
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(report)
+	w.Header().Set("Cache-Control", "no-cache") // revalidate before every use
+	w.Header().Set("ETag", result.ETag)
+	http.ServeContent(w, r, "", result.ComputedAt, bytes.NewReader(result.Body))
+	return nil
+}
+
+// invalidateTrends drops cached trend results after postings changed.
+func (h *Handler) invalidateTrends(ctx context.Context) {
+	var err error
+	if h.TrendCache != nil {
+		err = h.TrendCache.Invalidate(ctx)
+	} else {
+		err = h.Store.ClearTrendResults(ctx)
+	}
+	if err != nil {
+		log.Printf("[api] Failed to clear cached trend results: %v", err)
+	}
 }
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/events"
+	"github.com/vx6fid/job-crawler/internal/webhooks"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// ListWebhooksHandler lists the webhook subscriptions, without their secrets.
+func (h *Handler) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	hooks, err := h.Store.ListWebhooks(ctx)
+	if err != nil {
+		http.Error(w, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhooks": hooks,
+	})
+}
+
+// CreateWebhookHandler subscribes a URL to events from a JSON body with url,
+// events (event types; empty means all) and an optional secret. A secret is
+// generated when none is given; the response is the only time it is shown.
+func (h *Handler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var hook pkg.Webhook
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&hook); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := hook.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, t := range hook.Events {
+		if !slices.Contains(events.Types, t) {
+			http.Error(w, fmt.Sprintf("Unknown event type %q", t), http.StatusBadRequest)
+			return
+		}
+	}
+	if hook.Secret == "" {
+		hook.Secret = webhooks.NewSecret()
+	}
+
+	saved, err := h.Store.CreateWebhook(ctx, hook)
+	if err != nil {
+		http.Error(w, "Failed to save webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteWebhookHandler removes a webhook and its delivery log.
+func (h *Handler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := h.Store.DeleteWebhook(ctx, r.PathValue("id"))
+	if errors.Is(err, pkg.ErrWebhookNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WebhookDeliveriesHandler returns a webhook's most recent delivery attempts,
+// newest first; limit defaults to 50 and is capped at 500.
+func (h *Handler) WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, 500)
+	}
+
+	id := r.PathValue("id")
+	if _, err := h.Store.GetWebhook(ctx, id); errors.Is(err, pkg.ErrWebhookNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to load webhook", http.StatusInternalServerError)
+		return
+	}
+
+	deliveries, err := h.Store.ListDeliveries(ctx, id, limit)
+	if err != nil {
+		http.Error(w, "Failed to list deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deliveries": deliveries,
+	})
+}
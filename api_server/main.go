@@ -2,29 +2,17 @@ package main
 
 import (
 	"log"
-	"net/http"
 
 	"github.com/joho/godotenv"
-	"github.com/vx6fid/job-crawler/api_server/routes"
-	"github.com/vx6fid/job-crawler/pkg"
+	"github.com/vx6fid/job-crawler/api_server/server"
 )
 
 func main() {
 
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error: ", err)
+	// .env is optional: without it the server falls back to a local SQLite store
+	if err := godotenv.Load(); err != nil {
+		log.Println("[api] No .env file loaded:", err)
 	}
 
-	if err := pkg.ConnectMongo(); err != nil {
-		log.Fatal("[mongo] MongoDB connection failed:", err)
-	}
-
-	routes.RegisterRoutes()
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("api_server/static"))))
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "api_server/templates/index.html")
-	})
-	log.Println("[api] API server running on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Fatal("[api] ", server.Run(server.Options{Addr: server.DefaultAddr, Workers: -1}))
 }
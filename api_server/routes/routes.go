@@ -6,7 +6,35 @@ import (
 	"github.com/vx6fid/job-crawler/api_server/handlers"
 )
 
-func RegisterRoutes() {
-	http.HandleFunc("/api/trends", handlers.TrendReportHandler)
-	http.HandleFunc("/api/crawl", handlers.CrawlHandler)
+func RegisterRoutes(h *handlers.Handler) {
+	http.HandleFunc("/api/trends", h.TrendReportHandler)
+	http.HandleFunc("/api/trends/compare", h.CompareRolesHandler)
+	http.HandleFunc("/api/trends/momentum", h.SkillMomentumHandler)
+	http.HandleFunc("/api/trends/time-to-fill", h.TimeToFillHandler)
+	http.HandleFunc("/api/crawl", h.CrawlHandler)
+	http.HandleFunc("GET /api/crawls", h.ListCrawlsHandler)
+	http.HandleFunc("GET /api/crawls/{id}", h.GetCrawlHandler)
+	http.HandleFunc("GET /api/roles", h.ListRolesHandler)
+	http.HandleFunc("POST /api/roles", h.CreateRoleHandler)
+	http.HandleFunc("PUT /api/roles/{slug}", h.UpdateRoleHandler)
+	http.HandleFunc("GET /api/companies/{name}/jobs", h.CompanyJobsHandler)
+	http.HandleFunc("GET /api/companies/{name}/history", h.CompanyHistoryHandler)
+	http.HandleFunc("GET /api/companies/{name}/insights", h.CompanyInsightsHandler)
+	http.HandleFunc("GET /api/jobs", h.JobsHandler)
+	http.HandleFunc("GET /api/jobs/export", h.ExportJobsHandler)
+	http.HandleFunc("POST /api/jobs/import", h.ImportJobsHandler)
+	http.HandleFunc("GET /api/jobs/{id}", h.JobHandler)
+	http.HandleFunc("GET /api/jobs/{id}/history", h.JobHistoryHandler)
+	http.HandleFunc("GET /api/searches", h.ListSearchesHandler)
+	http.HandleFunc("POST /api/searches", h.CreateSearchHandler)
+	http.HandleFunc("DELETE /api/searches/{id}", h.DeleteSearchHandler)
+	http.HandleFunc("GET /api/schedules", h.ListSchedulesHandler)
+	http.HandleFunc("POST /api/schedules", h.CreateScheduleHandler)
+	http.HandleFunc("GET /api/schedules/{id}", h.GetScheduleHandler)
+	http.HandleFunc("PUT /api/schedules/{id}", h.UpdateScheduleHandler)
+	http.HandleFunc("DELETE /api/schedules/{id}", h.DeleteScheduleHandler)
+	http.HandleFunc("GET /api/webhooks", h.ListWebhooksHandler)
+	http.HandleFunc("POST /api/webhooks", h.CreateWebhookHandler)
+	http.HandleFunc("DELETE /api/webhooks/{id}", h.DeleteWebhookHandler)
+	http.HandleFunc("GET /api/webhooks/{id}/deliveries", h.WebhookDeliveriesHandler)
 }
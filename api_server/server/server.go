@@ -0,0 +1,138 @@
+// Package server runs the API server: the job store with its event bus and
+// webhook deliveries, the crawl scheduler, in-process crawl workers, and the
+// HTTP routes and frontend.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/vx6fid/job-crawler/api_server/handlers"
+	"github.com/vx6fid/job-crawler/api_server/routes"
+	"github.com/vx6fid/job-crawler/internal/events"
+	"github.com/vx6fid/job-crawler/internal/scheduler"
+	"github.com/vx6fid/job-crawler/internal/webhooks"
+	"github.com/vx6fid/job-crawler/internal/worker"
+	"github.com/vx6fid/job-crawler/pkg"
+	"github.com/vx6fid/job-crawler/trend_worker"
+)
+
+// DefaultAddr is where the API server listens when not told otherwise.
+const DefaultAddr = ":8080"
+
+// Options configures Run.
+type Options struct {
+	Addr string
+	// Workers is how many crawl workers run in process; -1 means the
+	// CRAWL_WORKERS environment variable, or 1 when it isn't set.
+	Workers int
+}
+
+// WorkersFromEnv returns the crawl workers to run in process, from
+// CRAWL_WORKERS (default 1, 0 to leave crawling to cmd/worker).
+func WorkersFromEnv() (int, error) {
+	v := os.Getenv("CRAWL_WORKERS")
+	if v == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, errors.New("CRAWL_WORKERS must be a non-negative integer")
+	}
+	return n, nil
+}
+
+// TrendCacheFromEnv returns the cache of trend results configured by
+// TREND_CACHE: store (the default) shares them with other processes through
+// store, so that crawls run by cmd/worker clear them too, memory holds them in
+// process only, and off computes them for every request. TREND_CACHE_MAX_AGE,
+// a duration such as 10m, bounds how long a result is served when no crawl
+// invalidates it.
+func TrendCacheFromEnv(store pkg.TrendResultStore) (*trend_worker.Cache, error) {
+	var maxAge time.Duration
+	if v := os.Getenv("TREND_CACHE_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, errors.New("TREND_CACHE_MAX_AGE must be a positive duration such as 10m")
+		}
+		maxAge = d
+	}
+
+	switch os.Getenv("TREND_CACHE") {
+	case "", "store":
+		return trend_worker.NewCache(store, maxAge), nil
+	case "memory":
+		return trend_worker.NewCache(nil, maxAge), nil
+	case "off":
+		return nil, nil
+	default:
+		return nil, errors.New("TREND_CACHE must be memory, store or off")
+	}
+}
+
+// Run opens the configured store and serves the API until the listener fails.
+// The frontend is served from api_server/static and api_server/templates,
+// relative to the working directory.
+func Run(opts Options) error {
+	if opts.Addr == "" {
+		opts.Addr = DefaultAddr
+	}
+	if opts.Workers < 0 {
+		n, err := WorkersFromEnv()
+		if err != nil {
+			return err
+		}
+		opts.Workers = n
+	}
+
+	store, err := pkg.OpenStore()
+	if err != nil {
+		return fmt.Errorf("opening job store: %w", err)
+	}
+
+	// Job and crawl events go out to the subscribed webhooks
+	bus := events.NewBus()
+	dispatcher := webhooks.NewDispatcher(store)
+	dispatcher.Start(webhooks.DefaultWorkers)
+	bus.Subscribe(dispatcher.Handle)
+
+	published := events.NewStore(store, bus)
+	h := handlers.New(published)
+	if h.TrendCache, err = TrendCacheFromEnv(store); err != nil {
+		return err
+	}
+	if h.TrendCache != nil {
+		// Crawls clear the results shared through the store themselves; the
+		// ones run in process also clear what this process holds
+		bus.Subscribe(func(ev events.Event) {
+			if summary, ok := ev.Data.(events.CrawlSummary); ok && summary.Changed() {
+				if err := h.TrendCache.Invalidate(context.Background()); err != nil {
+					log.Printf("[api] Failed to clear cached trend results: %v", err)
+				}
+			}
+		})
+	}
+
+	// Scheduled crawls are queued like the ones requested through the API
+	h.Scheduler = scheduler.New(published)
+	go h.Scheduler.Run(context.Background())
+
+	// Queued crawls run on the workers of cmd/worker, and on in-process ones
+	for range opts.Workers {
+		go worker.New(published).Run(context.Background())
+	}
+
+	routes.RegisterRoutes(h)
+	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("api_server/static"))))
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "api_server/templates/index.html")
+	})
+	log.Printf("[api] API server running on %s", opts.Addr)
+	return http.ListenAndServe(opts.Addr, nil)
+}
@@ -88,7 +88,7 @@ document.addEventListener("DOMContentLoaded", () => {
 
   const updateCharts = (data) => {
     // Get top 10 skills only
-    const topSkills = data.top_skills.slice(0, 10);
+    const topSkills = data.dimensions.skills.slice(0, 10);
 
     // Skills Chart (Horizontal Bar)
     skillsChart.updateOptions({
@@ -103,10 +103,10 @@ document.addEventListener("DOMContentLoaded", () => {
     });
 
     // Experience Chart (Bar)
-    experienceChart.data.labels = data.experience_distribution.map(
+    experienceChart.data.labels = data.dimensions.experience.map(
       (e) => e.value
     );
-    experienceChart.data.datasets[0].data = data.experience_distribution.map(
+    experienceChart.data.datasets[0].data = data.dimensions.experience.map(
       (e) => e.count
     );
     experienceChart.update();
@@ -115,11 +115,11 @@ document.addEventListener("DOMContentLoaded", () => {
     locationsChart.updateOptions({
       series: [
         {
-          data: data.top_locations.map((l) => l.count),
+          data: data.dimensions.locations.map((l) => l.count),
         },
       ],
       xaxis: {
-        categories: data.top_locations.map((l) => l.value),
+        categories: data.dimensions.locations.map((l) => l.value),
       },
     });
 
@@ -127,11 +127,11 @@ document.addEventListener("DOMContentLoaded", () => {
     companiesChart.updateOptions({
       series: [
         {
-          data: data.top_companies.map((c) => c.count),
+          data: data.dimensions.companies.map((c) => c.count),
         },
       ],
       xaxis: {
-        categories: data.top_companies.map((c) => c.value),
+        categories: data.dimensions.companies.map((c) => c.value),
       },
     });
   };
@@ -141,7 +141,9 @@ document.addEventListener("DOMContentLoaded", () => {
       statusDiv.textContent = "Loading trends...";
       trendsDiv.style.display = "none";
 
-      const res = await fetch(`/api/trends?role=${encodeURIComponent(role)}`);
+      const res = await fetch(
+        `/api/trends?role=${encodeURIComponent(role)}&dimensions=skills,experience,locations,companies`
+      );
       const data = await res.json();
 
       updateCharts(data);
@@ -155,8 +157,25 @@ document.addEventListener("DOMContentLoaded", () => {
     }
   };
 
+  // The role options come from the taxonomy, so roles added through the API
+  // show up without editing the page
+  const loadRoles = async () => {
+    try {
+      const res = await fetch("/api/roles?enabled=true");
+      const data = await res.json();
+      data.roles
+        .sort((a, b) => a.name.localeCompare(b.name))
+        .forEach((role) => roleSelect.add(new Option(role.name, role.slug)));
+    } catch (err) {
+      console.error(err);
+      statusDiv.textContent = "Failed to load roles.";
+      statusDiv.style.color = "#ef233c";
+    }
+  };
+
   // Initialize empty charts on page load
   initCharts();
+  loadRoles();
 
   // On page load, show general trends
   fetchTrends("");
@@ -164,6 +183,7 @@ document.addEventListener("DOMContentLoaded", () => {
   // Crawl button handler
   crawlBtn.addEventListener("click", async () => {
     const role = roleSelect.value;
+    const name = roleSelect.selectedOptions[0].text;
     if (!role) {
       statusDiv.textContent = "Please select a role first.";
       statusDiv.style.color = "#ef233c";
@@ -171,7 +191,7 @@ document.addEventListener("DOMContentLoaded", () => {
     }
 
     // Start crawling
-    statusDiv.textContent = `Analyzing "${role}" market trends...`;
+    statusDiv.textContent = `Analyzing "${name}" market trends...`;
     statusDiv.style.color = "var(--primary)";
     countdownDiv.textContent = "This typically takes about 60 seconds...";
     trendsDiv.style.display = "none";
@@ -197,7 +217,7 @@ document.addEventListener("DOMContentLoaded", () => {
   roleSelect.addEventListener("change", () => {
     const role = roleSelect.value;
     if (role) {
-      crawlBtn.querySelector(".button-text").textContent = `Analyze ${role}`;
+      crawlBtn.querySelector(".button-text").textContent = `Analyze ${roleSelect.selectedOptions[0].text}`;
     } else {
       crawlBtn.querySelector(".button-text").textContent = "Analyze Role";
     }
@@ -0,0 +1,83 @@
+// Command export dumps postings from the configured store to a file, taking
+// the same filters as GET /api/jobs.
+//
+//	go run ./cmd/export -o jobs.parquet -skills go,aws -posted-since 2025-01-01
+//	go run ./cmd/export -format csv -q kubernetes > jobs.csv
+//
+// The format defaults to the extension of -o, or jsonl when writing to stdout.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/vx6fid/job-crawler/internal/cli"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// filters are the search parameters of pkg.ParseJobQuery, offered as flags
+// with dashes in place of underscores.
+var filters = []struct{ param, usage string }{
+	{"q", "words that must all appear in the title or description"},
+	{"skills", "comma-separated skills a posting must all require"},
+	{"any_skills", "comma-separated skills a posting must require at least one of"},
+	{"role", "slug or name of a role, e.g. backend"},
+	{"location", "city, region, country or country code"},
+	{"company", "any spelling of the company name"},
+	{"source", "job board host, e.g. weworkremotely.com"},
+	{"posted_since", "date (2006-01-02) or RFC 3339 timestamp"},
+	{"salary_min", "annual amount the salary range must reach"},
+	{"salary_max", "annual amount the salary range must start at or below"},
+	{"experience", "years of experience the posting must accept"},
+	{"status", "open, not-seen-recently or closed (closed postings are left out unless asked for)"},
+	{"sort", "newest (default), oldest, salary or relevance"},
+}
+
+func main() {
+	output := flag.String("o", "-", `file to write, "-" for stdout`)
+	format := flag.String("format", "", "csv, jsonl or parquet (default: from the -o extension, else jsonl)")
+	limit := flag.Int("limit", 0, "most postings to export (0 = all)")
+	values := make([]*string, len(filters))
+	for i, f := range filters {
+		values[i] = flag.String(strings.ReplaceAll(f.param, "_", "-"), "", f.usage)
+	}
+	flag.Parse()
+
+	exportFormat, err := cli.ExportFormat(*format, *output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_ = godotenv.Load() // optional, the environment may already be set
+
+	store, err := pkg.OpenStore()
+	if err != nil {
+		log.Fatalf("Opening job store failed: %v", err)
+	}
+	defer store.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	params := url.Values{}
+	for i, f := range filters {
+		if *values[i] != "" {
+			params.Set(f.param, *values[i])
+		}
+	}
+	q, err := pkg.ParseJobQuery(ctx, store, params)
+	if err != nil {
+		log.Fatal(err)
+	}
+	q.Limit = *limit
+
+	if err := cli.Export(ctx, store, q, exportFormat, *output, os.Stderr); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,103 @@
+// Command import loads postings from JSONL or CSV dumps into the configured
+// store, through the same normalization and dedupe as crawled postings.
+//
+//	go run ./cmd/import jobs.jsonl more.csv
+//	cat jobs.csv | go run ./cmd/import -format csv -
+//
+// The format defaults to each file's extension, or jsonl for stdin.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/vx6fid/job-crawler/internal/importer"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func main() {
+	format := flag.String("format", "", "jsonl or csv (default: from the file extension, else jsonl)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-format jsonl|csv] file... (\"-\" reads stdin)\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format != "" {
+		if err := importer.CheckFormat(*format); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	_ = godotenv.Load() // optional, the environment may already be set
+
+	store, err := pkg.OpenStore()
+	if err != nil {
+		log.Fatalf("Opening job store failed: %v", err)
+	}
+	defer store.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	failed := false
+	for _, path := range flag.Args() {
+		if !importFile(ctx, store, path, *format) {
+			failed = true
+		}
+	}
+	if failed {
+		store.Close(context.Background())
+		os.Exit(1)
+	}
+}
+
+// importFile imports one file and prints its summary and line errors. It
+// returns false if the file could not be imported completely.
+func importFile(ctx context.Context, store pkg.Store, path, format string) bool {
+	if format == "" {
+		format = importer.JSONL
+		if ext := strings.TrimPrefix(filepath.Ext(path), "."); importer.CheckFormat(ext) == nil {
+			format = ext
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Printf("Opening %s failed: %v", path, err)
+			return false
+		}
+		defer f.Close()
+		r = f
+	}
+
+	result, err := importer.Import(ctx, store, r, format)
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, e.Line, e.Error)
+	}
+	fmt.Printf("%s: %d lines, %d inserted, %d updated, %d unchanged, %d merged, %d invalid, %d failed\n",
+		path, result.Lines, result.Inserted, result.Updated, result.Unchanged, result.Merged, result.Invalid, result.Failed)
+	if result.Changed() {
+		if err := store.ClearTrendResults(ctx); err != nil {
+			log.Printf("Clearing cached trend results failed: %v", err)
+		}
+	}
+	if err != nil {
+		log.Printf("Importing %s failed: %v", path, err)
+		return false
+	}
+	return true
+}
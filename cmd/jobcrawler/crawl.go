@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/internal/crawler/sites"
+	"github.com/vx6fid/job-crawler/internal/events"
+	"github.com/vx6fid/job-crawler/internal/webhooks"
+	"github.com/vx6fid/job-crawler/internal/worker"
+)
+
+func runCrawl(args []string) error {
+	fs := newFlagSet("crawl")
+	roles := fs.String("role", "", "comma-separated roles to crawl (required)")
+	sources := fs.String("source", "", "comma-separated board hosts, e.g. weworkremotely.com (default: every board)")
+	limit := fs.Int("limit", crawler.DefaultMaxJobs, "most postings to save")
+	timeout := fs.Duration("timeout", worker.DefaultTimeout, "how long the crawl may run")
+	queue := fs.Bool("queue", false, "queue the crawl for the crawl workers instead of crawling here")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req := worker.Request{Roles: splitList(*roles), Sources: splitList(*sources), MaxJobs: *limit}
+	if len(req.Roles) == 0 {
+		return errors.New("-role is required")
+	}
+	if _, unknown := sites.Boards(req.Sources); len(unknown) > 0 {
+		return fmt.Errorf("no parser for source: %v", unknown)
+	}
+	if *limit <= 0 || *timeout <= 0 {
+		return errors.New("-limit and -timeout must be positive")
+	}
+
+	store, closeStore, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i, role := range req.Roles {
+		if req.Roles[i], err = resolveRole(ctx, store, role); err != nil {
+			return err
+		}
+	}
+
+	if *queue {
+		tasks, err := worker.Enqueue(ctx, store, req)
+		if err != nil && !errors.Is(err, crawler.ErrAlreadyCrawling) {
+			return err
+		}
+		for _, task := range tasks {
+			fmt.Fprintf(stdout, "%s\t%s\t%s\n", task.ID, task.Status, task.Role)
+		}
+		return nil
+	}
+
+	// Postings saved here publish job events to webhooks like any crawl
+	bus := events.NewBus()
+	dispatcher := webhooks.NewDispatcher(store)
+	dispatcher.Start(webhooks.DefaultWorkers)
+	bus.Subscribe(dispatcher.Handle)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		dispatcher.Close(ctx)
+	}()
+
+	return crawler.Crawl(events.NewStore(store, bus), crawler.Options{
+		Roles:   req.Roles,
+		Sources: req.Sources,
+		MaxJobs: req.MaxJobs,
+		Timeout: *timeout,
+	})
+}
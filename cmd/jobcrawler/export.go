@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/cli"
+	"github.com/vx6fid/job-crawler/pkg"
+	"github.com/vx6fid/job-crawler/trend_worker"
+)
+
+func runExport(args []string) error {
+	fs := newFlagSet("export")
+	output := fs.String("o", "-", `file to write, "-" for stdout`)
+	format := fs.String("format", "", "csv, jsonl or parquet (default: from the -o extension, else jsonl)")
+	role := fs.String("role", "", "only postings of this role")
+	source := fs.String("source", "", "only postings from this board host, e.g. weworkremotely.com")
+	since := fs.String("since", "", "only postings from this date, timestamp or age (e.g. 30d) on")
+	until := fs.String("until", "", "only postings before this date, timestamp or age")
+	text := fs.String("q", "", "words that must all appear in the title or description")
+	skills := fs.String("skills", "", "comma-separated skills a posting must all require")
+	location := fs.String("location", "", "city, region, country or country code")
+	company := fs.String("company", "", "any spelling of the company name")
+	status := fs.String("status", "", "open, not-seen-recently or closed (closed postings are left out unless asked for)")
+	limit := fs.Int("limit", 0, "most postings to export (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	exportFormat, err := cli.ExportFormat(*format, *output)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	postedSince, err := trend_worker.ParseTime(*since, now)
+	if err != nil {
+		return fmt.Errorf("-since %v", err)
+	}
+	postedUntil, err := trend_worker.ParseTime(*until, now)
+	if err != nil {
+		return fmt.Errorf("-until %v", err)
+	}
+
+	store, closeStore, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	roleSlug, err := resolveRole(ctx, store, *role)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	for param, v := range map[string]string{"q": *text, "skills": *skills, "location": *location,
+		"company": *company, "source": *source, "status": *status} {
+		if v != "" {
+			params.Set(param, v)
+		}
+	}
+	q, err := pkg.ParseJobQuery(ctx, store, params)
+	if err != nil {
+		return err
+	}
+	q.Role, q.PostedSince, q.PostedUntil, q.Limit = roleSlug, postedSince, postedUntil, *limit
+
+	return cli.Export(ctx, store, q, exportFormat, *output, os.Stderr)
+}
@@ -0,0 +1,118 @@
+// Command jobcrawler crawls boards, reports trends, exports postings, migrates
+// the store and serves the API, all against the configured store (see
+// pkg.OpenStore).
+//
+//	jobcrawler crawl -role backend -source weworkremotely.com -limit 100
+//	jobcrawler trends -role devops -since 30d -limit 10 -format markdown
+//	jobcrawler export -role backend -since 2025-01-01 -o jobs.parquet
+//	jobcrawler migrate -status
+//	jobcrawler serve -addr :8080
+//
+// Run "jobcrawler <command> -h" for the flags of a command.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+type command struct {
+	name, usage string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"crawl", "crawl roles now, or queue them for the crawl workers", runCrawl},
+	{"trends", "report the top skills, locations, companies and more", runTrends},
+	{"export", "write postings as CSV, JSON lines or Parquet", runExport},
+	{"migrate", "apply or list schema migrations", runMigrate},
+	{"serve", "run the API server and frontend", runServe},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		usage(os.Stderr)
+		if len(os.Args) < 2 {
+			os.Exit(2)
+		}
+		return
+	}
+
+	_ = godotenv.Load() // optional, the environment may already be set
+
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := c.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "jobcrawler %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "jobcrawler: unknown command %q\n\n", name)
+	usage(os.Stderr)
+	os.Exit(2)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: jobcrawler <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.usage)
+	}
+}
+
+// newFlagSet returns the flag set of a command.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("jobcrawler "+name, flag.ExitOnError)
+}
+
+// stdout is where commands write their results.
+var stdout io.Writer = os.Stdout
+
+// openStore opens the configured store, closed by the returned function.
+var openStore = func() (pkg.Store, func(), error) {
+	store, err := pkg.OpenStore()
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening job store: %w", err)
+	}
+	return store, func() { store.Close(context.Background()) }, nil
+}
+
+// resolveRole returns the slug of a role given by slug, name or synonym, after
+// loading the roles added or disabled through the API from store. An empty
+// role stands for every role and resolves to "".
+func resolveRole(ctx context.Context, store pkg.RoleStore, role string) (string, error) {
+	if role == "" {
+		return "", nil
+	}
+	if err := pkg.LoadRoles(ctx, store); err != nil {
+		return "", fmt.Errorf("load roles: %w", err)
+	}
+	if !crawler.IsRoleAllowed(role) {
+		return "", fmt.Errorf("role not allowed: %s", role)
+	}
+	return pkg.RoleSlug(role), nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(v string) []string {
+	var list []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// useStore makes the commands run against store and write to the returned
+// buffer for the rest of the test.
+func useStore(t *testing.T, store pkg.Store) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	savedOpen, savedStdout := openStore, stdout
+	openStore = func() (pkg.Store, func(), error) { return store, func() {}, nil }
+	stdout = &out
+	t.Cleanup(func() {
+		openStore, stdout = savedOpen, savedStdout
+		pkg.SetRoles(pkg.DefaultRoles)
+	})
+	return &out
+}
+
+// seededStore returns a memory store holding a backend and a data posting.
+func seededStore(t *testing.T) pkg.Store {
+	t.Helper()
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	for _, job := range []pkg.JobPosting{
+		{Title: "backend engineer", Company: "Acme", Skills: []string{"go"}, Roles: []string{"backend"}},
+		{Title: "data engineer", Company: "Initech", Skills: []string{"python"}, Roles: []string{"data-engineer"}},
+	} {
+		job.PostedOn = time.Now().Add(-24 * time.Hour)
+		job.Description = job.Title
+		job.URL = "https://example.com/jobs/" + strings.ReplaceAll(job.Title, " ", "-")
+		if _, err := store.UpsertJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestSplitList(t *testing.T) {
+	if got := splitList(" backend, ,devops,"); !reflect.DeepEqual(got, []string{"backend", "devops"}) {
+		t.Errorf("unexpected list: %q", got)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("expected no entries, got %q", got)
+	}
+}
+
+func TestResolveRole(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	useStore(t, store)
+	backend, _ := pkg.ResolveRole("backend")
+	backend.Enabled = false
+	added := pkg.Role{Slug: "developer-advocate", Name: "Developer advocate", Synonyms: []string{"DevRel"}, Enabled: true}
+	for _, role := range []pkg.Role{added, backend} {
+		if err := store.SaveRole(ctx, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for role, want := range map[string]string{"": "", "DevRel": "developer-advocate", "devops": "devops"} {
+		if got, err := resolveRole(ctx, store, role); err != nil || got != want {
+			t.Errorf("%q: expected %q, got %q (%v)", role, want, got, err)
+		}
+	}
+	for _, role := range []string{"backend", "astronaut"} {
+		if _, err := resolveRole(ctx, store, role); err == nil || !strings.Contains(err.Error(), "role not allowed") {
+			t.Errorf("%q: expected the role to be refused, got %v", role, err)
+		}
+	}
+}
+
+func TestRunCrawl(t *testing.T) {
+	store := pkg.NewMemoryStore()
+	out := useStore(t, store)
+
+	for _, args := range [][]string{
+		{},
+		{"-role", "backend", "-source", "example.com"},
+		{"-role", "backend", "-limit", "0"},
+		{"-role", "astronaut", "-queue"},
+	} {
+		if err := runCrawl(args); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+
+	if err := runCrawl([]string{"-role", "Backend, devops", "-queue"}); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := store.ListCrawls(context.Background(), "", 0)
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("expected 2 queued crawls, got %+v (%v)", tasks, err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.HasSuffix(lines[0], "\tbackend") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestRunTrends(t *testing.T) {
+	out := useStore(t, seededStore(t))
+
+	for _, args := range [][]string{
+		{"-format", "xml"},
+		{"-since", "yesterday-ish"},
+		{"-role", "astronaut"},
+	} {
+		if err := runTrends(args); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+
+	if err := runTrends([]string{"-role", "Backend", "-dimensions", "skills", "-format", "csv"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "dimension,value,count,percent\nskills,go,1,100.0\n"; out.String() != want {
+		t.Errorf("expected the skills of the backend posting only, got %q", out.String())
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	useStore(t, seededStore(t))
+	dir := t.TempDir()
+
+	for _, args := range [][]string{
+		{"-format", "xml"},
+		{"-since", "yesterday-ish"},
+		{"-role", "astronaut"},
+	} {
+		if err := runExport(append(args, "-o", filepath.Join(dir, "bad.jsonl"))); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+
+	output := filepath.Join(dir, "jobs.csv")
+	if err := runExport([]string{"-role", "Backend", "-since", "7d", "-o", output}); err != nil {
+		t.Fatal(err)
+	}
+	body, err := os.ReadFile(output)
+	if err != nil || strings.Count(string(body), "\n") != 2 || !strings.Contains(string(body), "backend engineer") {
+		t.Errorf("expected a header and the backend posting, got %q (%v)", body, err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/cli"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func runMigrate(args []string) error {
+	fs := newFlagSet("migrate")
+	dryRun := fs.Bool("dry-run", false, "print pending migrations and their steps without applying them")
+	status := fs.Bool("status", false, "list every migration and whether it has been applied")
+	to := fs.Int("to", 0, "apply migrations up to and including this version (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := pkg.OpenStoreUnmigrated()
+	if err != nil {
+		return fmt.Errorf("opening job store: %w", err)
+	}
+	defer store.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	return cli.Migrate(ctx, store, pkg.MigrateOptions{DryRun: *dryRun, To: *to}, *status, os.Stdout)
+}
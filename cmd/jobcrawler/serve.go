@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/vx6fid/job-crawler/api_server/server"
+)
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", server.DefaultAddr, "address to listen on")
+	workers := fs.Int("workers", -1, "crawl workers to run in process (default: CRAWL_WORKERS, else 1)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return server.Run(server.Options{Addr: *addr, Workers: *workers})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vx6fid/job-crawler/trend_worker"
+)
+
+func runTrends(args []string) error {
+	fs := newFlagSet("trends")
+	role := fs.String("role", "", "role to report on (default: every posting)")
+	source := fs.String("source", "", "only postings from this board host, e.g. weworkremotely.com")
+	location := fs.String("location", "", "only postings in this city, region, country or country code")
+	since := fs.String("since", "", "only postings from this date, timestamp or age (e.g. 30d) on")
+	until := fs.String("until", "", "only postings before this date, timestamp or age")
+	limit := fs.Int("limit", trend_worker.DefaultTopN, "values listed per dimension")
+	dimensions := fs.String("dimensions", strings.Join(trend_worker.DefaultDimensions, ","),
+		"comma-separated dimensions, of "+strings.Join(trend_worker.DimensionNames(), ", "))
+	format := fs.String("format", trend_worker.FormatTable, "output format: "+strings.Join(trend_worker.Formats, ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := trend_worker.CheckFormat(*format); err != nil {
+		return err
+	}
+	q, err := trend_worker.ParseTrendQuery(url.Values{
+		"role":       {*role},
+		"source":     {*source},
+		"location":   {*location},
+		"since":      {*since},
+		"until":      {*until},
+		"limit":      {strconv.Itoa(*limit)},
+		"dimensions": {*dimensions},
+	}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	store, closeStore, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if q.Role, err = resolveRole(ctx, store, q.Role); err != nil {
+		return err
+	}
+	report, err := trend_worker.NewAnalyzer(store).AnalyzeTrends(ctx, q)
+	if err != nil {
+		return fmt.Errorf("trend analysis: %w", err)
+	}
+	return trend_worker.WriteReport(stdout, report, *format)
+}
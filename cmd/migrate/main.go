@@ -0,0 +1,40 @@
+// Command migrate applies pending schema migrations to the configured store.
+//
+//	go run ./cmd/migrate            apply every pending migration
+//	go run ./cmd/migrate -dry-run   show what would be done
+//	go run ./cmd/migrate -status    list migrations and when they were applied
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/vx6fid/job-crawler/internal/cli"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print pending migrations and their steps without applying them")
+	status := flag.Bool("status", false, "list every migration and whether it has been applied")
+	to := flag.Int("to", 0, "apply migrations up to and including this version (0 = all)")
+	flag.Parse()
+
+	_ = godotenv.Load() // optional, the environment may already be set
+
+	store, err := pkg.OpenStoreUnmigrated()
+	if err != nil {
+		log.Fatalf("Opening job store failed: %v", err)
+	}
+	defer store.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if err := cli.Migrate(ctx, store, pkg.MigrateOptions{DryRun: *dryRun, To: *to}, *status, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,76 @@
+// Command worker runs crawls queued in the configured store, by the API or the
+// scheduler. Run as many as needed, on any machine that reaches the store: each
+// task goes to one worker, and the task of a worker that dies is taken over
+// once its lease runs out.
+//
+//	go run ./cmd/worker -concurrency 4
+//
+// On SIGINT or SIGTERM a worker stops leasing and finishes its crawls first.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/vx6fid/job-crawler/internal/events"
+	"github.com/vx6fid/job-crawler/internal/webhooks"
+	"github.com/vx6fid/job-crawler/internal/worker"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func main() {
+	concurrency := flag.Int("concurrency", 1, "crawls to run at once")
+	lease := flag.Duration("lease", worker.DefaultLease, "how long a task stays leased without a heartbeat")
+	poll := flag.Duration("poll", worker.DefaultPoll, "wait between polls of an empty queue")
+	flag.Parse()
+	if *concurrency <= 0 || *lease <= 0 || *poll <= 0 {
+		log.Fatal("[worker] -concurrency, -lease and -poll must be positive")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("[worker] No .env file loaded:", err)
+	}
+
+	store, err := pkg.OpenStore()
+	if err != nil {
+		log.Fatal("[worker] Opening job store failed:", err)
+	}
+
+	// Crawls publish job and crawl events, delivered to webhooks from here
+	bus := events.NewBus()
+	dispatcher := webhooks.NewDispatcher(store)
+	dispatcher.Start(webhooks.DefaultWorkers)
+	bus.Subscribe(dispatcher.Handle)
+	published := events.NewStore(store, bus)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+	for range *concurrency {
+		w := worker.New(published)
+		w.Lease, w.Poll = *lease, *poll
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Run(ctx)
+		}()
+	}
+	<-ctx.Done()
+	log.Println("[worker] Shutting down, waiting for crawls in progress")
+	wg.Wait()
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dispatcher.Close(shutdown)
+	if err := store.Close(shutdown); err != nil {
+		log.Println("[worker] Closing job store failed:", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package alerts delivers the postings that match saved searches.
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// Notifier delivers the new matches of a saved search to search.Target.
+type Notifier interface {
+	Notify(ctx context.Context, search pkg.SavedSearch, jobs []pkg.JobPosting) error
+}
+
+// Alerter evaluates saved searches against newly inserted postings.
+type Alerter struct {
+	Searches  pkg.SearchStore
+	Notifiers map[string]Notifier // by pkg.SavedSearch.Notifier
+	Now       func() time.Time
+}
+
+// NewFromEnv returns an Alerter with the webhook and file notifiers, and the
+// email notifier when SMTP_ADDR (host:port) is set. SMTP_FROM is the sender
+// and SMTP_USERNAME / SMTP_PASSWORD, if set, authenticate with PLAIN.
+func NewFromEnv(searches pkg.SearchStore) *Alerter {
+	a := &Alerter{
+		Searches: searches,
+		Notifiers: map[string]Notifier{
+			pkg.NotifyWebhook: NewWebhookNotifier(),
+			pkg.NotifyFile:    NewFileNotifier(os.Stdout),
+		},
+		Now: time.Now,
+	}
+
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		email := &EmailNotifier{Addr: addr, From: os.Getenv("SMTP_FROM")}
+		if user := os.Getenv("SMTP_USERNAME"); user != "" {
+			host, _, _ := net.SplitHostPort(addr)
+			email.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		a.Notifiers[pkg.NotifyEmail] = email
+	}
+	return a
+}
+
+// Evaluate sends every saved search the postings of jobs it matches and
+// returns how many searches were notified. A failing notifier does not stop
+// the others; their errors are joined.
+func (a *Alerter) Evaluate(ctx context.Context, jobs []pkg.JobPosting) (int, error) {
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+	searches, err := a.Searches.ListSearches(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list saved searches: %w", err)
+	}
+
+	now := a.Now()
+	notified := 0
+	var errs []error
+	for _, search := range searches {
+		q := search.Query()
+		var matches []pkg.JobPosting
+		for _, job := range jobs {
+			if q.Matches(job, now) {
+				matches = append(matches, job)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		notifier, ok := a.Notifiers[search.Notifier]
+		if !ok {
+			errs = append(errs, fmt.Errorf("saved search %s: notifier %q is not configured", search.ID, search.Notifier))
+			continue
+		}
+		if err := notifier.Notify(ctx, search, matches); err != nil {
+			errs = append(errs, fmt.Errorf("saved search %s: %w", search.ID, err))
+			continue
+		}
+		log.Printf("[alerts] Sent %d new matches of %q via %s", len(matches), search.Name, search.Notifier)
+		notified++
+	}
+	return notified, errors.Join(errs...)
+}
+
+// Notification is what the webhook and file notifiers deliver.
+type Notification struct {
+	Search pkg.SavedSearch  `json:"search"`
+	Jobs   []pkg.JobPosting `json:"jobs"`
+	SentAt time.Time        `json:"sent_at"`
+}
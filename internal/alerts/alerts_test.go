@@ -0,0 +1,154 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// fakeSMTP accepts one session on a local port and sends the message it
+// receives on the returned channel.
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		tp := textproto.NewConn(conn)
+		defer tp.Close()
+
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line + " ")[0]); verb {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "DATA":
+				tp.PrintfLine("354 end with .")
+				data, _ := tp.ReadDotBytes()
+				received <- string(data)
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+
+	var jobs []pkg.JobPosting
+	for _, j := range []pkg.JobPosting{
+		{Title: "Backend Engineer", Company: "Acme", Skills: []string{"golang", "postgres"}, Salary: "$120k - $150k", Location: "Berlin, Germany"},
+		{Title: "Frontend Engineer", Company: "Globex", Skills: []string{"react"}, Location: "Remote, Canada"},
+	} {
+		j.PostedOn = time.Now()
+		j.URL = "https://example.com/" + strings.ToLower(j.Company)
+		res, err := store.UpsertJob(ctx, j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, _ := store.GetJob(ctx, res.ID)
+		jobs = append(jobs, *stored)
+	}
+
+	var hooked Notification
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&hooked)
+	}))
+	defer hook.Close()
+	smtpAddr, mails := fakeSMTP(t)
+	file := filepath.Join(t.TempDir(), "alerts.jsonl")
+	var stdout bytes.Buffer
+
+	for _, s := range []pkg.SavedSearch{
+		{Name: "go jobs", Skills: []string{"go"}, Notifier: pkg.NotifyWebhook, Target: hook.URL},
+		{Name: "canada", Location: "canada", Notifier: pkg.NotifyEmail, Target: "me@example.com"},
+		{Name: "well paid", SalaryMin: 140000, Notifier: pkg.NotifyFile, Target: file},
+		{Name: "frontend", Role: "Frontend developer", Notifier: pkg.NotifyFile, Target: "-"},
+		{Name: "rust", Skills: []string{"rust"}, Notifier: pkg.NotifyWebhook, Target: hook.URL},
+	} {
+		if err := s.Validate(); err != nil {
+			t.Fatalf("%s: %v", s.Name, err)
+		}
+		store.CreateSearch(ctx, s)
+	}
+
+	alerter := &Alerter{
+		Searches: store,
+		Notifiers: map[string]Notifier{
+			pkg.NotifyWebhook: NewWebhookNotifier(),
+			pkg.NotifyEmail:   &EmailNotifier{Addr: smtpAddr, From: "alerts@example.com"},
+			pkg.NotifyFile:    NewFileNotifier(&stdout),
+		},
+		Now: time.Now,
+	}
+	n, err := alerter.Evaluate(ctx, jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("expected 4 searches notified, got %d", n)
+	}
+
+	if hooked.Search.Name != "go jobs" || len(hooked.Jobs) != 1 || hooked.Jobs[0].Title != "Backend Engineer" {
+		t.Errorf("unexpected webhook payload: %+v", hooked)
+	}
+	select {
+	case mail := <-mails:
+		if !strings.Contains(mail, "Subject: 1 new jobs for \"canada\"") || !strings.Contains(mail, "Frontend Engineer at Globex") {
+			t.Errorf("unexpected mail:\n%s", mail)
+		}
+	case <-time.After(time.Second):
+		t.Error("no mail received")
+	}
+	if data, err := os.ReadFile(file); err != nil || !strings.Contains(string(data), `"title":"Backend Engineer"`) {
+		t.Errorf("unexpected alert file: %s (%v)", data, err)
+	}
+	var printed Notification
+	if err := json.Unmarshal(stdout.Bytes(), &printed); err != nil || len(printed.Jobs) != 1 || printed.Jobs[0].Title != "Frontend Engineer" {
+		t.Errorf("expected the frontend engineer on stdout, got %s (%v)", stdout.String(), err)
+	}
+}
+
+func TestEvaluateReportsFailures(t *testing.T) {
+	t.Setenv("SMTP_ADDR", "")
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	store.CreateSearch(ctx, pkg.SavedSearch{Name: "down", Role: "data-engineer", Notifier: pkg.NotifyWebhook, Target: "http://127.0.0.1:1/hook"})
+	store.CreateSearch(ctx, pkg.SavedSearch{Name: "no smtp", Role: "data-engineer", Notifier: pkg.NotifyEmail, Target: "me@example.com"})
+
+	job := pkg.JobPosting{Title: "Data Engineer", Roles: []string{"data-engineer"}, Status: pkg.StatusOpen, ExpireAt: time.Now().Add(time.Hour)}
+	n, err := NewFromEnv(store).Evaluate(ctx, []pkg.JobPosting{job})
+	if n != 0 || err == nil || !strings.Contains(err.Error(), `notifier "email" is not configured`) {
+		t.Errorf("expected both searches to fail, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,50 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// EmailNotifier mails a plain-text list of the matches to the search's target
+// address through an SMTP relay.
+type EmailNotifier struct {
+	Addr string    // host:port of the relay
+	From string    // sender address
+	Auth smtp.Auth // nil for relays that accept unauthenticated mail
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, search pkg.SavedSearch, jobs []pkg.JobPosting) error {
+	// net/smtp has no context support; give up before dialing if already done
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(n.Addr, n.Auth, n.From, []string{search.Target}, n.message(search, jobs))
+}
+
+func (n *EmailNotifier) message(search pkg.SavedSearch, jobs []pkg.JobPosting) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.From)
+	fmt.Fprintf(&b, "To: %s\r\n", search.Target)
+	fmt.Fprintf(&b, "Subject: %d new jobs for %q\r\n", len(jobs), search.Name)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	for _, job := range jobs {
+		fmt.Fprintf(&b, "%s at %s", job.Title, job.Company)
+		if job.Location != "" {
+			fmt.Fprintf(&b, " (%s)", job.Location)
+		}
+		b.WriteString("\r\n")
+		if job.Salary != "" {
+			fmt.Fprintf(&b, "%s\r\n", job.Salary)
+		}
+		fmt.Fprintf(&b, "%s\r\n\r\n", job.URL)
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,49 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// FileNotifier appends a Notification as a JSON line to the search's target
+// file, or writes it to Stdout when the target is "-".
+type FileNotifier struct {
+	Stdout io.Writer
+
+	mu sync.Mutex // keeps lines from concurrent notifications whole
+}
+
+func NewFileNotifier(stdout io.Writer) *FileNotifier {
+	return &FileNotifier{Stdout: stdout}
+}
+
+func (n *FileNotifier) Notify(_ context.Context, search pkg.SavedSearch, jobs []pkg.JobPosting) error {
+	line, err := json.Marshal(Notification{Search: search, Jobs: jobs, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if search.Target == "-" {
+		_, err := n.Stdout.Write(line)
+		return err
+	}
+	f, err := os.OpenFile(search.Target, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
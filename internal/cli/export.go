@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vx6fid/job-crawler/internal/export"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// ExportFormat checks format, defaulting it to the extension of output, or
+// to JSON lines when output is "-" or has no known extension.
+func ExportFormat(format, output string) (string, error) {
+	if format == "" {
+		format = export.JSONL
+		if ext := strings.TrimPrefix(filepath.Ext(output), "."); output != "-" && export.CheckFormat(ext) == nil {
+			format = ext
+		}
+	}
+	if err := export.CheckFormat(format); err != nil {
+		return "", err
+	}
+	return format, nil
+}
+
+// Export writes the postings q selects to output, "-" for stdout, and reports
+// how many it wrote to log. A file left incomplete by an error is removed.
+func Export(ctx context.Context, store pkg.JobStore, q pkg.JobQuery, format, output string, log io.Writer) (err error) {
+	var w io.Writer = os.Stdout
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		w = f
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("writing %s: %w", output, cerr)
+			}
+			if err != nil {
+				os.Remove(output)
+			}
+		}()
+	}
+
+	n, err := export.Jobs(ctx, store, q, format, w)
+	if err != nil {
+		return fmt.Errorf("export failed after %d jobs: %w", n, err)
+	}
+	fmt.Fprintf(log, "Exported %d jobs", n)
+	if output != "-" {
+		fmt.Fprintf(log, " to %s", output)
+	}
+	fmt.Fprintln(log, ".")
+	return nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestExportFormat(t *testing.T) {
+	cases := []struct{ format, output, want string }{
+		{"", "-", "jsonl"},
+		{"", "jobs.parquet", "parquet"},
+		{"", "jobs.txt", "jsonl"},
+		{"csv", "jobs.parquet", "csv"},
+	}
+	for _, c := range cases {
+		if got, err := ExportFormat(c.format, c.output); err != nil || got != c.want {
+			t.Errorf("%q, %q: expected %q, got %q (%v)", c.format, c.output, c.want, got, err)
+		}
+	}
+	if _, err := ExportFormat("xml", "-"); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	for _, title := range []string{"backend engineer", "data engineer"} {
+		job := pkg.JobPosting{Title: title, Company: "Acme", Location: "Berlin, Germany", PostedOn: time.Now(),
+			Description: title, URL: "https://example.com/jobs/" + strings.ReplaceAll(title, " ", "-")}
+		if _, err := store.UpsertJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := filepath.Join(t.TempDir(), "jobs.csv")
+	var log bytes.Buffer
+	if err := Export(ctx, store, pkg.JobQuery{}, "csv", output, &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.String() != "Exported 2 jobs to "+output+".\n" {
+		t.Errorf("unexpected report: %q", log.String())
+	}
+	body, err := os.ReadFile(output)
+	if err != nil || strings.Count(string(body), "\n") != 3 {
+		t.Errorf("expected a header and 2 rows, got %q (%v)", body, err)
+	}
+}
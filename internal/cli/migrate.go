@@ -0,0 +1,63 @@
+// Package cli holds the commands shared by the jobcrawler binary and the
+// standalone migrate and export commands, so that both behave the same.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// Migrate applies the pending schema migrations of store that opts selects
+// and reports each one with its steps to w. With status set it lists every
+// migration and when it was applied instead.
+func Migrate(ctx context.Context, store pkg.Store, opts pkg.MigrateOptions, status bool, w io.Writer) error {
+	migrator, ok := store.(pkg.Migrator)
+	if !ok {
+		fmt.Fprintln(w, "This store has no schema to migrate.")
+		return nil
+	}
+
+	if status {
+		migrations, err := migrator.MigrationStatus(ctx)
+		if err != nil {
+			return fmt.Errorf("reading migration status: %w", err)
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tAPPLIED\tDESCRIPTION")
+		for _, m := range migrations {
+			applied := "pending"
+			if m.AppliedAt != nil {
+				applied = m.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", m.Version, applied, m.Description)
+		}
+		return tw.Flush()
+	}
+
+	results, err := migrator.Migrate(ctx, opts)
+	for _, r := range results {
+		verb := "Applied"
+		if !r.Applied {
+			verb = "Would apply"
+		}
+		fmt.Fprintf(w, "%s %d: %s\n", verb, r.Version, r.Description)
+		for _, step := range r.Steps {
+			fmt.Fprintf(w, "    - %s\n", step)
+		}
+		if len(r.Steps) == 0 {
+			fmt.Fprintln(w, "    - nothing to change")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(w, "Nothing to migrate.")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := Migrate(ctx, pkg.NewMemoryStore(), pkg.MigrateOptions{}, false, &out); err != nil || out.String() != "This store has no schema to migrate.\n" {
+		t.Errorf("unexpected output for the memory store: %q (%v)", out.String(), err)
+	}
+
+	store, err := pkg.NewSQLiteStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	out.Reset()
+	if err := Migrate(ctx, store, pkg.MigrateOptions{}, false, &out); err != nil || out.String() != "Nothing to migrate.\n" {
+		t.Errorf("unexpected output for a migrated store: %q (%v)", out.String(), err)
+	}
+
+	out.Reset()
+	if err := Migrate(ctx, store, pkg.MigrateOptions{}, true, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !strings.HasPrefix(lines[0], "VERSION") || len(lines) < 2 || strings.Contains(out.String(), "pending") {
+		t.Errorf("unexpected status:\n%s", out.String())
+	}
+}
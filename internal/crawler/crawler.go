@@ -2,44 +2,119 @@ package crawler
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
-	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gocolly/colly/v2"
+	"github.com/vx6fid/job-crawler/internal/alerts"
 	"github.com/vx6fid/job-crawler/internal/crawler/sites"
 	"github.com/vx6fid/job-crawler/internal/downloader"
+	"github.com/vx6fid/job-crawler/internal/events"
 	"github.com/vx6fid/job-crawler/internal/urlfrontier"
 	"github.com/vx6fid/job-crawler/pkg"
 )
 
-func StartCrawling(roles []string, maxJobs int, timeout time.Duration) error {
+// recheckLimit caps how many not-seen-recently postings per role are fetched
+// again to find out whether they were taken down.
+const recheckLimit = 10
 
-	// Initialization Section
-	if err := pkg.ConnectMongo(); err != nil {
-		return fmt.Errorf("MongoDB connection failed: %v", err)
-	}
+// DefaultMaxJobs is how many postings a crawl saves when not told otherwise.
+const DefaultMaxJobs = 50
+
+// ErrAlreadyCrawling is returned by Crawl when every role it was given is
+// being crawled already.
+var ErrAlreadyCrawling = errors.New("roles are already being crawled")
+
+// Options selects what a crawl covers.
+type Options struct {
+	Roles   []string
+	Sources []string      // board hosts (see sites.Sources); empty means every board
+	MaxJobs int           // 0 means DefaultMaxJobs
+	Timeout time.Duration // how long the crawl may fetch; 0 means no limit
+}
+
+// StartCrawling crawls the given roles on every board and saves every parsed
+// posting to store (see Crawl).
+func StartCrawling(store pkg.Store, roles []string, maxJobs int, timeout time.Duration) error {
+	return Crawl(store, Options{Roles: roles, MaxJobs: maxJobs, Timeout: timeout})
+}
 
+// Crawl crawls the roles of opts on its boards and saves every parsed posting
+// to store. Postings found in listings are marked seen, postings whose page is
+// gone are closed, and postings of a crawled role that dropped out of its
+// listing move along the lifecycle (see pkg.LifecyclePolicy). A role that is
+// already being crawled is left out, so no role is crawled twice at once, and
+// so is a role that is unknown or disabled.
+func Crawl(store pkg.Store, opts Options) error {
+
+	// Initialization Section
 	start := time.Now()
 	jobCounter := 0
+	gone := 0 // postings closed because their page is gone
+	maxJobs := opts.MaxJobs
+	if maxJobs <= 0 {
+		maxJobs = DefaultMaxJobs
+	}
 
-	frontier := urlfrontier.NewFrontier(100)
-	d := downloader.NewDownloader()
+	boards, unknown := sites.Boards(opts.Sources)
+	for _, source := range unknown {
+		log.Printf("--- [ERROR] --- No parser for source: %s", source)
+	}
 
-	// Role Validation and Search URL Construction Section
-	for _, role := range roles {
+	// Pick up roles added or disabled through the API since the store was opened
+	rolesCtx, cancelRoles := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := pkg.LoadRoles(rolesCtx, store); err != nil {
+		log.Printf("--- [ERROR] --- Failed to reload roles: %v", err)
+	}
+	cancelRoles()
+
+	var allowed []string
+	for _, role := range opts.Roles {
 		if !IsRoleAllowed(role) {
 			log.Printf("--- [ERROR] --- Role not allowed: %s", role)
 			continue
 		}
-		searchURL := fmt.Sprintf("https://weworkremotely.com/remote-jobs/search?term=%s", url.QueryEscape(role))
-		frontier.Add(urlfrontier.CrawlTask{
-			URL:  searchURL,
-			Type: "listing",
-		})
+		allowed = append(allowed, pkg.RoleSlug(role)) // a role's slug and name claim the same crawl
+	}
+	roles := claimRoles(allowed)
+	defer releaseRoles(roles)
+	if len(roles) < len(allowed) {
+		log.Printf("--- :| --- Skipping roles already being crawled: %v", missing(allowed, roles))
+	}
+	if len(roles) == 0 && len(allowed) > 0 {
+		return ErrAlreadyCrawling
+	}
+
+	frontier := urlfrontier.NewFrontier(100)
+	d := downloader.NewDownloader()
+	writer := pkg.NewBulkWriter(store, pkg.DefaultBatchSize)
+
+	// Search URL Construction Section
+	for _, role := range roles {
+		for _, board := range boards {
+			frontier.Add(urlfrontier.CrawlTask{
+				URL:  board.SearchURL(searchTerm(role)),
+				Type: "listing",
+				Meta: map[string]string{"role": role},
+			})
+		}
+		enqueueRechecks(store, frontier, role, opts.Sources)
 	}
 
+	// Listings that were crawled, as the sweep scope for each: postings of that
+	// role from that site which the listing no longer shows
+	var sweeps []pkg.JobQuery
+	var mu sync.Mutex // guards sweeps and jobCounter, which parser callbacks update
+
+	crawlCtx, cancelCrawl := context.Background(), context.CancelFunc(func() {})
+	if opts.Timeout > 0 {
+		crawlCtx, cancelCrawl = context.WithTimeout(crawlCtx, opts.Timeout)
+	}
+	defer cancelCrawl()
+
 	tick := time.NewTicker(5 * time.Second) // Fires every 5 seconds to log progress
 	defer tick.Stop()
 
@@ -48,22 +123,29 @@ func StartCrawling(roles []string, maxJobs int, timeout time.Duration) error {
 	// Main Crawling Loop Section
 	for frontier.QueueSize() > 0 {
 		// Non-blocking progress logging, if tick has fired then log else continue
+		mu.Lock()
+		count := jobCounter
+		mu.Unlock()
 		select {
 		case <-tick.C:
-			log.Printf("[DEBUG] Queue Size: %d, Job Count: %d", frontier.QueueSize(), jobCounter)
+			log.Printf("[DEBUG] Queue Size: %d, Job Count: %d", frontier.QueueSize(), count)
 		default:
 		}
 
-		if jobCounter >= maxJobs {
+		if count >= maxJobs {
 			log.Printf("--- :| --- Reached job limit (%d). Stopping crawl.", maxJobs)
 			break
 		}
+		if crawlCtx.Err() != nil {
+			log.Printf("--- :| --- Reached crawl timeout (%s). Stopping crawl.", opts.Timeout)
+			break
+		}
 
 		task := frontier.GetNext()
 		log.Printf("Crawling: %s [%s]", task.URL, task.Type)
 
 		// Create short-lived context for this specific task
-		ctx, cancel := context.WithTimeout(context.Background(), perTaskTimeout)
+		ctx, cancel := context.WithTimeout(crawlCtx, perTaskTimeout)
 
 		parser := sites.GetParser(task.URL)
 		if parser == nil {
@@ -80,6 +162,21 @@ func StartCrawling(roles []string, maxJobs int, timeout time.Duration) error {
 					log.Printf("--- [ERROR] --- Parser error: %v", err)
 					return
 				}
+				if len(jobs) == 0 {
+					return // nothing to compare against; don't treat every posting as delisted
+				}
+
+				listed := make([]string, 0, len(jobs))
+				for _, job := range jobs {
+					listed = append(listed, job.ApplyURL)
+				}
+				if _, err := store.MarkSeen(ctx, listed, time.Now()); err != nil {
+					log.Printf("--- [ERROR] --- Failed to mark listed jobs seen: %v", err)
+				}
+				mu.Lock()
+				sweeps = append(sweeps, pkg.JobQuery{Role: task.Meta["role"], Source: e.Request.URL.Hostname()})
+				mu.Unlock()
+
 				for _, job := range jobs {
 					frontier.Add(urlfrontier.CrawlTask{
 						URL:  job.ApplyURL,
@@ -101,14 +198,23 @@ func StartCrawling(roles []string, maxJobs int, timeout time.Duration) error {
 					log.Printf("--- [ERROR] --- Job parser error: %v", err)
 					return
 				}
-				if err := pkg.UpsertJob(job); err != nil {
-					log.Printf("--- [ERROR] --- Failed to save job: %v", err)
+				if err := writer.Add(ctx, job); err != nil {
+					log.Printf("--- [ERROR] --- Failed to save jobs: %v", err)
 				} else {
-					log.Printf("--- :) --- Saved job: %s @ %s", job.Title, job.Company)
+					log.Printf("--- :) --- Queued job: %s @ %s", job.Title, job.Company)
+					mu.Lock()
 					jobCounter++
+					mu.Unlock()
 				}
 			})
-			if err != nil {
+			if downloader.IsGone(err) {
+				if n, err := store.CloseJobs(ctx, []string{task.URL}, pkg.ClosedGone, time.Now()); err != nil {
+					log.Printf("--- [ERROR] --- Failed to close job: %v", err)
+				} else {
+					gone += n
+					log.Printf("--- :| --- Job is gone, closed: %s", task.URL)
+				}
+			} else if err != nil {
 				log.Printf("--- [ERROR] --- Fetch error: %v", err)
 			}
 		}
@@ -117,6 +223,177 @@ func StartCrawling(roles []string, maxJobs int, timeout time.Duration) error {
 		cancel()
 	}
 
-	log.Printf("Crawler finished. Total jobs saved: %d | Duration: %.2fs", jobCounter, time.Since(start).Seconds())
+	flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if _, err := writer.Flush(flushCtx); err != nil {
+		log.Printf("--- [ERROR] --- Failed to save jobs: %v", err)
+	}
+	cancel()
+	totals := writer.Totals()
+	for _, itemErr := range totals.Errors {
+		log.Printf("--- [ERROR] --- Failed to save job %s: %v", itemErr.URL, itemErr.Err)
+	}
+
+	// Sweep after the flush so postings saved by this crawl count as seen
+	swept := sweepLifecycle(store, sweeps)
+	notifySavedSearches(store, writer.InsertedIDs())
+
+	log.Printf("Crawler finished. Jobs inserted: %d, updated: %d, unchanged: %d, merged: %d, failed: %d | Duration: %.2fs",
+		totals.Inserted, totals.Updated, totals.Unchanged, totals.Merged, totals.Failed, time.Since(start).Seconds())
+	summary := events.CrawlSummary{
+		Roles:     roles,
+		Sources:   opts.Sources,
+		StartedAt: start,
+		Duration:  time.Since(start).Seconds(),
+		Inserted:  totals.Inserted,
+		Updated:   totals.Updated,
+		Unchanged: totals.Unchanged,
+		Merged:    totals.Merged,
+		Failed:    totals.Failed,
+		NotSeen:   swept.NotSeen,
+		Closed:    swept.Closed + gone,
+	}
+
+	// Trend results shared through the store are stale for every process now
+	if summary.Changed() {
+		clearCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := store.ClearTrendResults(clearCtx); err != nil {
+			log.Printf("--- [ERROR] --- Failed to clear cached trend results: %v", err)
+		}
+		cancel()
+	}
+	events.BusOf(store).Publish(events.CrawlFinished, summary)
 	return nil
 }
+
+// enqueueRechecks queues a role's not-seen-recently postings from the given
+// boards (all when there are none) so that a 404 closes them and a successful
+// fetch reopens them.
+func enqueueRechecks(store pkg.Store, frontier *urlfrontier.Frontier, role string, sources []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if len(sources) == 0 {
+		sources = []string{""}
+	}
+	var stale []pkg.JobPosting
+	for _, source := range sources {
+		jobs, err := store.QueryJobs(ctx, pkg.JobQuery{Role: role, Source: source, Status: pkg.StatusNotSeen, Limit: recheckLimit})
+		if err != nil {
+			log.Printf("--- [ERROR] --- Failed to load postings to recheck: %v", err)
+			return
+		}
+		stale = append(stale, jobs...)
+	}
+	for _, job := range stale {
+		if job.URL == "" {
+			continue
+		}
+		frontier.Add(urlfrontier.CrawlTask{
+			URL:  job.URL,
+			Type: "job",
+			Meta: map[string]string{"recheck": job.ID},
+		})
+	}
+}
+
+// notifySavedSearches sends the postings this crawl inserted to the saved
+// searches they match.
+func notifySavedSearches(store pkg.Store, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	jobs := make([]pkg.JobPosting, 0, len(ids))
+	for _, id := range ids {
+		job, err := store.GetJob(ctx, id)
+		if err != nil {
+			log.Printf("--- [ERROR] --- Failed to load new job %s: %v", id, err)
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+
+	n, err := alerts.NewFromEnv(store).Evaluate(ctx, jobs)
+	if err != nil {
+		log.Printf("--- [ERROR] --- Saved search notifications failed: %v", err)
+	}
+	log.Printf("Saved searches notified of new jobs: %d", n)
+}
+
+// sweepLifecycle sweeps each crawled listing and returns the total.
+func sweepLifecycle(store pkg.Store, sweeps []pkg.JobQuery) pkg.LifecycleSweep {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var total pkg.LifecycleSweep
+	for _, q := range sweeps {
+		sweep, err := store.SweepLifecycle(ctx, q, pkg.DefaultLifecyclePolicy(), time.Now())
+		if err != nil {
+			log.Printf("--- [ERROR] --- Lifecycle sweep failed for %q: %v", q.Role, err)
+			continue
+		}
+		log.Printf("Lifecycle sweep for %q on %s: %d not seen recently, %d closed", q.Role, q.Source, sweep.NotSeen, sweep.Closed)
+		total.NotSeen += sweep.NotSeen
+		total.Closed += sweep.Closed
+	}
+	return total
+}
+
+var (
+	runningMu sync.Mutex
+	running   = map[string]bool{} // lowercased roles being crawled
+)
+
+// claimRoles marks the roles that are not being crawled as running and
+// returns them.
+func claimRoles(roles []string) []string {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+
+	var claimed []string
+	for _, role := range roles {
+		key := strings.ToLower(role)
+		if running[key] {
+			continue
+		}
+		running[key] = true
+		claimed = append(claimed, role)
+	}
+	return claimed
+}
+
+func releaseRoles(roles []string) {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+
+	for _, role := range roles {
+		delete(running, strings.ToLower(role))
+	}
+}
+
+// IsCrawling reports whether role is being crawled right now.
+func IsCrawling(role string) bool {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	return running[strings.ToLower(role)]
+}
+
+// missing returns the roles of all that are not in some.
+func missing(all, some []string) []string {
+	var out []string
+	for _, role := range all {
+		found := false
+		for _, r := range some {
+			if r == role {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, role)
+		}
+	}
+	return out
+}
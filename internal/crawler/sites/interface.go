@@ -2,6 +2,7 @@ package sites
 
 import (
 	"context"
+	"strings"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/vx6fid/job-crawler/pkg"
@@ -20,7 +21,30 @@ var knownTechnologies = []string{
 	"celery", "airflow", "snowflake", "bigquery", "redshift", "zipkin", "jaeger",
 }
 
+// ExtractSkills returns the known technologies a job description mentions,
+// lowercased.
+func ExtractSkills(description string) []string {
+	var skills []string
+	lowerDesc := strings.ToLower(description)
+	for _, keyword := range knownTechnologies {
+		if strings.Contains(lowerDesc, strings.ToLower(keyword)) {
+			skills = append(skills, strings.ToLower(keyword))
+		}
+	}
+	return skills
+}
+
+// CleanTitle trims and lowercases a job title the way postings are stored, so
+// that every path into the store hashes the same posting the same way.
+func CleanTitle(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
 type SiteParser interface {
+	// Source is the board's host, e.g. weworkremotely.com.
+	Source() string
+	// SearchURL is the board's listing of postings for role.
+	SearchURL(role string) string
 	Matches(url string) bool
 	Parse(ctx context.Context, e *colly.HTMLElement) ([]pkg.JobPosting, error)
 	ParseJobDescription(e *colly.HTMLElement) (pkg.JobPosting, error)
@@ -1,5 +1,7 @@
 package sites
 
+import "strings"
+
 var parsers []SiteParser
 
 func Register(p SiteParser) {
@@ -14,3 +16,34 @@ func GetParser(url string) SiteParser {
 	}
 	return nil
 }
+
+// Sources lists the hosts of every registered board.
+func Sources() []string {
+	sources := make([]string, 0, len(parsers))
+	for _, p := range parsers {
+		sources = append(sources, p.Source())
+	}
+	return sources
+}
+
+// Boards returns the parsers of the given hosts, or every parser when there
+// are none, and the hosts no parser handles.
+func Boards(sources []string) (boards []SiteParser, unknown []string) {
+	if len(sources) == 0 {
+		return parsers, nil
+	}
+	for _, source := range sources {
+		found := false
+		for _, p := range parsers {
+			if strings.EqualFold(p.Source(), source) {
+				boards = append(boards, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, source)
+		}
+	}
+	return boards, unknown
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"regexp"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -23,6 +23,14 @@ func (p *WeWorkRemotelyParser) Matches(url string) bool {
 	return strings.Contains(url, "weworkremotely.com")
 }
 
+func (p *WeWorkRemotelyParser) Source() string {
+	return "weworkremotely.com"
+}
+
+func (p *WeWorkRemotelyParser) SearchURL(role string) string {
+	return fmt.Sprintf("https://weworkremotely.com/remote-jobs/search?term=%s", url.QueryEscape(role))
+}
+
 func (p *WeWorkRemotelyParser) Parse(ctx context.Context, e *colly.HTMLElement) ([]pkg.JobPosting, error) {
 	log.Println("[STEP] Parse started")
 
@@ -54,8 +62,8 @@ func (p *WeWorkRemotelyParser) ParseJobDescription(e *colly.HTMLElement) (pkg.Jo
 	log.Printf("[STEP] ParseJobDescription started for: %s", e.Request.URL.String())
 
 	job := pkg.JobPosting{
-		Title:       strings.ToLower(strings.TrimSpace(e.ChildText("h2.lis-container__header__hero__company-info__title"))),
-		Company:     strings.ToLower(strings.TrimSpace(e.ChildText("div.lis-container__header__hero__company-info__description strong"))),
+		Title:       CleanTitle(e.ChildText("h2.lis-container__header__hero__company-info__title")),
+		Company:     strings.TrimSpace(e.ChildText("div.lis-container__header__hero__company-info__description strong")), // canonicalized by pkg.ResolveCompany
 		URL:         e.Request.URL.String(),
 		Source:      "weworkremotely.com",
 		Description: strings.TrimSpace(e.ChildText("div.lis-container__job__content__description")),
@@ -90,6 +98,7 @@ func (p *WeWorkRemotelyParser) ParseJobDescription(e *colly.HTMLElement) (pkg.Jo
 		}
 	})
 	job.Location = strings.TrimSuffix(location, ", ")
+	pkg.NormalizeLocation(&job)
 
 	// Skills from sidebar
 	skillsSet := make(map[string]struct{})
@@ -101,11 +110,8 @@ func (p *WeWorkRemotelyParser) ParseJobDescription(e *colly.HTMLElement) (pkg.Jo
 	})
 
 	// Skills from description (keyword match)
-	lowerDesc := strings.ToLower(job.Description)
-	for _, keyword := range knownTechnologies {
-		if strings.Contains(lowerDesc, strings.ToLower(keyword)) {
-			skillsSet[keyword] = struct{}{}
-		}
+	for _, keyword := range ExtractSkills(job.Description) {
+		skillsSet[keyword] = struct{}{}
 	}
 
 	// Deduplicate and sort skills
@@ -114,18 +120,9 @@ func (p *WeWorkRemotelyParser) ParseJobDescription(e *colly.HTMLElement) (pkg.Jo
 	}
 	sort.Strings(job.Skills)
 
-	// Extract experience from description
-	job.Experience = extractExperience(job.Description)
+	// Experience range, seniority and employment type
+	pkg.ClassifyJob(&job)
 
 	log.Printf("[STEP] -> [weworkremotely] Parsed job: %s at %s", job.Title, job.Company)
 	return job, nil
 }
-
-func extractExperience(desc string) string {
-	re := regexp.MustCompile(`\b(\d{1,2})\+?\s*(years|yrs?)\b`)
-	match := re.FindStringSubmatch(strings.ToLower(desc))
-	if len(match) > 1 {
-		return fmt.Sprintf("%s years", match[1])
-	}
-	return ""
-}
@@ -2,8 +2,10 @@ package downloader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 
 	"github.com/gocolly/colly/v2"
 )
@@ -12,6 +14,24 @@ type Downloader struct {
 	collector *colly.Collector
 }
 
+// StatusError is returned when a page answers with an HTTP error status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: HTTP %d", e.URL, e.StatusCode)
+}
+
+// IsGone reports whether err means the page no longer exists (404 or 410),
+// which for a posting means it was taken down.
+func IsGone(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone)
+}
+
 func NewDownloader() *Downloader {
 	c := colly.NewCollector(
 		colly.Async(true),
@@ -20,40 +40,41 @@ func NewDownloader() *Downloader {
 	return &Downloader{collector: c}
 }
 
+// FetchWithParser fetches url and calls parseFunc on its body. It returns once
+// no callback of the fetch is running any more, also when ctx ends first.
 func (d *Downloader) FetchWithParser(ctx context.Context, url string, parseFunc func(e *colly.HTMLElement)) error {
-	done := make(chan struct{})
-	var resultErr error
-
-	// Register the parsing handler
-	d.collector.OnHTML("body", parseFunc)
-
-	// Mark crawl finished
-	d.collector.OnScraped(func(_ *colly.Response) {
-		select {
-		case <-done:
-			// already closed due to error
-		default:
-			close(done)
+	// A fresh collector per fetch, so handlers of earlier fetches don't run again
+	c := d.collector.Clone()
+	c.Context = ctx // cancels the request in flight when ctx ends
+	c.OnHTML("body", parseFunc)
+
+	var fetchErr error
+	c.OnError(func(r *colly.Response, err error) {
+		if r != nil && r.StatusCode >= 400 {
+			fetchErr = &StatusError{URL: url, StatusCode: r.StatusCode}
+			return
 		}
+		fetchErr = err
 	})
 
 	// Start crawl in background
+	done := make(chan error, 1)
 	go func() {
-		resultErr = d.collector.Visit(url)
-		d.collector.Wait()
-		select {
-		case <-done:
-		default:
-			close(done)
+		err := c.Visit(url)
+		c.Wait()
+		if err == nil {
+			err = fetchErr
 		}
+		done <- err
 	}()
 
 	// Timeout or success
 	select {
 	case <-ctx.Done():
 		log.Printf("--- [TIMEOUT] --- Fetch cancelled for: %s", url)
+		<-done // a response that already arrived may still be parsing
 		return fmt.Errorf("fetch timeout: %w", ctx.Err())
-	case <-done:
-		return resultErr
+	case err := <-done:
+		return err
 	}
 }
@@ -0,0 +1,95 @@
+// Package events carries what happens to postings and crawls to whoever
+// subscribed, such as outgoing webhooks.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Event types.
+const (
+	JobCreated    = "job.created"    // Data: the stored pkg.JobPosting
+	JobUpdated    = "job.updated"    // Data: the stored pkg.JobPosting, after a material change
+	JobClosed     = "job.closed"     // Data: the stored pkg.JobPosting, now closed
+	CrawlFinished = "crawl.finished" // Data: CrawlSummary
+)
+
+// Types lists every event type, in the order they are documented.
+var Types = []string{JobCreated, JobUpdated, JobClosed, CrawlFinished}
+
+// Event is one thing that happened. ID is unique, so receivers can tell a
+// redelivery from a new event.
+type Event struct {
+	ID   string      `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// CrawlSummary is the data of a crawl.finished event.
+type CrawlSummary struct {
+	Roles     []string  `json:"roles"`
+	Sources   []string  `json:"sources,omitempty"` // boards crawled; empty means every board
+	StartedAt time.Time `json:"started_at"`
+	Duration  float64   `json:"duration_seconds"`
+	Inserted  int       `json:"inserted"`
+	Updated   int       `json:"updated"`
+	Unchanged int       `json:"unchanged"`
+	Merged    int       `json:"merged"`
+	Failed    int       `json:"failed"`
+	NotSeen   int       `json:"not_seen"` // postings the lifecycle sweep marked not-seen-recently
+	Closed    int       `json:"closed"`   // postings closed as gone or delisted
+}
+
+// Changed reports whether the crawl changed any stored posting.
+func (s CrawlSummary) Changed() bool {
+	return s.Inserted+s.Updated+s.Merged+s.NotSeen+s.Closed > 0
+}
+
+// Bus fans events out to its subscribers. Subscribers run synchronously on
+// the publishing goroutine and must hand slow work off.
+type Bus struct {
+	mu   sync.RWMutex
+	subs []func(Event)
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers fn for every event published from now on.
+func (b *Bus) Subscribe(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, fn)
+}
+
+// Active reports whether anyone listens, so publishers can skip building
+// events nobody receives.
+func (b *Bus) Active() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs) > 0
+}
+
+// Publish sends an event of type eventType to every subscriber. A nil Bus
+// drops it.
+func (b *Bus) Publish(eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+	ev := Event{ID: bson.NewObjectID().Hex(), Type: eventType, Time: time.Now(), Data: data}
+
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	for _, fn := range subs {
+		fn(ev)
+	}
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// Store is a pkg.Store that publishes job events for the writes it passes on:
+// job.created and job.updated from upserts, job.closed from CloseJobs and
+// lifecycle sweeps.
+type Store struct {
+	pkg.Store
+	Bus *Bus
+}
+
+func NewStore(store pkg.Store, bus *Bus) *Store {
+	return &Store{Store: store, Bus: bus}
+}
+
+// BusOf returns the bus store publishes on, or nil if it doesn't publish.
+func BusOf(store pkg.Store) *Bus {
+	if s, ok := store.(*Store); ok {
+		return s.Bus
+	}
+	return nil
+}
+
+func (s *Store) UpsertJob(ctx context.Context, job pkg.JobPosting) (pkg.UpsertResult, error) {
+	res, err := s.Store.UpsertJob(ctx, job)
+	if err == nil {
+		s.publishUpserts(ctx, []pkg.UpsertResult{res})
+	}
+	return res, err
+}
+
+func (s *Store) UpsertJobs(ctx context.Context, jobs []pkg.JobPosting) (pkg.BatchResult, error) {
+	result, err := s.Store.UpsertJobs(ctx, jobs)
+	if err == nil {
+		s.publishUpserts(ctx, result.Results)
+	}
+	return result, err
+}
+
+func (s *Store) CloseJobs(ctx context.Context, urls []string, reason string, at time.Time) (int, error) {
+	if !s.Bus.Active() {
+		return s.Store.CloseJobs(ctx, urls, reason, at)
+	}
+
+	// Only postings that are still open get a job.closed event
+	open, err := s.Store.QueryJobs(ctx, pkg.JobQuery{URLs: urls})
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.Store.CloseJobs(ctx, urls, reason, at)
+	if err != nil {
+		return n, err
+	}
+	for _, job := range open {
+		if pkg.JobStatus(job) != pkg.StatusClosed {
+			s.publishJob(ctx, JobClosed, job.ID)
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) SweepLifecycle(ctx context.Context, q pkg.JobQuery, policy pkg.LifecyclePolicy, now time.Time) (pkg.LifecycleSweep, error) {
+	sweep, err := s.Store.SweepLifecycle(ctx, q, policy, now)
+	if err == nil && s.Bus.Active() {
+		for _, id := range sweep.ClosedIDs {
+			s.publishJob(ctx, JobClosed, id)
+		}
+	}
+	return sweep, err
+}
+
+func (s *Store) publishUpserts(ctx context.Context, results []pkg.UpsertResult) {
+	if !s.Bus.Active() {
+		return
+	}
+	for _, res := range results {
+		switch res.Action {
+		case pkg.UpsertInserted:
+			s.publishJob(ctx, JobCreated, res.ID)
+		case pkg.UpsertUpdated:
+			s.publishJob(ctx, JobUpdated, res.ID)
+		}
+	}
+}
+
+// publishJob publishes the stored version of the posting, with every derived
+// field filled in.
+func (s *Store) publishJob(ctx context.Context, eventType, id string) {
+	job, err := s.Store.GetJob(ctx, id)
+	if err != nil {
+		log.Printf("[events] Failed to load job %s for %s: %v", id, eventType, err)
+		return
+	}
+	s.Bus.Publish(eventType, job)
+}
@@ -0,0 +1,241 @@
+// Package export writes postings as CSV, JSON lines or Parquet for notebooks
+// and spreadsheets. Postings are read from the store a page at a time and
+// written as they arrive, so an export never holds the whole result.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// Formats.
+const (
+	CSV     = "csv"     // one row per posting, skills joined with ";"
+	JSONL   = "jsonl"   // one JSON posting per line, every field
+	Parquet = "parquet" // the CSV columns, typed, with skills as a list
+)
+
+// Formats lists every format.
+var Formats = []string{CSV, JSONL, Parquet}
+
+// pageSize is how many postings are read from the store at once.
+var pageSize = 500
+
+// rowGroupSize bounds how many rows the Parquet writer buffers.
+const rowGroupSize = 10_000
+
+// CheckFormat validates a format name.
+func CheckFormat(format string) error {
+	switch format {
+	case CSV, JSONL, Parquet:
+		return nil
+	}
+	return fmt.Errorf("format must be %s", strings.Join(Formats, ", "))
+}
+
+// ContentType is the media type of an export in format.
+func ContentType(format string) string {
+	switch format {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case JSONL:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// Row is the flat form of a posting in CSV and Parquet exports.
+type Row struct {
+	ID             string     `parquet:"id"`
+	Title          string     `parquet:"title"`
+	Company        string     `parquet:"company"`
+	CompanyID      string     `parquet:"company_id"`
+	Location       string     `parquet:"location"`
+	RemotePolicy   string     `parquet:"remote_policy"`
+	Source         string     `parquet:"source"`
+	URL            string     `parquet:"url"`
+	ApplyURL       string     `parquet:"apply_url"`
+	PostedOn       time.Time  `parquet:"posted_on,timestamp(millisecond)"`
+	Status         string     `parquet:"status"`
+	LastSeenAt     time.Time  `parquet:"last_seen_at,timestamp(millisecond)"`
+	ClosedAt       *time.Time `parquet:"closed_at,optional"`
+	Salary         string     `parquet:"salary"`
+	SalaryMin      int64      `parquet:"salary_min"`
+	SalaryMax      int64      `parquet:"salary_max"`
+	SalaryCurrency string     `parquet:"salary_currency"`
+	Seniority      string     `parquet:"seniority"`
+	EmploymentType string     `parquet:"employment_type"`
+	ExperienceMin  *int64     `parquet:"experience_min,optional"`
+	ExperienceMax  *int64     `parquet:"experience_max,optional"`
+	Skills         []string   `parquet:"skills,list"`
+	Description    string     `parquet:"description,zstd"`
+}
+
+var csvHeader = []string{
+	"id", "title", "company", "company_id", "location", "remote_policy", "source", "url", "apply_url",
+	"posted_on", "status", "last_seen_at", "closed_at", "salary", "salary_min", "salary_max",
+	"salary_currency", "seniority", "employment_type", "experience_min", "experience_max", "skills",
+	"description",
+}
+
+// NewRow flattens a posting.
+func NewRow(job pkg.JobPosting) Row {
+	row := Row{
+		ID:             job.ID,
+		Title:          job.Title,
+		Company:        job.Company,
+		CompanyID:      job.CompanyID,
+		Location:       job.Location,
+		RemotePolicy:   job.RemotePolicy,
+		Source:         job.Source,
+		URL:            job.URL,
+		ApplyURL:       job.ApplyURL,
+		PostedOn:       job.PostedOn,
+		Status:         pkg.JobStatus(job),
+		LastSeenAt:     job.LastSeenAt,
+		ClosedAt:       job.ClosedAt,
+		Salary:         job.Salary,
+		SalaryMin:      int64(job.SalaryMin),
+		SalaryMax:      int64(job.SalaryMax),
+		SalaryCurrency: job.SalaryCurrency,
+		Seniority:      job.Seniority,
+		EmploymentType: job.EmploymentType,
+		Skills:         job.Skills,
+		Description:    job.Description,
+	}
+	if r := job.ExperienceRange; r != nil {
+		lo, hi := int64(r.Min), int64(r.Max)
+		row.ExperienceMin = &lo
+		if hi > 0 {
+			row.ExperienceMax = &hi
+		}
+	}
+	return row
+}
+
+func (r Row) record() []string {
+	optional := func(n *int64) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.FormatInt(*n, 10)
+	}
+	timestamp := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	closedAt := ""
+	if r.ClosedAt != nil {
+		closedAt = timestamp(*r.ClosedAt)
+	}
+	return []string{
+		r.ID, r.Title, r.Company, r.CompanyID, r.Location, r.RemotePolicy, r.Source, r.URL, r.ApplyURL,
+		timestamp(r.PostedOn), r.Status, timestamp(r.LastSeenAt), closedAt, r.Salary,
+		strconv.FormatInt(r.SalaryMin, 10), strconv.FormatInt(r.SalaryMax, 10), r.SalaryCurrency,
+		r.Seniority, r.EmploymentType, optional(r.ExperienceMin), optional(r.ExperienceMax),
+		strings.Join(r.Skills, ";"), r.Description,
+	}
+}
+
+// Jobs writes the postings matching q to w in format, in q's order, and
+// returns how many it wrote. A q.Limit above 0 caps the count. Parquet needs
+// nothing but a plain io.Writer; its footer is written last.
+func Jobs(ctx context.Context, store pkg.JobStore, q pkg.JobQuery, format string, w io.Writer) (int, error) {
+	if err := CheckFormat(format); err != nil {
+		return 0, err
+	}
+	out := newWriter(format, w)
+
+	limit := q.Limit
+	written := 0
+	for limit <= 0 || written < limit {
+		page := q
+		page.Limit = pageSize
+		if limit > 0 {
+			page.Limit = min(pageSize, limit-written)
+		}
+		jobs, err := store.QueryJobs(ctx, page)
+		if err != nil {
+			return written, err
+		}
+		for _, job := range jobs {
+			if err := out.write(job); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if len(jobs) < page.Limit {
+			break
+		}
+		next := page.NextCursor(jobs)
+		q.After = &next
+	}
+	return written, out.close()
+}
+
+type writer interface {
+	write(job pkg.JobPosting) error
+	close() error
+}
+
+func newWriter(format string, w io.Writer) writer {
+	switch format {
+	case CSV:
+		return &csvWriter{w: csv.NewWriter(w)}
+	case JSONL:
+		return jsonlWriter{enc: json.NewEncoder(w)}
+	default:
+		return parquetWriter{w: parquet.NewGenericWriter[Row](w, parquet.MaxRowsPerRowGroup(rowGroupSize))}
+	}
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) write(job pkg.JobPosting) error {
+	if !c.header {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	return c.w.Write(NewRow(job).record())
+}
+
+func (c *csvWriter) close() error {
+	if !c.header {
+		// An empty export still says what its columns are
+		c.w.Write(csvHeader)
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter struct{ enc *json.Encoder }
+
+func (j jsonlWriter) write(job pkg.JobPosting) error { return j.enc.Encode(job) }
+
+func (j jsonlWriter) close() error { return nil }
+
+type parquetWriter struct{ w *parquet.GenericWriter[Row] }
+
+func (p parquetWriter) write(job pkg.JobPosting) error {
+	_, err := p.w.Write([]Row{NewRow(job)})
+	return err
+}
+
+func (p parquetWriter) close() error { return p.w.Close() }
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func seed(t *testing.T) *pkg.MemoryStore {
+	t.Helper()
+	store := pkg.NewMemoryStore()
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		job := pkg.JobPosting{
+			Title:       fmt.Sprintf("Go Engineer %d", i),
+			Company:     "Acme",
+			Location:    "Berlin, Germany",
+			Salary:      "€60k - €80k",
+			PostedOn:    base.Add(time.Duration(i) * 24 * time.Hour),
+			Description: "Build services in Go, with a comma, and \"quotes\".",
+			URL:         fmt.Sprintf("https://example.com/jobs/%d", i),
+			Source:      "example.com",
+			Skills:      []string{"go", "postgres"},
+		}
+		if i == 4 {
+			job.Skills = []string{"react"}
+		}
+		if _, err := store.UpsertJob(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestJobsFormats(t *testing.T) {
+	ctx := context.Background()
+	store := seed(t)
+	pageSize = 2 // make the export span several pages
+	defer func() { pageSize = 500 }()
+	q := pkg.JobQuery{SkillsAll: []string{"go"}, OpenOnly: true}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		n, err := Jobs(ctx, store, q, CSV, &buf)
+		if err != nil || n != 4 {
+			t.Fatalf("wrote %d (%v), want 4", n, err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 5 || records[0][1] != "title" {
+			t.Fatalf("want a header and 4 rows, got %v", records)
+		}
+		// Newest first by default
+		row := records[1]
+		if row[1] != "Go Engineer 3" || row[9] != "2025-03-04T00:00:00Z" || row[14] != "60000" || row[16] != "EUR" || row[21] != "go;postgresql" {
+			t.Errorf("unexpected row %q", row)
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		var buf bytes.Buffer
+		if n, err := Jobs(ctx, store, q, JSONL, &buf); err != nil || n != 4 {
+			t.Fatalf("wrote %d (%v), want 4", n, err)
+		}
+		var titles []string
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var job pkg.JobPosting
+			if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+				t.Fatal(err)
+			}
+			titles = append(titles, job.Title)
+		}
+		if len(titles) != 4 || titles[3] != "Go Engineer 0" {
+			t.Errorf("unexpected postings %v", titles)
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		var buf bytes.Buffer
+		if n, err := Jobs(ctx, store, q, Parquet, &buf); err != nil || n != 4 {
+			t.Fatalf("wrote %d (%v), want 4", n, err)
+		}
+		rows, err := parquet.Read[Row](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 4 || rows[0].Title != "Go Engineer 3" || rows[0].SalaryMax != 80000 || len(rows[0].Skills) != 2 || !rows[0].PostedOn.Equal(time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected rows %+v", rows)
+		}
+	})
+}
+
+func TestJobsLimit(t *testing.T) {
+	store := seed(t)
+	pageSize = 2
+	defer func() { pageSize = 500 }()
+
+	var buf bytes.Buffer
+	n, err := Jobs(context.Background(), store, pkg.JobQuery{Limit: 3}, JSONL, &buf)
+	if err != nil || n != 3 || bytes.Count(buf.Bytes(), []byte("\n")) != 3 {
+		t.Errorf("wrote %d (%v), want 3", n, err)
+	}
+	if _, err := Jobs(context.Background(), store, pkg.JobQuery{}, "xlsx", &buf); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
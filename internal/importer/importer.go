@@ -0,0 +1,277 @@
+// Package importer loads postings from JSONL or CSV dumps, such as those made
+// by the export package, for seeding a database or backfilling from existing
+// datasets. Imported postings go through the crawler's normalization and the
+// store's usual upsert and dedupe path.
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler/sites"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// Formats.
+const (
+	JSONL = "jsonl" // one pkg.JobPosting per line, in its JSON shape
+	CSV   = "csv"   // a header row naming pkg.JobPosting JSON fields; skills separated by ";" or ","
+)
+
+// ErrBadInput matches the errors of Import for input it can't read, such as a
+// CSV without a header or a line that is too long.
+var ErrBadInput = errors.New("unreadable input")
+
+// maxErrors caps the line errors kept in a Result; later ones are only counted.
+const maxErrors = 1000
+
+// maxLine is the longest JSONL line accepted.
+const maxLine = 16 << 20
+
+// CheckFormat validates a format name.
+func CheckFormat(format string) error {
+	if format != JSONL && format != CSV {
+		return fmt.Errorf("format must be %s or %s", JSONL, CSV)
+	}
+	return nil
+}
+
+// Result counts what an import did. Invalid lines were never sent to the
+// store; Failed ones were rejected by it.
+type Result struct {
+	Lines     int         `json:"lines"`
+	Inserted  int         `json:"inserted"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Merged    int         `json:"merged"`
+	Invalid   int         `json:"invalid"`
+	Failed    int         `json:"failed"`
+	Errors    []LineError `json:"errors,omitempty"` // the first 1000
+}
+
+// Changed reports whether the import changed any stored posting.
+func (r Result) Changed() bool {
+	return r.Inserted+r.Updated+r.Merged > 0
+}
+
+// LineError is why the posting on a line was not imported. Line numbers start
+// at 1 and count the CSV header.
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func (r *Result) lineError(line int, err error) {
+	if len(r.Errors) < maxErrors {
+		r.Errors = append(r.Errors, LineError{Line: line, Error: err.Error()})
+	}
+}
+
+// Import reads postings in format from r and upserts them in batches of
+// pkg.DefaultBatchSize. Bad lines are reported in the result and skipped. The
+// error is for input that can't be read on (matching ErrBadInput) or a batch
+// the store failed to write; the result still counts what came before it.
+func Import(ctx context.Context, store pkg.BatchStore, r io.Reader, format string) (Result, error) {
+	if err := CheckFormat(format); err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	var batch []pkg.JobPosting
+	var lines []int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		res, err := store.UpsertJobs(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("lines %d-%d: %w", lines[0], lines[len(lines)-1], err)
+		}
+		result.Inserted += res.Inserted
+		result.Updated += res.Updated
+		result.Unchanged += res.Unchanged
+		result.Merged += res.Merged
+		result.Failed += res.Failed
+		for _, e := range res.Errors {
+			result.lineError(lines[e.Index], e.Err)
+		}
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	add := func(line int, job pkg.JobPosting, err error) error {
+		result.Lines++
+		if err == nil {
+			job, err = prepare(job, time.Now())
+		}
+		if err != nil {
+			result.Invalid++
+			result.lineError(line, err)
+			return nil
+		}
+		batch = append(batch, job)
+		lines = append(lines, line)
+		if len(batch) < pkg.DefaultBatchSize {
+			return nil
+		}
+		return flush()
+	}
+
+	var err error
+	if format == CSV {
+		err = readCSV(r, add)
+	} else {
+		err = readJSONL(r, add)
+	}
+	if err != nil {
+		return result, err
+	}
+	return result, flush()
+}
+
+func readJSONL(r io.Reader, add func(line int, job pkg.JobPosting, err error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var job pkg.JobPosting
+		err := json.Unmarshal([]byte(text), &job)
+		if err != nil {
+			err = fmt.Errorf("invalid JSON: %w", err)
+		}
+		if err := add(line, job, err); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadInput, err)
+	}
+	return nil
+}
+
+func readCSV(r io.Reader, add func(line int, job pkg.JobPosting, err error) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: CSV header: %w", ErrBadInput, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return fmt.Errorf("%w: CSV header has no title column", ErrBadInput)
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := add(parseErr.StartLine, pkg.JobPosting{}, parseErr.Err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrBadInput, err)
+		}
+		line, _ := cr.FieldPos(0)
+		job, err := csvJob(columns, record)
+		if err := add(line, job, err); err != nil {
+			return err
+		}
+	}
+}
+
+// csvJob reads the columns of a posting's source fields from record.
+func csvJob(columns map[string]int, record []string) (pkg.JobPosting, error) {
+	get := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	job := pkg.JobPosting{
+		Title:       get("title"),
+		Company:     get("company"),
+		Location:    get("location"),
+		Salary:      get("salary"),
+		Description: get("description"),
+		URL:         get("url"),
+		Source:      get("source"),
+		ApplyURL:    get("apply_url"),
+		Experience:  get("experience"),
+	}
+	for _, skill := range strings.FieldsFunc(get("skills"), func(r rune) bool { return r == ';' || r == ',' }) {
+		if skill = strings.TrimSpace(skill); skill != "" {
+			job.Skills = append(job.Skills, skill)
+		}
+	}
+	if v := get("posted_on"); v != "" {
+		posted, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			posted, err = time.Parse("2006-01-02", v)
+		}
+		if err != nil {
+			return job, fmt.Errorf("posted_on %q is not a date (2006-01-02) or RFC 3339 timestamp", v)
+		}
+		job.PostedOn = posted
+	}
+	return job, nil
+}
+
+// prepare validates an imported posting and normalizes it the way the crawler
+// does its parsed postings. Only the fields a crawler reads from a page are
+// kept; IDs, hashes and lifecycle fields are the store's to set.
+func prepare(in pkg.JobPosting, now time.Time) (pkg.JobPosting, error) {
+	job := pkg.JobPosting{
+		Title:       sites.CleanTitle(in.Title),
+		Company:     strings.TrimSpace(in.Company),
+		Location:    strings.TrimSpace(in.Location),
+		Salary:      strings.TrimSpace(in.Salary),
+		PostedOn:    in.PostedOn,
+		Description: strings.TrimSpace(in.Description),
+		URL:         strings.TrimSpace(in.URL),
+		Source:      strings.TrimSpace(in.Source),
+		ApplyURL:    strings.TrimSpace(in.ApplyURL),
+		Experience:  strings.TrimSpace(in.Experience),
+	}
+	if job.Title == "" || job.Company == "" {
+		return job, errors.New("title and company are required")
+	}
+	u, err := url.Parse(job.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return job, errors.New("url must be an http(s) URL")
+	}
+	if job.Source == "" {
+		job.Source = strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	if job.PostedOn.IsZero() {
+		job.PostedOn = now
+	}
+
+	pkg.NormalizeLocation(&job)
+	job.Skills = append(append(job.Skills, in.Skills...), sites.ExtractSkills(job.Description)...)
+	pkg.ClassifyJob(&job)
+	return job, nil
+}
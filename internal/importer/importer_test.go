@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler/sites"
+	"github.com/vx6fid/job-crawler/internal/export"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestImportJSONL(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+
+	input := strings.Join([]string{
+		`{"title": "Senior Go Engineer", "company": "Acme Inc.", "url": "https://www.example.com/jobs/1", "posted_on": "2025-03-01T00:00:00Z",` +
+			` "location": "Berlin, Germany", "salary": "€70k - €90k", "skills": ["Golang"], "description": "5+ years building services with Kubernetes and PostgreSQL."}`,
+		``,
+		`{"title": "broken"`,
+		`{"company": "Acme", "url": "https://example.com/jobs/2"}`,
+		`{"title": "Designer", "company": "Acme", "url": "ftp://example.com/jobs/3"}`,
+		// The same posting again dedupes onto the first
+		`{"title": "Senior Go Engineer", "company": "ACME", "url": "https://www.example.com/jobs/1", "posted_on": "2025-03-01T00:00:00Z",` +
+			` "location": "Berlin, Germany", "salary": "€70k - €90k", "skills": ["Golang"], "description": "5+ years building services with Kubernetes and PostgreSQL."}`,
+	}, "\n")
+
+	result, err := Import(ctx, store, strings.NewReader(input), JSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Lines != 5 || result.Inserted != 1 || result.Unchanged != 1 || result.Invalid != 3 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	var lines []int
+	for _, e := range result.Errors {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 4, 5}) {
+		t.Errorf("errors on lines %v, want [3 4 5]: %+v", lines, result.Errors)
+	}
+
+	jobs, _ := store.QueryJobs(ctx, pkg.JobQuery{})
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+	job := jobs[0]
+	if job.Source != "example.com" || job.SalaryMin != 70000 || job.Seniority != "senior" ||
+		job.ExperienceRange == nil || job.ExperienceRange.Min != 5 || len(job.Locations) == 0 {
+		t.Errorf("posting was not normalized: %+v", job)
+	}
+	if !reflect.DeepEqual(job.Skills, []string{"go", "kubernetes", "postgresql", "sql"}) {
+		t.Errorf("skills = %v", job.Skills)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+
+	input := "title,company,url,posted_on,skills,extra\n" +
+		"Data Engineer,Globex,https://example.com/jobs/1,2025-02-01,\"python;airflow\",x\n" +
+		"Analyst,Globex,https://example.com/jobs/2,yesterday,,\n" +
+		"\"Multi\nline\",Globex,https://example.com/jobs/3,,,\n"
+
+	result, err := Import(ctx, store, strings.NewReader(input), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Lines != 3 || result.Inserted != 2 || result.Invalid != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Line != 3 {
+		t.Errorf("expected an error on line 3, got %+v", result.Errors)
+	}
+
+	if _, err := Import(ctx, store, strings.NewReader("company,url\nAcme,https://example.com\n"), CSV); !errors.Is(err, ErrBadInput) {
+		t.Errorf("expected ErrBadInput without a title column, got %v", err)
+	}
+}
+
+// An export imports back into an empty store as the same postings.
+func TestImportExportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := pkg.NewMemoryStore()
+	if _, err := Import(ctx, source, strings.NewReader(
+		`{"title": "Go Engineer", "company": "Acme", "url": "https://example.com/1", "posted_on": "2025-03-01T00:00:00Z", "salary": "$100k+", "skills": ["go"]}`+"\n"+
+			`{"title": "SRE", "company": "Initech", "url": "https://example.com/2", "posted_on": "2025-03-02T00:00:00Z", "location": "Remote, Canada"}`+"\n"),
+		JSONL); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{export.CSV, export.JSONL} {
+		var buf bytes.Buffer
+		if _, err := export.Jobs(ctx, source, pkg.JobQuery{}, format, &buf); err != nil {
+			t.Fatal(err)
+		}
+		target := pkg.NewMemoryStore()
+		result, err := Import(ctx, target, &buf, format)
+		if err != nil || result.Inserted != 2 || len(result.Errors) != 0 {
+			t.Fatalf("%s: unexpected result %+v (%v)", format, result, err)
+		}
+
+		want, _ := source.QueryJobs(ctx, pkg.JobQuery{})
+		got, _ := target.QueryJobs(ctx, pkg.JobQuery{})
+		for i := range want {
+			if got[i].Hash != want[i].Hash || got[i].SalaryMin != want[i].SalaryMin || !reflect.DeepEqual(got[i].Skills, want[i].Skills) {
+				t.Errorf("%s: posting %d differs after a round trip: %+v, want %+v", format, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestImportMatchesCrawled(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+
+	// As the crawler stores it
+	crawled := pkg.JobPosting{Title: "senior go engineer", Company: "Acme", Location: "Berlin, Germany",
+		PostedOn: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Description: "Build services in Go.",
+		URL: "https://weworkremotely.com/remote-jobs/acme-senior-go-engineer", Source: "weworkremotely.com"}
+	crawled.Skills = sites.ExtractSkills(crawled.Description)
+	pkg.NormalizeLocation(&crawled)
+	pkg.ClassifyJob(&crawled)
+	if _, err := store.UpsertJob(ctx, crawled); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"title": " Senior Go Engineer ", "company": "Acme", "url": "https://weworkremotely.com/remote-jobs/acme-senior-go-engineer",` +
+		` "posted_on": "2025-03-01T00:00:00Z", "location": "Berlin, Germany", "description": "Build services in Go."}`
+	result, err := Import(ctx, store, strings.NewReader(input), JSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Unchanged != 1 || result.Inserted != 0 {
+		t.Errorf("expected the import to match the crawled posting, got %+v", result)
+	}
+}
@@ -0,0 +1,224 @@
+// Package scheduler enqueues crawls on the cron schedules kept in the job
+// store, for the crawl workers to run (see package worker). Each run starts
+// after a random jitter so that schedules sharing a time don't hit the boards
+// together, and a run whose roles are all still queued or being crawled is
+// skipped.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/internal/worker"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// reloadInterval is how often schedules are re-read from the store, which
+// picks up changes made by other processes.
+const reloadInterval = time.Minute
+
+// Scheduler fires crawls for the enabled schedules of Store.
+type Scheduler struct {
+	Store pkg.ScheduleStore
+	// Crawl starts one scheduled crawl; crawler.ErrAlreadyCrawling counts as
+	// skipped.
+	Crawl func(ctx context.Context, schedule pkg.Schedule) error
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	running sync.WaitGroup
+	reload  chan struct{}
+	now     func() time.Time
+	jitter  func(max time.Duration) time.Duration
+}
+
+type entry struct {
+	schedule pkg.Schedule
+	next     time.Time
+	busy     bool
+}
+
+// New returns a scheduler that enqueues crawls on the queue of store.
+func New(store pkg.Store) *Scheduler {
+	return &Scheduler{
+		Store: store,
+		Crawl: func(ctx context.Context, schedule pkg.Schedule) error {
+			_, err := worker.Enqueue(ctx, store, worker.Request{
+				Roles:      schedule.Roles,
+				Sources:    schedule.Sources,
+				MaxJobs:    schedule.MaxJobs,
+				ScheduleID: schedule.ID,
+			})
+			return err
+		},
+		entries: map[string]*entry{},
+		reload:  make(chan struct{}, 1),
+		now:     time.Now,
+		jitter: func(max time.Duration) time.Duration {
+			if max <= 0 {
+				return 0
+			}
+			return rand.N(max)
+		},
+	}
+}
+
+// Reload asks the scheduler to re-read its schedules now, after one was
+// created, edited or deleted. It does not block.
+func (s *Scheduler) Reload() {
+	if s == nil {
+		return
+	}
+	select {
+	case s.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Run fires schedules until ctx is done, then waits for the runs in progress.
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.running.Wait()
+
+	s.sync(ctx)
+	reload := time.NewTicker(reloadInterval)
+	defer reload.Stop()
+
+	for {
+		timer := time.NewTimer(s.untilNext())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.fireDue(ctx)
+		case <-reload.C:
+			timer.Stop()
+			s.sync(ctx)
+		case <-s.reload:
+			timer.Stop()
+			s.sync(ctx)
+		}
+	}
+}
+
+// sync reconciles the entries with the stored schedules. A schedule keeps its
+// next time unless its cron expression changed.
+func (s *Scheduler) sync(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	schedules, err := s.Store.ListSchedules(ctx)
+	if err != nil {
+		log.Printf("[scheduler] Failed to load schedules: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	seen := make(map[string]bool, len(schedules))
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		seen[schedule.ID] = true
+		e, ok := s.entries[schedule.ID]
+		if !ok || e.schedule.Cron != schedule.Cron {
+			next := schedule.Next(now)
+			if next.IsZero() {
+				log.Printf("[scheduler] Schedule %s has an invalid cron expression %q", schedule.ID, schedule.Cron)
+				continue
+			}
+			if !ok {
+				e = &entry{}
+				s.entries[schedule.ID] = e
+			}
+			e.next = next
+		}
+		e.schedule = schedule
+	}
+	for id := range s.entries {
+		if !seen[id] {
+			delete(s.entries, id)
+		}
+	}
+}
+
+// untilNext is how long to wait for the earliest schedule.
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := reloadInterval
+	now := s.now()
+	for _, e := range s.entries {
+		wait = min(wait, e.next.Sub(now))
+	}
+	return max(wait, 0)
+}
+
+// fireDue starts a run of every schedule whose time has come.
+func (s *Scheduler) fireDue(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, e := range s.entries {
+		if e.next.After(now) {
+			continue
+		}
+		e.next = e.schedule.Next(now)
+		if e.busy {
+			log.Printf("[scheduler] Skipping %q: its previous run is still going", e.schedule.Name)
+			s.record(ctx, id, pkg.ScheduleRun{StartedAt: now, Status: pkg.RunSkipped, Error: "previous run still in progress"})
+			continue
+		}
+		e.busy = true
+		s.running.Add(1)
+		go s.run(ctx, e, e.schedule)
+	}
+}
+
+// run waits out the jitter, crawls and records how it went.
+func (s *Scheduler) run(ctx context.Context, e *entry, schedule pkg.Schedule) {
+	defer s.running.Done()
+	defer func() {
+		s.mu.Lock()
+		e.busy = false
+		s.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(s.jitter(time.Duration(schedule.JitterSeconds) * time.Second)):
+	case <-ctx.Done():
+		return
+	}
+
+	start := s.now()
+	log.Printf("[scheduler] Running %q: roles %v", schedule.Name, schedule.Roles)
+	err := s.Crawl(ctx, schedule)
+
+	run := pkg.ScheduleRun{StartedAt: start, Duration: s.now().Sub(start).Seconds(), Status: pkg.RunOK}
+	switch {
+	case errors.Is(err, crawler.ErrAlreadyCrawling):
+		run.Status, run.Error = pkg.RunSkipped, err.Error()
+	case err != nil:
+		run.Status, run.Error = pkg.RunFailed, err.Error()
+		log.Printf("[scheduler] Run of %q failed: %v", schedule.Name, err)
+	}
+	s.record(ctx, schedule.ID, run)
+}
+
+func (s *Scheduler) record(ctx context.Context, id string, run pkg.ScheduleRun) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := s.Store.RecordScheduleRun(ctx, id, run); err != nil && !errors.Is(err, pkg.ErrScheduleNotFound) {
+		log.Printf("[scheduler] Failed to record run of %s: %v", id, err)
+	}
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// testScheduler returns a scheduler on a fake clock whose crawls report on
+// started and then wait for finish.
+func testScheduler(store pkg.ScheduleStore, clock *time.Time) (s *Scheduler, started chan string, finish chan error) {
+	started, finish = make(chan string, 10), make(chan error, 10)
+	s = &Scheduler{
+		Store: store,
+		Crawl: func(ctx context.Context, schedule pkg.Schedule) error {
+			started <- schedule.Name
+			return <-finish
+		},
+		entries: map[string]*entry{},
+		reload:  make(chan struct{}, 1),
+		now:     func() time.Time { return *clock },
+		jitter:  func(time.Duration) time.Duration { return 0 },
+	}
+	return s, started, finish
+}
+
+func lastRun(t *testing.T, store pkg.ScheduleStore, id string) *pkg.ScheduleRun {
+	t.Helper()
+	schedule, err := store.GetSchedule(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schedule.LastRun
+}
+
+func TestSchedulerFiresDueSchedules(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	every5, _ := store.CreateSchedule(ctx, pkg.Schedule{Name: "every5", Cron: "*/5 * * * *", Roles: []string{"backend"}, Enabled: true})
+	store.CreateSchedule(ctx, pkg.Schedule{Name: "disabled", Cron: "* * * * *", Roles: []string{"frontend"}})
+
+	clock := time.Date(2025, 3, 1, 10, 0, 30, 0, time.UTC)
+	s, started, finish := testScheduler(store, &clock)
+	s.sync(ctx)
+
+	clock = clock.Add(time.Minute)
+	s.fireDue(ctx)
+	select {
+	case name := <-started:
+		t.Fatalf("%s ran before its time", name)
+	default:
+	}
+
+	clock = time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC)
+	s.fireDue(ctx)
+	if name := <-started; name != "every5" {
+		t.Fatalf("ran %s, want every5", name)
+	}
+
+	// Still running at the next time: skipped rather than run twice
+	clock = clock.Add(5 * time.Minute)
+	s.fireDue(ctx)
+	if run := lastRun(t, store, every5.ID); run == nil || run.Status != pkg.RunSkipped {
+		t.Errorf("expected a skipped run, got %+v", run)
+	}
+
+	finish <- nil
+	s.running.Wait()
+	if run := lastRun(t, store, every5.ID); run == nil || run.Status != pkg.RunOK || !run.StartedAt.Equal(time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("expected an ok run, got %+v", run)
+	}
+
+	// Roles busy in another crawl count as skipped
+	clock = clock.Add(5 * time.Minute)
+	s.fireDue(ctx)
+	<-started
+	finish <- crawler.ErrAlreadyCrawling
+	s.running.Wait()
+	if run := lastRun(t, store, every5.ID); run.Status != pkg.RunSkipped || run.Error == "" {
+		t.Errorf("expected a skipped run, got %+v", run)
+	}
+}
+
+func TestSchedulerSyncPicksUpEdits(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	schedule, _ := store.CreateSchedule(ctx, pkg.Schedule{Name: "daily", Cron: "@daily", Roles: []string{"backend"}, Enabled: true})
+
+	clock := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	s, _, _ := testScheduler(store, &clock)
+	s.sync(ctx)
+	if next := s.entries[schedule.ID].next; !next.Equal(time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("next = %v", next)
+	}
+
+	schedule.Cron = "@hourly"
+	store.UpdateSchedule(ctx, schedule)
+	s.sync(ctx)
+	if next := s.entries[schedule.ID].next; !next.Equal(time.Date(2025, 3, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("next after the edit = %v", next)
+	}
+	if wait := s.untilNext(); wait != time.Minute {
+		t.Errorf("untilNext = %v, want the reload interval", wait)
+	}
+
+	schedule.Enabled = false
+	store.UpdateSchedule(ctx, schedule)
+	s.sync(ctx)
+	if len(s.entries) != 0 {
+		t.Errorf("disabled schedule is still scheduled")
+	}
+}
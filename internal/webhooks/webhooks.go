@@ -0,0 +1,226 @@
+// Package webhooks delivers events to subscribed URLs. Each request carries
+// the event as JSON, signed with the subscription's secret, and failed
+// deliveries are retried with exponential backoff. Every attempt is recorded
+// in the delivery log.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/events"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// Headers of a delivery request.
+const (
+	EventHeader     = "X-Webhook-Event"     // event type
+	DeliveryHeader  = "X-Webhook-Delivery"  // event ID, the same on every retry
+	TimestampHeader = "X-Webhook-Timestamp" // unix seconds when the request was signed
+	SignatureHeader = "X-Webhook-Signature" // see Sign
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultWorkers     = 4
+	queueSize          = 1000
+)
+
+// Sign returns the signature of a request body sent at timestamp:
+// "sha256=" and the hex HMAC-SHA256, keyed with secret, of "<timestamp>.<body>".
+// Receivers should recompute it and reject stale timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is Sign(secret, timestamp, body).
+func Verify(secret, signature string, timestamp int64, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	if _, err := cryptorand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
+
+// Backoff is the default wait before retry n (1 for the first retry): 1s,
+// doubling up to 5 minutes, with up to 20% jitter so failing receivers are
+// not hit in lockstep.
+func Backoff(n int) time.Duration {
+	wait := time.Second << min(n-1, 8)
+	wait = min(wait, 5*time.Minute)
+	return wait + time.Duration(rand.Int64N(int64(wait)/5+1))
+}
+
+// Dispatcher delivers published events to the webhooks subscribed to them.
+type Dispatcher struct {
+	Store       pkg.WebhookStore
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     func(n int) time.Duration
+
+	queue chan job
+	wg    sync.WaitGroup
+	ctx   context.Context
+	stop  context.CancelFunc
+}
+
+type job struct {
+	hook pkg.Webhook
+	ev   events.Event
+}
+
+func NewDispatcher(store pkg.WebhookStore) *Dispatcher {
+	return &Dispatcher{
+		Store:       store,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     Backoff,
+	}
+}
+
+// Start runs workers goroutines that deliver queued events until Close.
+func (d *Dispatcher) Start(workers int) {
+	d.queue = make(chan job, queueSize)
+	d.ctx, d.stop = context.WithCancel(context.Background())
+	for range workers {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for j := range d.queue {
+				if err := d.Deliver(d.ctx, j.hook, j.ev); err != nil {
+					log.Printf("[webhooks] Giving up on %s for webhook %s: %v", j.ev.Type, j.hook.ID, err)
+				}
+			}
+		}()
+	}
+}
+
+// Close stops accepting events and waits for queued deliveries. Pending
+// retries are abandoned once ctx is done.
+func (d *Dispatcher) Close(ctx context.Context) {
+	close(d.queue)
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		d.stop()
+		<-done
+	}
+	d.stop()
+}
+
+// Handle queues ev for every webhook subscribed to its type. It is meant to be
+// subscribed to an events.Bus and does not block on delivery.
+func (d *Dispatcher) Handle(ev events.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hooks, err := d.Store.ListWebhooks(ctx)
+	if err != nil {
+		log.Printf("[webhooks] Failed to load webhooks for %s: %v", ev.Type, err)
+		return
+	}
+	for _, hook := range hooks {
+		if !hook.Wants(ev.Type) {
+			continue
+		}
+		select {
+		case d.queue <- job{hook: hook, ev: ev}:
+		default:
+			log.Printf("[webhooks] Queue full, dropping %s %s for webhook %s", ev.Type, ev.ID, hook.ID)
+		}
+	}
+}
+
+// Deliver sends ev to hook, retrying failures up to MaxAttempts with Backoff
+// between attempts. Client errors other than 408 and 429 are not retried.
+func (d *Dispatcher) Deliver(ctx context.Context, hook pkg.Webhook, ev events.Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		delivery, retry := d.attempt(ctx, hook, ev, body)
+		delivery.Attempt = attempt
+		if err := d.Store.RecordDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+			log.Printf("[webhooks] Failed to record delivery: %v", err)
+		}
+		if delivery.Success {
+			return nil
+		}
+		if !retry || attempt >= d.MaxAttempts {
+			return fmt.Errorf("attempt %d: %s", attempt, delivery.Error)
+		}
+
+		select {
+		case <-time.After(d.Backoff(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// attempt makes one request and reports whether a failure is worth retrying.
+func (d *Dispatcher) attempt(ctx context.Context, hook pkg.Webhook, ev events.Event, body []byte) (pkg.Delivery, bool) {
+	delivery := pkg.Delivery{WebhookID: hook.ID, EventID: ev.ID, EventType: ev.Type, DeliveredAt: time.Now()}
+	defer func() { delivery.Duration = time.Since(delivery.DeliveredAt) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, false
+	}
+	timestamp := delivery.DeliveredAt.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, ev.Type)
+	req.Header.Set(DeliveryHeader, ev.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		delivery.Duration = time.Since(delivery.DeliveredAt)
+		return delivery, true
+	}
+	resp.Body.Close()
+	delivery.StatusCode = resp.StatusCode
+	delivery.Duration = time.Since(delivery.DeliveredAt)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		delivery.Success = true
+		return delivery, false
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		delivery.Error = resp.Status
+		return delivery, true
+	default:
+		delivery.Error = resp.Status
+		return delivery, false
+	}
+}
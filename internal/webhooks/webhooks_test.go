@@ -0,0 +1,134 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/events"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestDeliverSignsAndRetries(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	hook, err := store.CreateWebhook(ctx, pkg.Webhook{URL: "http://placeholder", Secret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if !Verify("s3cret", r.Header.Get(SignatureHeader), ts, body) {
+			t.Errorf("signature %q does not verify", r.Header.Get(SignatureHeader))
+		}
+		if r.Header.Get(EventHeader) != events.JobCreated || r.Header.Get(DeliveryHeader) != "ev1" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		var ev events.Event
+		if err := json.Unmarshal(body, &ev); err != nil || ev.ID != "ev1" {
+			t.Errorf("body = %s", body)
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	hook.URL = srv.URL
+
+	d := NewDispatcher(store)
+	d.Backoff = func(int) time.Duration { return time.Millisecond }
+	ev := events.Event{ID: "ev1", Type: events.JobCreated, Time: time.Now(), Data: map[string]string{"title": "Go Engineer"}}
+	if err := d.Deliver(ctx, hook, ev); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := store.ListDeliveries(ctx, hook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 3 {
+		t.Fatalf("got %d deliveries, want 3", len(deliveries))
+	}
+	// Newest first
+	if d := deliveries[0]; !d.Success || d.Attempt != 3 || d.StatusCode != http.StatusOK {
+		t.Errorf("last attempt = %+v", d)
+	}
+	if d := deliveries[2]; d.Success || d.Attempt != 1 || d.StatusCode != http.StatusServiceUnavailable || d.Error == "" {
+		t.Errorf("first attempt = %+v", d)
+	}
+}
+
+func TestDeliverStopsOnClientError(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+	hook, _ := store.CreateWebhook(ctx, pkg.Webhook{URL: srv.URL, Secret: "s"})
+
+	d := NewDispatcher(store)
+	d.Backoff = func(int) time.Duration { return time.Millisecond }
+	if err := d.Deliver(ctx, hook, events.Event{ID: "ev1", Type: events.JobClosed}); err == nil {
+		t.Fatal("expected an error for 410 Gone")
+	}
+	if deliveries, _ := store.ListDeliveries(ctx, hook.ID, 10); len(deliveries) != 1 {
+		t.Errorf("got %d deliveries, want 1", len(deliveries))
+	}
+}
+
+func TestDispatcherDeliversStoreEvents(t *testing.T) {
+	ctx := context.Background()
+	memory := pkg.NewMemoryStore()
+
+	var mu sync.Mutex
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get(EventHeader))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	if _, err := memory.CreateWebhook(ctx, pkg.Webhook{URL: srv.URL, Secret: "s", Events: []string{events.JobCreated, events.JobClosed}}); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := events.NewBus()
+	d := NewDispatcher(memory)
+	d.Start(1)
+	bus.Subscribe(d.Handle)
+	store := events.NewStore(memory, bus)
+
+	job := pkg.JobPosting{Title: "Go Engineer", Company: "Acme", URL: "https://example.com/1", PostedOn: time.Now(), Description: "v1"}
+	if _, err := store.UpsertJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	job.Description = "v2"
+	if _, err := store.UpsertJob(ctx, job); err != nil { // job.updated, not subscribed
+		t.Fatal(err)
+	}
+	if _, err := store.CloseJobs(ctx, []string{job.URL}, "gone", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CloseJobs(ctx, []string{job.URL}, "gone", time.Now()); err != nil { // already closed
+		t.Fatal(err)
+	}
+	d.Close(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0] != events.JobCreated || received[1] != events.JobClosed {
+		t.Errorf("received %v, want [%s %s]", received, events.JobCreated, events.JobClosed)
+	}
+}
@@ -0,0 +1,201 @@
+// Package worker runs crawls leased from the crawl queue of the job store. Any
+// number of workers, in any number of processes, can share one queue: each
+// task is leased to one worker at a time, the worker renews its lease while it
+// crawls, and a task whose worker died is leased again once its lease runs
+// out. The API and the scheduler only enqueue.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// Defaults for the fields of Worker.
+const (
+	DefaultLease   = 2 * time.Minute
+	DefaultPoll    = 5 * time.Second
+	DefaultTimeout = 60 * time.Second // of one crawl
+)
+
+// Worker leases crawl tasks from Queue and runs them one at a time.
+type Worker struct {
+	ID    string // lease holder; unique across processes
+	Queue pkg.QueueStore
+	// Crawl runs one leased task.
+	Crawl func(task pkg.CrawlTask) error
+	Lease time.Duration // renewed every third of it while crawling
+	Poll  time.Duration // wait between leases when the queue is empty
+}
+
+// New returns a worker that crawls into store, with the queue of store.
+func New(store pkg.Store) *Worker {
+	return &Worker{
+		ID:    NewID(),
+		Queue: store,
+		Crawl: func(task pkg.CrawlTask) error {
+			return crawler.Crawl(store, crawler.Options{
+				Roles:   []string{task.Role},
+				Sources: task.Sources,
+				MaxJobs: task.MaxJobs,
+				Timeout: DefaultTimeout,
+			})
+		},
+		Lease: DefaultLease,
+		Poll:  DefaultPoll,
+	}
+}
+
+var idCounter struct {
+	sync.Mutex
+	n int
+}
+
+// NewID returns a worker ID made of the host name, process ID and a counter,
+// so that workers are told apart across machines and within a process.
+func NewID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	idCounter.Lock()
+	defer idCounter.Unlock()
+	idCounter.n++
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), idCounter.n)
+}
+
+// Run leases and crawls tasks until ctx is done. A crawl in progress when ctx
+// is done is finished first.
+func (w *Worker) Run(ctx context.Context) {
+	log.Printf("[worker %s] Started", w.ID)
+	for {
+		ran := w.RunOne(ctx)
+		if ctx.Err() != nil {
+			log.Printf("[worker %s] Stopped", w.ID)
+			return
+		}
+		if ran {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("[worker %s] Stopped", w.ID)
+			return
+		case <-time.After(w.Poll):
+		}
+	}
+}
+
+// RunOne leases one task and crawls it, and reports whether there was one.
+func (w *Worker) RunOne(ctx context.Context) bool {
+	task, err := w.Queue.LeaseCrawl(ctx, w.ID, w.Lease)
+	if errors.Is(err, pkg.ErrQueueEmpty) {
+		return false
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[worker %s] Failed to lease a crawl: %v", w.ID, err)
+		}
+		return false
+	}
+	log.Printf("[worker %s] Crawling %q (task %s, attempt %d)", w.ID, task.Role, task.ID, task.Attempts)
+
+	// The crawl outlives ctx, so the queue calls below must too
+	bg := context.Background()
+	stop := make(chan struct{})
+	var heartbeat sync.WaitGroup
+	heartbeat.Add(1)
+	go func() {
+		defer heartbeat.Done()
+		w.heartbeat(bg, task.ID, stop)
+	}()
+
+	errMsg := ""
+	if err := w.Crawl(*task); err != nil {
+		errMsg = err.Error()
+		log.Printf("[worker %s] Crawl of %q failed: %v", w.ID, task.Role, err)
+	}
+	close(stop)
+	heartbeat.Wait()
+
+	finishCtx, cancel := context.WithTimeout(bg, 10*time.Second)
+	defer cancel()
+	if err := w.Queue.FinishCrawl(finishCtx, task.ID, w.ID, errMsg); err != nil {
+		log.Printf("[worker %s] Failed to finish task %s: %v", w.ID, task.ID, err)
+	}
+	return true
+}
+
+// heartbeat renews the lease on a task until stop is closed.
+func (w *Worker) heartbeat(ctx context.Context, id string, stop <-chan struct{}) {
+	ticker := time.NewTicker(w.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			renewCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			err := w.Queue.RenewLease(renewCtx, id, w.ID, w.Lease)
+			cancel()
+			if errors.Is(err, pkg.ErrLeaseLost) {
+				// Another worker has the task now; it will crawl it again
+				log.Printf("[worker %s] Lost the lease on task %s", w.ID, id)
+				return
+			}
+			if err != nil {
+				log.Printf("[worker %s] Failed to renew the lease on task %s: %v", w.ID, id, err)
+			}
+		}
+	}
+}
+
+// Request is what to crawl, as given to Enqueue.
+type Request struct {
+	Roles      []string
+	Sources    []string
+	MaxJobs    int
+	ScheduleID string
+}
+
+// Enqueue adds a task per role of req to queue and returns them, along with
+// the tasks already queued or running for the other roles. If every role had
+// one already, it returns those and crawler.ErrAlreadyCrawling.
+func Enqueue(ctx context.Context, queue pkg.QueueStore, req Request) ([]pkg.CrawlTask, error) {
+	var tasks []pkg.CrawlTask
+	created := 0
+	seen := map[string]bool{}
+	for _, role := range req.Roles {
+		role = strings.TrimSpace(role)
+		if role == "" || seen[strings.ToLower(role)] {
+			continue
+		}
+		seen[strings.ToLower(role)] = true
+
+		task, ok, err := queue.EnqueueCrawl(ctx, pkg.CrawlTask{
+			Role:       role,
+			Sources:    req.Sources,
+			MaxJobs:    req.MaxJobs,
+			ScheduleID: req.ScheduleID,
+		})
+		if err != nil {
+			return tasks, err
+		}
+		if ok {
+			created++
+		}
+		tasks = append(tasks, task)
+	}
+	if created == 0 && len(tasks) > 0 {
+		return tasks, crawler.ErrAlreadyCrawling
+	}
+	return tasks, nil
+}
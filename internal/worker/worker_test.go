@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/internal/crawler"
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestEnqueue(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+
+	tasks, err := Enqueue(ctx, store, Request{Roles: []string{"backend", " Backend", "frontend"}, MaxJobs: 5})
+	if err != nil || len(tasks) != 2 || tasks[0].Role != "backend" || tasks[1].MaxJobs != 5 {
+		t.Fatalf("unexpected tasks: %+v (%v)", tasks, err)
+	}
+
+	again, err := Enqueue(ctx, store, Request{Roles: []string{"frontend"}})
+	if !errors.Is(err, crawler.ErrAlreadyCrawling) || len(again) != 1 || again[0].ID != tasks[1].ID {
+		t.Fatalf("expected the queued task and ErrAlreadyCrawling, got %+v (%v)", again, err)
+	}
+	if _, err := Enqueue(ctx, store, Request{Roles: []string{"frontend", "devops"}}); err != nil {
+		t.Errorf("expected no error when a role is new, got %v", err)
+	}
+}
+
+func TestRunOne(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	tasks, err := Enqueue(ctx, store, Request{Roles: []string{"backend", "frontend"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var crawled []string
+	w := &Worker{ID: "w1", Queue: store, Lease: time.Minute, Poll: time.Millisecond}
+	w.Crawl = func(task pkg.CrawlTask) error {
+		crawled = append(crawled, task.Role)
+		if task.Role == "frontend" {
+			return errors.New("board unreachable")
+		}
+		return nil
+	}
+	for w.RunOne(ctx) {
+	}
+	if len(crawled) != 2 || crawled[0] != "backend" {
+		t.Fatalf("unexpected crawls: %v", crawled)
+	}
+
+	done, _ := store.GetCrawl(ctx, tasks[0].ID)
+	failed, _ := store.GetCrawl(ctx, tasks[1].ID)
+	if done.Status != pkg.TaskDone || done.Worker != "w1" {
+		t.Errorf("unexpected done task: %+v", done)
+	}
+	if failed.Status != pkg.TaskFailed || failed.Error != "board unreachable" {
+		t.Errorf("unexpected failed task: %+v", failed)
+	}
+}
+
+func TestHeartbeatKeepsLease(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	if _, err := Enqueue(ctx, store, Request{Roles: []string{"backend"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	other := &Worker{ID: "w2", Queue: store, Lease: time.Minute}
+	w := &Worker{ID: "w1", Queue: store, Lease: 30 * time.Millisecond}
+	stolen := false
+	w.Crawl = func(task pkg.CrawlTask) error {
+		// Well past the first lease, which only the heartbeat keeps alive
+		time.Sleep(100 * time.Millisecond)
+		stolen = other.RunOne(ctx)
+		return nil
+	}
+	other.Crawl = func(pkg.CrawlTask) error { return nil }
+
+	if !w.RunOne(ctx) {
+		t.Fatal("expected a task")
+	}
+	if stolen {
+		t.Error("expected the lease to be renewed while crawling")
+	}
+	tasks, _ := store.ListCrawls(ctx, pkg.TaskDone, 0)
+	if len(tasks) != 1 || tasks[0].Worker != "w1" || tasks[0].Attempts != 1 {
+		t.Errorf("unexpected tasks: %+v", tasks)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := pkg.NewMemoryStore()
+	w := &Worker{ID: "w1", Queue: store, Lease: time.Minute, Poll: time.Millisecond}
+	w.Crawl = func(pkg.CrawlTask) error { return nil }
+
+	stopped := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(stopped)
+	}()
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+}
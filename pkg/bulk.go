@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// DefaultBatchSize is how many postings a BulkWriter buffers before flushing.
+const DefaultBatchSize = 50
+
+// BatchStore upserts many postings at once with the semantics of UpsertJob.
+// A failing posting does not stop the others: UpsertJobs only returns an error
+// when the batch as a whole could not be written, per-posting failures are in
+// BatchResult.Errors.
+type BatchStore interface {
+	UpsertJobs(ctx context.Context, jobs []JobPosting) (BatchResult, error)
+}
+
+// BatchResult counts what a batch upsert did.
+type BatchResult struct {
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"` // modified: material fields changed
+	Unchanged int `json:"unchanged"`
+	Merged    int `json:"merged"`
+	Failed    int `json:"failed"`
+
+	Results []UpsertResult `json:"results,omitempty"` // per posting in input order, zero for failed ones
+	Errors  []ItemError    `json:"errors,omitempty"`
+}
+
+// ItemError is the failure of one posting in a batch.
+type ItemError struct {
+	Index int    // position in the batch
+	URL   string // posting URL, to find it again
+	Err   error
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("item %d (%s): %v", e.Index, e.URL, e.Err)
+}
+
+func (e ItemError) Unwrap() error {
+	return e.Err
+}
+
+func (e ItemError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Index int    `json:"index"`
+		URL   string `json:"url,omitempty"`
+		Error string `json:"error"`
+	}{e.Index, e.URL, e.Err.Error()})
+}
+
+func newBatchResult(n int) BatchResult {
+	return BatchResult{Results: make([]UpsertResult, n)}
+}
+
+// record stores the outcome of the posting at index i.
+func (r *BatchResult) record(i int, job JobPosting, res UpsertResult, err error) {
+	if err != nil {
+		r.Failed++
+		r.Errors = append(r.Errors, ItemError{Index: i, URL: job.URL, Err: err})
+		return
+	}
+	r.Results[i] = res
+	switch res.Action {
+	case UpsertInserted:
+		r.Inserted++
+	case UpsertUpdated:
+		r.Updated++
+	case UpsertUnchanged:
+		r.Unchanged++
+	case UpsertMerged:
+		r.Merged++
+	}
+}
+
+// upsertEach is UpsertJobs for stores whose UpsertJob is already cheap.
+func upsertEach(ctx context.Context, store JobStore, jobs []JobPosting) BatchResult {
+	result := newBatchResult(len(jobs))
+	for i, job := range jobs {
+		res, err := store.UpsertJob(ctx, job)
+		result.record(i, job, res, err)
+	}
+	return result
+}
+
+// BulkWriter buffers postings and upserts them in batches, so a crawl costs a
+// few round trips per batch instead of per posting. It is safe for concurrent
+// use; call Flush when done to write what is still buffered.
+type BulkWriter struct {
+	store BatchStore
+	size  int
+
+	mu       sync.Mutex
+	buf      []JobPosting
+	totals   BatchResult
+	inserted []string
+}
+
+// NewBulkWriter returns a writer that flushes every size postings. A size of 0
+// means DefaultBatchSize.
+func NewBulkWriter(store BatchStore, size int) *BulkWriter {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	return &BulkWriter{store: store, size: size}
+}
+
+// Add buffers job and flushes once the buffer is full. The error is that of
+// the flush; per-posting failures are collected in Totals.
+func (w *BulkWriter) Add(ctx context.Context, job JobPosting) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, job)
+	if len(w.buf) < w.size {
+		return nil
+	}
+	_, err := w.flush(ctx)
+	return err
+}
+
+// Flush writes everything buffered and returns the result of that batch.
+func (w *BulkWriter) Flush(ctx context.Context) (BatchResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush(ctx)
+}
+
+func (w *BulkWriter) flush(ctx context.Context) (BatchResult, error) {
+	if len(w.buf) == 0 {
+		return BatchResult{}, nil
+	}
+	batch := w.buf
+	w.buf = nil
+
+	result, err := w.store.UpsertJobs(ctx, batch)
+	if err != nil {
+		// The whole batch failed; count every posting in it as failed
+		w.totals.Failed += len(batch)
+		for i, job := range batch {
+			w.totals.Errors = append(w.totals.Errors, ItemError{Index: i, URL: job.URL, Err: err})
+		}
+		return result, err
+	}
+
+	w.totals.Inserted += result.Inserted
+	w.totals.Updated += result.Updated
+	w.totals.Unchanged += result.Unchanged
+	w.totals.Merged += result.Merged
+	w.totals.Failed += result.Failed
+	w.totals.Errors = append(w.totals.Errors, result.Errors...)
+	for _, res := range result.Results {
+		if res.Action == UpsertInserted {
+			w.inserted = append(w.inserted, res.ID)
+		}
+	}
+	return result, nil
+}
+
+// InsertedIDs returns the IDs of the postings that flushed batches inserted,
+// in the order they were added.
+func (w *BulkWriter) InsertedIDs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.inserted...)
+}
+
+// Totals returns the counts and errors of every batch flushed so far. Results
+// are not kept across batches.
+func (w *BulkWriter) Totals() BatchResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	totals := w.totals
+	totals.Errors = append([]ItemError(nil), w.totals.Errors...)
+	return totals
+}
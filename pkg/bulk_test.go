@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUpsertJobs(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		posted := time.Now().AddDate(0, 0, -1)
+
+		stored, err := store.UpsertJob(ctx, testJob("backend engineer", "Acme", posted))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changed := testJob("backend engineer", "Acme", posted)
+		changed.Salary = "$150,000"
+		repost := testJob("Backend Engineer (Remote)", "Acme", posted.AddDate(0, 0, 2))
+		repost.URL = "https://example-board.com/jobs/7"
+		batch := []JobPosting{
+			testJob("frontend engineer", "Acme", posted),
+			changed,
+			testJob("frontend engineer", "Acme", posted), // twice in one batch
+			{Title: "no company", PostedOn: posted},      // fails
+			repost,
+		}
+
+		result, err := store.UpsertJobs(ctx, batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Inserted != 1 || result.Updated != 1 || result.Unchanged != 1 || result.Merged != 1 || result.Failed != 1 {
+			t.Errorf("unexpected counts: %+v", result)
+		}
+		if len(result.Errors) != 1 || result.Errors[0].Index != 3 {
+			t.Errorf("expected item 3 to fail, got %+v", result.Errors)
+		}
+		if result.Results[1].ID != stored.ID || result.Results[4].ID != stored.ID {
+			t.Errorf("expected update and merge of %s, got %+v", stored.ID, result.Results)
+		}
+		if result.Results[0].ID == "" || result.Results[2].ID != result.Results[0].ID {
+			t.Errorf("expected the repeated posting to resolve to the inserted one, got %+v", result.Results)
+		}
+
+		if n, _ := store.CountJobs(ctx, JobQuery{}); n != 2 {
+			t.Errorf("expected 2 stored postings, got %d", n)
+		}
+		if revisions, _ := store.JobHistory(ctx, stored.ID); len(revisions) != 1 {
+			t.Errorf("expected the salary change to be recorded, got %+v", revisions)
+		}
+	})
+}
+
+func TestBulkWriter(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	writer := NewBulkWriter(store, 2)
+	posted := time.Now()
+
+	for _, title := range []string{"backend engineer", "frontend engineer", "data engineer"} {
+		if err := writer.Add(ctx, testJob(title, "Acme", posted)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, _ := store.CountJobs(ctx, JobQuery{}); n != 2 {
+		t.Errorf("expected one flushed batch of 2, got %d stored", n)
+	}
+
+	result, err := writer.Flush(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 1 {
+		t.Errorf("expected the buffered posting to be flushed, got %+v", result)
+	}
+
+	writer.Add(ctx, JobPosting{Title: "no company", URL: "https://example.com/broken"})
+	if result, _ := writer.Flush(ctx); result.Failed != 1 {
+		t.Errorf("expected a failed posting, got %+v", result)
+	}
+
+	totals := writer.Totals()
+	if totals.Inserted != 3 || totals.Failed != 1 || len(totals.Errors) != 1 || totals.Errors[0].URL != "https://example.com/broken" {
+		t.Errorf("unexpected totals: %+v", totals)
+	}
+	if ids := writer.InsertedIDs(); len(ids) != 3 {
+		t.Errorf("expected the IDs of 3 inserted postings, got %v", ids)
+	}
+}
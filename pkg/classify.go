@@ -0,0 +1,225 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	SeniorityIntern    = "intern"
+	SeniorityJunior    = "junior"
+	SeniorityMid       = "mid"
+	SenioritySenior    = "senior"
+	SeniorityStaff     = "staff"
+	SeniorityPrincipal = "principal"
+	SeniorityLead      = "lead"
+	SeniorityManager   = "manager"
+)
+
+const (
+	EmploymentFullTime  = "full-time"
+	EmploymentPartTime  = "part-time"
+	EmploymentContract  = "contract"
+	EmploymentFreelance = "freelance"
+)
+
+// ExperienceRange is the years of experience a posting asks for.
+// Max is 0 when the range is open-ended ("5+ years", "at least two years").
+type ExperienceRange struct {
+	Min int `bson:"min" json:"min"`
+	Max int `bson:"max,omitempty" json:"max,omitempty"`
+}
+
+// Label renders the range the way it is shown in experience distributions.
+func (r ExperienceRange) Label() string {
+	switch {
+	case r.Max == 0:
+		return fmt.Sprintf("%d+ years", r.Min)
+	case r.Min == r.Max:
+		return fmt.Sprintf("%d years", r.Min)
+	default:
+		return fmt.Sprintf("%d-%d years", r.Min, r.Max)
+	}
+}
+
+// titleSeniorityRules are checked in order against the title; the first match wins.
+var titleSeniorityRules = []struct {
+	level string
+	re    *regexp.Regexp
+}{
+	{SeniorityIntern, regexp.MustCompile(`\b(intern|internship|trainee|apprentice)\b`)},
+	{SeniorityPrincipal, regexp.MustCompile(`\b(principal|distinguished)\b`)},
+	{SeniorityStaff, regexp.MustCompile(`\bstaff\s+(engineer|developer|software|data|scientist|designer|architect|product|security|machine|backend|frontend|full[\s-]?stack)`)},
+	{SeniorityManager, regexp.MustCompile(`\b(head of|director|vp|vice president|cto|engineering manager|manager,? engineering|manager of)\b`)},
+	{SeniorityLead, regexp.MustCompile(`\b(lead|tech lead|team lead|leader)\b`)},
+	{SenioritySenior, regexp.MustCompile(`\b(senior|sr\.?|iii|iv)(\s|$|,)`)},
+	{SeniorityJunior, regexp.MustCompile(`\b(junior|jr\.?|entry[\s-]level|graduate|associate)(\s|$|,)`)},
+	{SeniorityMid, regexp.MustCompile(`\b(mid|mid[\s-]level|intermediate|ii)(\s|$|,)`)},
+}
+
+// icManagerTitle matches "manager" titles that are individual-contributor roles, not people management.
+var icManagerTitle = regexp.MustCompile(`\b(product|project|program|account|community|marketing|content|success|sales|social media|office|brand)\s+manager\b`)
+
+var genericManagerTitle = regexp.MustCompile(`\bmanager\b`)
+
+var descriptionSeniorityRules = []struct {
+	level string
+	re    *regexp.Regexp
+}{
+	{SeniorityIntern, regexp.MustCompile(`\b(internship|intern position)\b`)},
+	{SeniorityJunior, regexp.MustCompile(`\b(entry[\s-]level|junior position|new grad(uate)?s?)\b`)},
+}
+
+// ClassifySeniority derives a seniority level from the title, then from explicit
+// description phrases, and finally from the experience range. It returns "" when
+// nothing is known.
+func ClassifySeniority(title, description string, exp *ExperienceRange) string {
+	t := strings.ToLower(title)
+	for _, rule := range titleSeniorityRules {
+		if rule.re.MatchString(t) {
+			return rule.level
+		}
+	}
+	if genericManagerTitle.MatchString(t) && !icManagerTitle.MatchString(t) {
+		return SeniorityManager
+	}
+
+	d := strings.ToLower(description)
+	for _, rule := range descriptionSeniorityRules {
+		if rule.re.MatchString(d) {
+			return rule.level
+		}
+	}
+
+	if exp != nil {
+		switch {
+		case exp.Min <= 1:
+			return SeniorityJunior
+		case exp.Min <= 4:
+			return SeniorityMid
+		default:
+			return SenioritySenior
+		}
+	}
+	return ""
+}
+
+// employmentTypeRules match how postings state their employment type. A bare
+// "contract" is too often something else ("smart contracts") to count.
+var employmentTypeRules = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{EmploymentFreelance, regexp.MustCompile(`\b(freelance|freelancers?)\b`)},
+	{EmploymentContract, regexp.MustCompile(`\b(contractors?|contract[\s-](role|position|basis|job|engagement|to[\s-]hire)|on an? (fixed[\s-]term )?contract|\w+[\s-]months?[\s-]contract|fixed[\s-]term|temporary|b2b)\b|\(contract\)`)},
+	{EmploymentPartTime, regexp.MustCompile(`\bpart[\s-]?time\b`)},
+	{EmploymentFullTime, regexp.MustCompile(`\b(full[\s-]?time|permanent)\b`)},
+}
+
+// employmentNegation matches what, right before a mention, takes it back, as in
+// "not a contract role", "non-permanent" or "smart contract engagement".
+var employmentNegation = regexp.MustCompile(`\b(not|no|non|smart)(\s+an?)?[\s-]*$`)
+
+// ClassifyEmploymentType looks for an employment type in the title first and the
+// description second, taking the one mentioned first. It returns "" when the
+// posting does not say.
+func ClassifyEmploymentType(title, description string) string {
+	for _, text := range []string{title, description} {
+		t := strings.ToLower(text)
+		kind, first := "", len(t)
+		for _, rule := range employmentTypeRules {
+			for _, m := range rule.re.FindAllStringIndex(t, -1) {
+				if m[0] >= first {
+					break
+				}
+				if !employmentNegation.MatchString(t[:m[0]]) {
+					kind, first = rule.kind, m[0]
+					break
+				}
+			}
+		}
+		if kind != "" {
+			return kind
+		}
+	}
+	return ""
+}
+
+var numberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7,
+	"eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "fifteen": 15,
+}
+
+const yearsNumber = `(\d{1,2}|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|fifteen)`
+
+// experienceRe alternatives are ordered so that at the same position a range wins
+// over a "+" bound, which wins over "at least", "up to" and a bare number.
+var experienceRe = regexp.MustCompile(
+	`\b(?:` + yearsNumber + `\s*(?:-|–|to)\s*` + yearsNumber + `\+?\s*(?:years?|yrs?)` +
+		`|` + yearsNumber + `\s*\+\s*(?:years?|yrs?)` +
+		`|(?:at least|minimum of|minimum|min\.?|over|more than)\s+` + yearsNumber + `\s*\+?\s*(?:years?|yrs?)` +
+		`|up to\s+` + yearsNumber + `\s*(?:years?|yrs?)` +
+		`|` + yearsNumber + `\s*(?:years?|yrs?))\b`)
+
+// ExtractExperienceRange finds the first experience requirement in text, handling
+// "3-5 years", "5+ yrs", "at least two years" and "up to 3 years".
+func ExtractExperienceRange(text string) *ExperienceRange {
+	lower := strings.ToLower(text)
+	for _, loc := range experienceRe.FindAllStringSubmatchIndex(lower, -1) {
+		// "10 years ago" and "5 years old" describe the company, not the candidate
+		rest := strings.TrimSpace(lower[loc[1]:])
+		if strings.HasPrefix(rest, "ago") || strings.HasPrefix(rest, "old") {
+			continue
+		}
+
+		group := func(i int) (int, bool) {
+			if loc[2*i] < 0 {
+				return 0, false
+			}
+			return parseYears(lower[loc[2*i]:loc[2*i+1]])
+		}
+
+		var r ExperienceRange
+		if min, ok := group(1); ok {
+			max, _ := group(2)
+			r = ExperienceRange{Min: min, Max: max}
+		} else if min, ok := group(3); ok {
+			r = ExperienceRange{Min: min}
+		} else if min, ok := group(4); ok {
+			r = ExperienceRange{Min: min}
+		} else if max, ok := group(5); ok {
+			r = ExperienceRange{Min: 0, Max: max}
+		} else if min, ok := group(6); ok {
+			r = ExperienceRange{Min: min}
+		} else {
+			continue
+		}
+
+		if r.Min > 30 || r.Max > 30 || (r.Max != 0 && r.Max < r.Min) {
+			continue
+		}
+		return &r
+	}
+	return nil
+}
+
+func parseYears(s string) (int, bool) {
+	if n, ok := numberWords[s]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// ClassifyJob fills in the experience range and label, seniority and employment
+// type from the posting's title and description.
+func ClassifyJob(job *JobPosting) {
+	job.ExperienceRange = ExtractExperienceRange(job.Description)
+	if job.ExperienceRange != nil {
+		job.Experience = job.ExperienceRange.Label()
+	}
+	job.Seniority = ClassifySeniority(job.Title, job.Description, job.ExperienceRange)
+	job.EmploymentType = ClassifyEmploymentType(job.Title, job.Description)
+}
@@ -0,0 +1,96 @@
+package pkg
+
+import "testing"
+
+func TestExtractExperienceRange(t *testing.T) {
+	cases := []struct {
+		text string
+		want *ExperienceRange
+	}{
+		{"You have 3-5 years of experience with Go", &ExperienceRange{Min: 3, Max: 5}},
+		{"3 to 5 yrs building APIs", &ExperienceRange{Min: 3, Max: 5}},
+		{"5+ yrs in DevOps", &ExperienceRange{Min: 5}},
+		{"At least two years of professional experience", &ExperienceRange{Min: 2}},
+		{"up to 3 years of experience", &ExperienceRange{Min: 0, Max: 3}},
+		{"Founded 10 years ago, we need 4 years of React", &ExperienceRange{Min: 4}},
+		{"No experience requirement", nil},
+	}
+
+	for _, c := range cases {
+		got := ExtractExperienceRange(c.text)
+		switch {
+		case c.want == nil && got != nil:
+			t.Errorf("%q: expected no range, got %+v", c.text, *got)
+		case c.want != nil && got == nil:
+			t.Errorf("%q: expected %+v, got nil", c.text, *c.want)
+		case c.want != nil && *got != *c.want:
+			t.Errorf("%q: expected %+v, got %+v", c.text, *c.want, *got)
+		}
+	}
+}
+
+func TestClassifySeniority(t *testing.T) {
+	cases := []struct {
+		title string
+		desc  string
+		exp   *ExperienceRange
+		want  string
+	}{
+		{"software engineering intern", "", nil, SeniorityIntern},
+		{"principal engineer", "", nil, SeniorityPrincipal},
+		{"staff software engineer", "", nil, SeniorityStaff},
+		{"engineering manager, platform", "", nil, SeniorityManager},
+		{"senior product manager", "", nil, SenioritySenior},
+		{"product manager", "", nil, ""},
+		{"tech lead - backend", "", nil, SeniorityLead},
+		{"sr. devops engineer", "", nil, SenioritySenior},
+		{"junior frontend developer", "", nil, SeniorityJunior},
+		{"backend engineer ii", "", nil, SeniorityMid},
+		{"backend engineer i", "", nil, ""},
+		{"i18n engineer, i/o platform", "", nil, ""},
+		{"backend engineer", "this is an entry-level role", nil, SeniorityJunior},
+		{"backend engineer", "", &ExperienceRange{Min: 6}, SenioritySenior},
+		{"backend engineer", "", &ExperienceRange{Min: 3, Max: 5}, SeniorityMid},
+	}
+
+	for _, c := range cases {
+		if got := ClassifySeniority(c.title, c.desc, c.exp); got != c.want {
+			t.Errorf("%q: expected %q, got %q", c.title, c.want, got)
+		}
+	}
+}
+
+func TestClassifyEmploymentType(t *testing.T) {
+	cases := map[string]string{
+		"This is a full-time position":        EmploymentFullTime,
+		"Part time, 20 hours a week":          EmploymentPartTime,
+		"6-month contract with extension":     EmploymentContract,
+		"We are hiring freelancers worldwide": EmploymentFreelance,
+		"Join our team":                       "",
+
+		"This is a contract position":                   EmploymentContract,
+		"Contractors welcome":                           EmploymentContract,
+		"You will write and audit smart contracts":      "",
+		"Experience with smart contract engagement":     "",
+		"This is not a contract role":                   "",
+		"Full-time role, contractors welcome to apply":  EmploymentFullTime,
+		"B2B or employment, full-time":                  EmploymentContract,
+		"Non-permanent, part-time role":                 EmploymentPartTime,
+		"We sign every contract we make with customers": "",
+	}
+	for desc, want := range cases {
+		if got := ClassifyEmploymentType("developer", desc); got != want {
+			t.Errorf("%q: expected %q, got %q", desc, want, got)
+		}
+	}
+
+	titles := map[string]string{
+		"backend engineer (contract)": EmploymentContract,
+		"smart contract engineer":     "",
+	}
+	for title, want := range titles {
+		if got := ClassifyEmploymentType(title, ""); got != want {
+			t.Errorf("title %q: expected %q, got %q", title, want, got)
+		}
+	}
+}
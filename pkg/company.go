@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ErrCompanyNotFound is returned when no canonical company matches a name.
+var ErrCompanyNotFound = errors.New("company not found")
+
+// Company is the canonical employer record that every spelling of a company
+// name resolves to. ID is the normalized key, e.g. "proxify" for "Proxify AB".
+type Company struct {
+	ID        string            `bson:"_id" json:"id"`
+	Name      string            `bson:"name" json:"name"`
+	Aliases   []string          `bson:"aliases" json:"aliases"` // spellings seen in postings
+	Domain    string            `bson:"domain,omitempty" json:"domain,omitempty"`
+	Metadata  map[string]string `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	FirstSeen time.Time         `bson:"firstSeen" json:"first_seen"`
+	LastSeen  time.Time         `bson:"lastSeen" json:"last_seen"`
+}
+
+// legalSuffixes are dropped from the end of company names when normalizing.
+var legalSuffixes = map[string]bool{
+	"ab": true, "ag": true, "as": true, "bv": true, "co": true, "company": true, "corp": true,
+	"corporation": true, "gmbh": true, "inc": true, "incorporated": true, "limited": true,
+	"llc": true, "llp": true, "ltd": true, "nv": true, "oy": true, "plc": true, "pte": true,
+	"pty": true, "sa": true, "sarl": true, "sas": true, "se": true, "spa": true, "srl": true,
+}
+
+var (
+	companyDomainSuffix = regexp.MustCompile(`\.(com|io|co|ai|dev|app|net|org|mx|de|uk|tech)$`)
+	nonAlphanumeric     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// NormalizeCompanyKey reduces a company name to the key used to match spellings
+// of the same employer: lowercased, punctuation and legal suffixes removed, words
+// joined by "-". "Proxify AB", "proxify" and "Proxify, Inc." all become "proxify".
+func NormalizeCompanyKey(name string) string {
+	key := strings.ToLower(strings.TrimSpace(name))
+	key = companyDomainSuffix.ReplaceAllString(key, "")
+	key = strings.ReplaceAll(key, "&", " and ")
+
+	words := strings.Fields(nonAlphanumeric.ReplaceAllString(key, " "))
+	for len(words) > 1 && legalSuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, "-")
+}
+
+// CleanCompanyName tidies a company name for display without changing its spelling.
+func CleanCompanyName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// nonEmployerHosts are job boards and applicant tracking systems whose domain
+// says nothing about the employer.
+var nonEmployerHosts = []string{
+	"weworkremotely.com", "greenhouse.io", "lever.co", "workable.com", "ashbyhq.com",
+	"bamboohr.com", "smartrecruiters.com", "breezy.hr", "recruitee.com", "workday.com",
+	"myworkdayjobs.com", "jobvite.com", "teamtailor.com", "personio.de", "notion.site",
+	"google.com", "linkedin.com", "typeform.com", "forms.gle", "wellfound.com",
+}
+
+// CompanyDomain guesses the employer's domain from an apply URL, ignoring job
+// boards and applicant tracking systems. It returns "" when it cannot tell.
+func CompanyDomain(applyURL string) string {
+	u, err := url.Parse(applyURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range nonEmployerHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return ""
+		}
+	}
+	for _, prefix := range []string{"www.", "jobs.", "careers.", "apply.", "boards."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	return host
+}
+
+// resolveCompany finds or creates the canonical company for a raw name and
+// records the spelling as an alias.
+func (s *MongoStore) resolveCompany(ctx context.Context, name, applyURL string, now time.Time) (*Company, error) {
+	name = CleanCompanyName(name)
+	key := NormalizeCompanyKey(name)
+	if key == "" {
+		return nil, errors.New("empty company name")
+	}
+
+	var company Company
+	err := s.companies.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$addToSet": bson.M{"aliases": name},
+			"$set":      bson.M{"lastSeen": now},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&company)
+	if err == nil {
+		return &company, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	// First time we see this company; upserting on _id keeps concurrent inserts safe
+	err = s.companies.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$setOnInsert": bson.M{
+				"name":      name,
+				"domain":    CompanyDomain(applyURL),
+				"firstSeen": now,
+			},
+			"$addToSet": bson.M{"aliases": name},
+			"$set":      bson.M{"lastSeen": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&company)
+	if err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// GetCompany looks a company up by any spelling of its name or by its key.
+func (s *MongoStore) GetCompany(ctx context.Context, name string) (*Company, error) {
+	key := NormalizeCompanyKey(name)
+	var company Company
+	err := s.companies.FindOne(ctx, bson.M{"_id": key}).Decode(&company)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCompanyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// HiringPeriod is the number of postings a company published in one month.
+type HiringPeriod struct {
+	Period   string   `json:"period"` // YYYY-MM
+	Postings int      `json:"postings"`
+	Titles   []string `json:"titles"`
+}
+
+// CompanyHiringHistory buckets postings by the month they were posted, oldest first.
+func CompanyHiringHistory(jobs []JobPosting) []HiringPeriod {
+	buckets := make(map[string]*HiringPeriod)
+	for _, job := range jobs {
+		posted := job.PostedOn
+		if posted.IsZero() {
+			posted = job.CreatedAt
+		}
+		period := posted.Format("2006-01")
+
+		b, ok := buckets[period]
+		if !ok {
+			b = &HiringPeriod{Period: period}
+			buckets[period] = b
+		}
+		b.Postings++
+		b.Titles = append(b.Titles, job.Title)
+	}
+
+	history := make([]HiringPeriod, 0, len(buckets))
+	for _, b := range buckets {
+		sort.Strings(b.Titles)
+		history = append(history, *b)
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Period < history[j].Period })
+	return history
+}
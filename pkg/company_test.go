@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeCompanyKey(t *testing.T) {
+	cases := map[string]string{
+		"Proxify AB":               "proxify",
+		"proxify":                  "proxify",
+		"  Proxify  ":              "proxify",
+		"Proxify, Inc.":            "proxify",
+		"Sana Vita Operations LLC": "sana-vita-operations",
+		"Cheqpay.mx":               "cheqpay",
+		"Procter & Gamble Co":      "procter-and-gamble",
+		"Company":                  "company",
+		"":                         "",
+	}
+	for name, want := range cases {
+		if got := NormalizeCompanyKey(name); got != want {
+			t.Errorf("%q: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestCompanyDomain(t *testing.T) {
+	cases := map[string]string{
+		"https://careers.acme.com/jobs/123":        "acme.com",
+		"https://www.acme.io/apply":                "acme.io",
+		"https://boards.greenhouse.io/acme/jobs/1": "",
+		"https://weworkremotely.com/listings/acme": "",
+		"not a url": "",
+	}
+	for applyURL, want := range cases {
+		if got := CompanyDomain(applyURL); got != want {
+			t.Errorf("%q: expected %q, got %q", applyURL, want, got)
+		}
+	}
+}
+
+func TestCompanyHiringHistory(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	jobs := []JobPosting{
+		{Title: "backend engineer", PostedOn: day("2025-06-10")},
+		{Title: "devops engineer", PostedOn: day("2025-05-02")},
+		{Title: "designer", PostedOn: day("2025-06-01")},
+		{Title: "pm", CreatedAt: day("2025-04-20")},
+	}
+
+	history := CompanyHiringHistory(jobs)
+	if len(history) != 3 {
+		t.Fatalf("expected 3 periods, got %+v", history)
+	}
+	if history[0].Period != "2025-04" || history[2].Period != "2025-06" {
+		t.Errorf("periods not sorted oldest first: %+v", history)
+	}
+	if history[2].Postings != 2 || history[2].Titles[0] != "backend engineer" {
+		t.Errorf("unexpected June bucket: %+v", history[2])
+	}
+}
@@ -1,7 +1,9 @@
 package pkg
 
 import (
+	"context"
 	"log"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -20,10 +22,15 @@ func TestUpsertJob(t *testing.T) {
 		log.Println("Could not load .env file for test:", err)
 	}
 
-	err = ConnectMongo()
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping MongoDB integration test")
+	}
+
+	store, err := ConnectMongo()
 	if err != nil {
 		t.Fatalf("MongoDB connection failed: %v", err)
 	}
+	defer store.Close(context.Background())
 
 	job := JobPosting{
 		Title:       "DevOps Engineer",
@@ -39,7 +46,7 @@ func TestUpsertJob(t *testing.T) {
 		Experience:  "3+ years",
 	}
 
-	err = UpsertJob(job)
+	_, err = store.UpsertJob(context.Background(), job)
 	if err != nil {
 		t.Fatalf("Failed to upsert job: %v", err)
 	}
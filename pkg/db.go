@@ -2,8 +2,12 @@ package pkg
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -11,87 +15,909 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-var jobCollection *mongo.Collection
+// MongoStore is the MongoDB implementation of Store.
+type MongoStore struct {
+	client     *mongo.Client
+	db         *mongo.Database
+	jobs       *mongo.Collection
+	companies  *mongo.Collection
+	history    *mongo.Collection
+	searches   *mongo.Collection
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+	schedules  *mongo.Collection
+	crawls     *mongo.Collection
+	roles      *mongo.Collection
+	trends     *mongo.Collection
+}
 
-func EnsureTTLIndex(collection *mongo.Collection) error {
-	index := mongo.IndexModel{
-		Keys: bson.M{"CreatedAt": 1},
-		Options: options.Index().
-			SetExpireAfterSeconds(30 * 24 * 60 * 60). // 30 days
-			SetName("CreatedAt_TTL"),
+// ConnectMongo connects to the database in DATABASE_URL.
+func ConnectMongo() (*MongoStore, error) {
+	database_url := os.Getenv("DATABASE_URL")
+	if database_url == "" {
+		return nil, errors.New("DATABASE_URL not set")
 	}
+	return NewMongoStore(database_url)
+}
+
+func NewMongoStore(uri string) (*MongoStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	_, err := collection.Indexes().CreateOne(context.Background(), index)
+	// Job IDs are Mongo ObjectIDs but JobPosting.ID is a string
+	clientOptions := options.Client().ApplyURI(uri).
+		SetBSONOptions(&options.BSONOptions{ObjectIDAsHexString: true})
+	client, err := mongo.Connect(clientOptions)
 	if err != nil {
-		log.Printf("[mongo] Failed to create TTL index: %v", err)
-	} else {
-		log.Println("[mongo] TTL index on 'CreatedAt' ensured.")
+		return nil, err
 	}
 
-	return err
+	if err = client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	db := client.Database("job_scraper")
+	s := &MongoStore{
+		client:     client,
+		db:         db,
+		jobs:       db.Collection("jobs"),
+		companies:  db.Collection("companies"),
+		history:    db.Collection("job_history"),
+		searches:   db.Collection("saved_searches"),
+		webhooks:   db.Collection("webhooks"),
+		deliveries: db.Collection("webhook_deliveries"),
+		schedules:  db.Collection("schedules"),
+		crawls:     db.Collection("crawl_tasks"),
+		roles:      db.Collection("roles"),
+		trends:     db.Collection("trend_results"),
+	}
+
+	log.Println("[mongo] Connected to MongoDB")
+	return s, nil
 }
 
-func ConnectMongo() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (s *MongoStore) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+func (s *MongoStore) UpsertJob(ctx context.Context, job JobPosting) (UpsertResult, error) {
+	log.Printf("[mongo] Upserting job: %s at %s", job.Title, job.Company)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	database_url := os.Getenv("DATABASE_URL")
-	if database_url == "" {
-		log.Fatal("DATABASE_URL not set in .env file")
+	res, err := s.upsertJob(ctx, job)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another writer inserted the same posting between our lookup and insert;
+		// the retry finds it and updates it instead
+		res, err = s.upsertJob(ctx, job)
 	}
+	return res, err
+}
 
-	clientOptions := options.Client().ApplyURI(database_url)
-	client, err := mongo.Connect(clientOptions)
+func (s *MongoStore) upsertJob(ctx context.Context, job JobPosting) (UpsertResult, error) {
+	now := time.Now()
+	company, err := s.resolveCompany(ctx, job.Company, job.ApplyURL, now)
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("resolve company %q: %w", job.Company, err)
+	}
+	prepareJob(&job, company, now)
+
+	// Exact match on our own hash or on the hash of a posting merged into this one
+	filter := bson.M{"$or": bson.A{bson.M{"hash": job.Hash}, bson.M{"aliasHashes": job.Hash}}}
+
+	var existing JobPosting
+	err = s.jobs.FindOne(ctx, filter).Decode(&existing)
+	if err == nil {
+		// Update only if a material field changed, keeping the old values as a revision
+		if changes := materialChanges(existing, job); len(changes) > 0 {
+			if _, err := s.history.InsertOne(ctx, newRevision(existing.ID, job, changes, now)); err != nil {
+				return UpsertResult{}, fmt.Errorf("record revision: %w", err)
+			}
+			doc := replacementFor(existing, job, now)
+			doc.ID = "" // _id is immutable and stored as an ObjectID
+			_, err = s.jobs.ReplaceOne(ctx, idFilter(existing.ID), doc)
+			return UpsertResult{ID: existing.ID, Action: UpsertUpdated}, err
+		}
+
+		_, err = s.jobs.UpdateOne(ctx, idFilter(existing.ID), seenUpdate(now, bson.M{"lastUpdated": now}))
+		log.Printf("[mongo] Job already exists, updated lastUpdated for: %s at %s", job.Title, job.Company)
+		return UpsertResult{ID: existing.ID, Action: UpsertUnchanged}, err
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return UpsertResult{}, err
+	}
+
+	// Same job re-posted or seen on another board: attach it to the canonical posting
+	canonical, err := s.findNearDuplicate(ctx, job)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	if canonical != nil {
+		update := seenUpdate(now, bson.M{"lastUpdated": now})
+		update["$addToSet"] = bson.M{"aliasHashes": job.Hash}
+		if !hasSource(canonical.Sources, job.URL) {
+			update["$push"] = bson.M{"sources": sourceLink(job, now)}
+		}
+		_, err = s.jobs.UpdateOne(ctx, idFilter(canonical.ID), update)
+		log.Printf("[mongo] Near-duplicate merged into existing job: %s at %s", job.Title, job.Company)
+		return UpsertResult{ID: canonical.ID, Action: UpsertMerged}, err
+	}
+
+	res, err := s.jobs.InsertOne(ctx, newJobDocument(job, now))
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	log.Printf("[mongo] Job inserted: %s at %s", job.Title, job.Company)
+	return UpsertResult{ID: idString(res.InsertedID), Action: UpsertInserted}, nil
+}
+
+// findNearDuplicate looks for an already stored posting of the same job among
+// postings with the same cluster key.
+func (s *MongoStore) findNearDuplicate(ctx context.Context, job JobPosting) (*JobPosting, error) {
+	filter := bson.M{
+		"clusterKey": job.ClusterKey,
+		"postedOn": bson.M{
+			"$gte": job.PostedOn.Add(-NearDuplicateWindow),
+			"$lte": job.PostedOn.Add(NearDuplicateWindow),
+		},
+	}
+	cursor, err := s.jobs.Find(ctx, filter, options.Find().SetLimit(50))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []JobPosting
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+	return FindNearDuplicate(job, candidates), nil
+}
+
+func (s *MongoStore) GetJob(ctx context.Context, id string) (*JobPosting, error) {
+	var job JobPosting
+	err := s.jobs.FindOne(ctx, idFilter(id)).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *MongoStore) QueryJobs(ctx context.Context, q JobQuery) ([]JobPosting, error) {
+	newest := bson.D{{Key: "postedOn", Value: -1}, {Key: "_id", Value: -1}}
+	opts := options.Find().SetSort(newest)
+	switch q.sortOrder() {
+	case SortOldest:
+		opts.SetSort(bson.D{{Key: "postedOn", Value: 1}, {Key: "_id", Value: 1}})
+	case SortSalary:
+		opts.SetSort(bson.D{{Key: "salaryMin", Value: -1}, {Key: "_id", Value: -1}})
+	case SortRelevance:
+		opts.SetSort(append(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}, newest...))
+	}
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+	if skip := q.skip(); skip > 0 {
+		opts.SetSkip(int64(skip))
+	}
+
+	filter := mongoFilter(q)
+	if after := mongoAfter(q); after != nil {
+		filter = bson.M{"$and": bson.A{filter, after}}
+	}
+	cursor, err := s.jobs.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []JobPosting
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (s *MongoStore) CountJobs(ctx context.Context, q JobQuery) (int, error) {
+	n, err := s.jobs.CountDocuments(ctx, mongoFilter(q))
+	return int(n), err
+}
+
+// CountBy counts field values with an aggregation pipeline. For a dotted field
+// such as "locations.country" the array named by the first segment is unwound.
+func (s *MongoStore) CountBy(ctx context.Context, field string, q JobQuery, limit int) ([]CountResult, error) {
+	if err := checkCountableField(field); err != nil {
+		return nil, err
+	}
+
+	// Skip postings where the field is unknown, which is 0 for salary amounts
+	unknown := bson.A{nil, ""}
+	if field == "salaryMin" || field == "salaryMax" {
+		unknown = bson.A{nil, 0}
+	}
+
+	agg := []bson.M{}
+	if match := mongoFilter(q); len(match) > 0 {
+		agg = append(agg, bson.M{"$match": match})
+	}
+	agg = append(agg,
+		bson.M{"$unwind": "$" + strings.SplitN(field, ".", 2)[0]},
+		bson.M{"$match": bson.M{field: bson.M{"$nin": unknown}}},
+		bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	)
+	if limit > 0 {
+		agg = append(agg, bson.M{"$limit": limit})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	cursor, err := s.jobs.Aggregate(ctx, agg)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []CountResult
+	for cursor.Next(ctx) {
+		var r struct {
+			ID    interface{} `bson:"_id"` // a string, or a number for salaries
+			Count int         `bson:"count"`
+		}
+		if err := cursor.Decode(&r); err != nil {
+			return nil, err
+		}
+		results = append(results, CountResult{Value: fmt.Sprint(r.ID), Count: r.Count})
+	}
+
+	return results, cursor.Err()
+}
+
+func (s *MongoStore) DeleteJob(ctx context.Context, id string) error {
+	res, err := s.jobs.DeleteOne(ctx, idFilter(id))
 	if err != nil {
 		return err
 	}
+	if res.DeletedCount == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
 
-	if err = client.Ping(ctx, nil); err != nil {
+func (s *MongoStore) JobHistory(ctx context.Context, jobID string) ([]Revision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "changedAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.history.Find(ctx, bson.M{"jobId": jobID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []Revision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (s *MongoStore) CreateSearch(ctx context.Context, search SavedSearch) (SavedSearch, error) {
+	search.ID = ""
+	search.CreatedAt = time.Now()
+	res, err := s.searches.InsertOne(ctx, search)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	search.ID = idString(res.InsertedID)
+	return search, nil
+}
+
+func (s *MongoStore) ListSearches(ctx context.Context) ([]SavedSearch, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.searches.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	searches := []SavedSearch{}
+	if err := cursor.All(ctx, &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+func (s *MongoStore) DeleteSearch(ctx context.Context, id string) error {
+	res, err := s.searches.DeleteOne(ctx, idFilter(id))
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrSearchNotFound
+	}
+	return nil
+}
+
+func (s *MongoStore) CreateWebhook(ctx context.Context, hook Webhook) (Webhook, error) {
+	hook.ID = ""
+	hook.CreatedAt = time.Now()
+	res, err := s.webhooks.InsertOne(ctx, hook)
+	if err != nil {
+		return Webhook{}, err
+	}
+	hook.ID = idString(res.InsertedID)
+	return hook, nil
+}
+
+func (s *MongoStore) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	var hook Webhook
+	err := s.webhooks.FindOne(ctx, idFilter(id)).Decode(&hook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (s *MongoStore) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.webhooks.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	hooks := []Webhook{}
+	if err := cursor.All(ctx, &hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (s *MongoStore) DeleteWebhook(ctx context.Context, id string) error {
+	res, err := s.webhooks.DeleteOne(ctx, idFilter(id))
+	if err != nil {
 		return err
 	}
+	if res.DeletedCount == 0 {
+		return ErrWebhookNotFound
+	}
+	_, err = s.deliveries.DeleteMany(ctx, bson.M{"webhookId": id})
+	return err
+}
 
-	jobCollection = client.Database("job_scraper").Collection("jobs")
-	_ = EnsureTTLIndex(jobCollection) // Ensure TTL index on CreatedAt
+func (s *MongoStore) RecordDelivery(ctx context.Context, d Delivery) error {
+	d.ID = ""
+	_, err := s.deliveries.InsertOne(ctx, d)
+	return err
+}
 
-	log.Println("[mongo] Connected to MongoDB")
+func (s *MongoStore) CreateSchedule(ctx context.Context, schedule Schedule) (Schedule, error) {
+	schedule.ID = ""
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = schedule.CreatedAt
+	schedule.LastRun = nil
+	res, err := s.schedules.InsertOne(ctx, schedule)
+	if err != nil {
+		return Schedule{}, err
+	}
+	schedule.ID = idString(res.InsertedID)
+	return schedule, nil
+}
+
+func (s *MongoStore) GetSchedule(ctx context.Context, id string) (*Schedule, error) {
+	var schedule Schedule
+	err := s.schedules.FindOne(ctx, idFilter(id)).Decode(&schedule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (s *MongoStore) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.schedules.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	schedules := []Schedule{}
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule sets only the definition fields, so a run recorded meanwhile
+// is kept.
+func (s *MongoStore) UpdateSchedule(ctx context.Context, schedule Schedule) (Schedule, error) {
+	update := bson.M{"$set": bson.M{
+		"name":          schedule.Name,
+		"cron":          schedule.Cron,
+		"roles":         schedule.Roles,
+		"sources":       schedule.Sources,
+		"maxJobs":       schedule.MaxJobs,
+		"jitterSeconds": schedule.JitterSeconds,
+		"enabled":       schedule.Enabled,
+		"updatedAt":     time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated Schedule
+	err := s.schedules.FindOneAndUpdate(ctx, idFilter(schedule.ID), update, opts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Schedule{}, ErrScheduleNotFound
+	}
+	return updated, err
+}
+
+func (s *MongoStore) DeleteSchedule(ctx context.Context, id string) error {
+	res, err := s.schedules.DeleteOne(ctx, idFilter(id))
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrScheduleNotFound
+	}
 	return nil
 }
 
-func UpsertJob(job JobPosting) error {
-	log.Printf("[mongo] Upserting job: %s at %s", job.Title, job.Company)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (s *MongoStore) RecordScheduleRun(ctx context.Context, id string, run ScheduleRun) error {
+	res, err := s.schedules.UpdateOne(ctx, idFilter(id), bson.M{"$set": bson.M{"lastRun": run}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
 
-	job.Hash = GenerateHash(job.Title, job.Company, job.Location, job.PostedOn.Format("2006-01-02"))
-	job.DescriptionHash = GenerateHash(job.Description)
+// EnqueueCrawl upserts on the role's active task, so that concurrent
+// enqueues of a role leave one task.
+func (s *MongoStore) EnqueueCrawl(ctx context.Context, task CrawlTask) (CrawlTask, bool, error) {
+	task = newCrawlTask(task, time.Now())
+	task.ID = ""
+	active := bson.M{"roleKey": task.RoleKey, "status": bson.M{"$in": bson.A{TaskQueued, TaskRunning}}}
+	res, err := s.crawls.UpdateOne(ctx, active, bson.M{"$setOnInsert": task}, options.UpdateOne().SetUpsert(true))
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		// Another process enqueued the role between the match and the insert;
+		// the unique roleKey_active index kept the second task out
+	case err != nil:
+		return CrawlTask{}, false, err
+	case res.UpsertedID != nil:
+		task.ID = idString(res.UpsertedID)
+		return task, true, nil
+	}
 
-	filter := bson.M{"hash": job.Hash}
+	var existing CrawlTask
+	if err := s.crawls.FindOne(ctx, active).Decode(&existing); err != nil {
+		return CrawlTask{}, false, err
+	}
+	return existing, false, nil
+}
 
-	var existing JobPosting
-	err := jobCollection.FindOne(ctx, filter).Decode(&existing)
+func (s *MongoStore) LeaseCrawl(ctx context.Context, worker string, lease time.Duration) (*CrawlTask, error) {
+	now := time.Now()
+	_, err := s.crawls.UpdateMany(ctx, bson.M{
+		"status":     TaskRunning,
+		"leaseUntil": bson.M{"$lt": now},
+		"attempts":   bson.M{"$gte": MaxTaskAttempts},
+	}, bson.M{
+		"$set":   bson.M{"status": TaskFailed, "error": expiredLeaseError, "finishedAt": now},
+		"$unset": bson.M{"leaseUntil": ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ready := bson.M{"$or": bson.A{
+		bson.M{"status": TaskQueued},
+		bson.M{"status": TaskRunning, "leaseUntil": bson.M{"$lt": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{"status": TaskRunning, "worker": worker, "leaseUntil": now.Add(lease), "startedAt": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "enqueuedAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+	var task CrawlTask
+	err = s.crawls.FindOneAndUpdate(ctx, ready, update, opts).Decode(&task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrQueueEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
 
-	job.LastUpdated = time.Now()
+func (s *MongoStore) RenewLease(ctx context.Context, id, worker string, lease time.Duration) error {
+	return s.updateLeasedCrawl(ctx, id, worker, bson.M{"$set": bson.M{"leaseUntil": time.Now().Add(lease)}})
+}
 
-	// ToDo: this is a temporary fix. We should use the actual expiration date from the job posting.
-	job.ExpireAt = job.PostedOn.AddDate(0, 0, 30)
+func (s *MongoStore) FinishCrawl(ctx context.Context, id, worker, errMsg string) error {
+	status := TaskDone
+	if errMsg != "" {
+		status = TaskFailed
+	}
+	return s.updateLeasedCrawl(ctx, id, worker, bson.M{
+		"$set":   bson.M{"status": status, "error": errMsg, "finishedAt": time.Now()},
+		"$unset": bson.M{"leaseUntil": ""},
+	})
+}
 
-	if err == nil {
-		// Update only if description changed
-		if existing.DescriptionHash != job.DescriptionHash {
-			job.CreatedAt = existing.CreatedAt
-			_, err = jobCollection.ReplaceOne(ctx, filter, job)
+// updateLeasedCrawl applies update to a task if worker still holds its lease.
+func (s *MongoStore) updateLeasedCrawl(ctx context.Context, id, worker string, update bson.M) error {
+	filter := idFilter(id)
+	filter["status"] = TaskRunning
+	filter["worker"] = worker
+	res, err := s.crawls.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if _, err := s.GetCrawl(ctx, id); err != nil {
 			return err
 		}
+		return ErrLeaseLost
+	}
+	return nil
+}
 
-		_, err = jobCollection.UpdateOne(ctx, filter, bson.M{
-			"$set": bson.M{"lastUpdated": time.Now()},
-		})
-		log.Printf("[mongo] Job already exists, updated lastUpdated for: %s at %s", job.Title, job.Company)
-		return err
+func (s *MongoStore) GetCrawl(ctx context.Context, id string) (*CrawlTask, error) {
+	var task CrawlTask
+	err := s.crawls.FindOne(ctx, idFilter(id)).Decode(&task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCrawlTaskNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
 
-	job.CreatedAt = time.Now()
-	_, err = jobCollection.InsertOne(ctx, job)
-	log.Printf("[mongo] Job inserted: %s at %s", job.Title, job.Company)
+// ListCrawls relies on the TTL index of crawl_tasks to drop tasks finished
+// before TaskRetention.
+func (s *MongoStore) ListCrawls(ctx context.Context, status string, limit int) ([]CrawlTask, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "enqueuedAt", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cursor, err := s.crawls.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tasks := []CrawlTask{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *MongoStore) ListRoles(ctx context.Context) ([]Role, error) {
+	cursor, err := s.roles.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	roles := []Role{}
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SaveRole keys roles on their slug, so saving one again replaces it.
+func (s *MongoStore) SaveRole(ctx context.Context, role Role) error {
+	_, err := s.roles.ReplaceOne(ctx, bson.M{"_id": role.Slug}, role, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetTrendResult checks expireAt itself, as the TTL index only removes
+// expired results about once a minute.
+func (s *MongoStore) GetTrendResult(ctx context.Context, key string) (*TrendResult, error) {
+	var result TrendResult
+	err := s.trends.FindOne(ctx, bson.M{"_id": key, "expireAt": bson.M{"$gt": time.Now()}}).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrTrendResultNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *MongoStore) SaveTrendResult(ctx context.Context, result TrendResult) error {
+	_, err := s.trends.ReplaceOne(ctx, bson.M{"_id": result.Key}, result, options.Replace().SetUpsert(true))
 	return err
 }
+
+func (s *MongoStore) ClearTrendResults(ctx context.Context) error {
+	_, err := s.trends.DeleteMany(ctx, bson.M{})
+	return err
+}
+
+// ListDeliveries relies on the TTL index of webhook_deliveries to drop
+// deliveries past DeliveryRetention.
+func (s *MongoStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]Delivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deliveredAt", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cursor, err := s.deliveries.Find(ctx, bson.M{"webhookId": webhookID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []Delivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// seenUpdate is markSeen as an update document, merged with extra $set fields.
+func seenUpdate(at time.Time, set bson.M) bson.M {
+	set["status"] = StatusOpen
+	set["lastSeenAt"] = at
+	set["expireAt"] = at.Add(JobRetention)
+	return bson.M{
+		"$set":   set,
+		"$unset": bson.M{"closedAt": "", "closedReason": ""},
+	}
+}
+
+// urlFilter matches postings crawled from any of urls.
+func urlFilter(urls []string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"url": bson.M{"$in": urls}},
+		bson.M{"sources.url": bson.M{"$in": urls}},
+	}}
+}
+
+func (s *MongoStore) MarkSeen(ctx context.Context, urls []string, at time.Time) (int, error) {
+	if len(urls) == 0 {
+		return 0, nil
+	}
+	res, err := s.jobs.UpdateMany(ctx, urlFilter(urls), seenUpdate(at, bson.M{}))
+	if err != nil {
+		return 0, err
+	}
+	return int(res.MatchedCount), nil
+}
+
+func (s *MongoStore) CloseJobs(ctx context.Context, urls []string, reason string, at time.Time) (int, error) {
+	if len(urls) == 0 {
+		return 0, nil
+	}
+	filter := bson.M{"$and": bson.A{urlFilter(urls), bson.M{"status": bson.M{"$ne": StatusClosed}}}}
+	res, err := s.jobs.UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+		"status":       StatusClosed,
+		"closedAt":     at,
+		"closedReason": reason,
+		"expireAt":     at.Add(JobRetention),
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.ModifiedCount), nil
+}
+
+// SweepLifecycle runs sweepJob as two UpdateMany calls: close first, so that
+// postings unseen past CloseAfter skip the not-seen-recently state.
+func (s *MongoStore) SweepLifecycle(ctx context.Context, q JobQuery, policy LifecyclePolicy, now time.Time) (LifecycleSweep, error) {
+	// lastSeenAt, or lastUpdated for postings stored before lastSeenAt existed
+	seenField := bson.M{"$ifNull": bson.A{"$lastSeenAt", "$lastUpdated"}}
+	unseenSince := func(cutoff time.Time) bson.M {
+		return bson.M{"$or": bson.A{
+			bson.M{"lastSeenAt": bson.M{"$lt": cutoff}},
+			bson.M{"lastSeenAt": bson.M{"$exists": false}, "lastUpdated": bson.M{"$lt": cutoff}},
+		}}
+	}
+
+	var sweep LifecycleSweep
+	closeFilter := bson.M{"$and": bson.A{
+		mongoFilter(q),
+		bson.M{"status": bson.M{"$ne": StatusClosed}},
+		unseenSince(now.Add(-policy.CloseAfter)),
+	}}
+	// Look the postings up first so the sweep can report which were closed
+	var toClose []struct {
+		ID string `bson:"_id"`
+	}
+	cursor, err := s.jobs.Find(ctx, closeFilter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return sweep, err
+	}
+	if err := cursor.All(ctx, &toClose); err != nil {
+		return sweep, err
+	}
+	ids := make(bson.A, 0, len(toClose))
+	for _, doc := range toClose {
+		ids = append(ids, idFilter(doc.ID)["_id"])
+		sweep.ClosedIDs = append(sweep.ClosedIDs, doc.ID)
+	}
+	closeFilter = bson.M{"$and": bson.A{closeFilter, bson.M{"_id": bson.M{"$in": ids}}}}
+
+	closed, err := s.jobs.UpdateMany(ctx, closeFilter, mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"status":       StatusClosed,
+		"closedReason": ClosedDelisted,
+		"closedAt":     seenField,
+		"expireAt":     bson.M{"$add": bson.A{seenField, JobRetention.Milliseconds()}},
+	}}}})
+	if err != nil {
+		return sweep, err
+	}
+	sweep.Closed = int(closed.ModifiedCount)
+
+	staleFilter := bson.M{"$and": bson.A{
+		mongoFilter(q),
+		bson.M{"status": statusFilter(StatusOpen)},
+		unseenSince(now.Add(-policy.StaleAfter)),
+	}}
+	stale, err := s.jobs.UpdateMany(ctx, staleFilter, bson.M{"$set": bson.M{"status": StatusNotSeen}})
+	if err != nil {
+		return sweep, err
+	}
+	sweep.NotSeen = int(stale.ModifiedCount)
+	return sweep, nil
+}
+
+// mongoFilter translates a JobQuery into a find/$match filter.
+func mongoFilter(q JobQuery) bson.M {
+	filter := bson.M{}
+	var and bson.A // conditions that each need their own $or
+	if q.Role != "" {
+		filter["roles"] = RoleSlug(q.Role)
+	}
+	if terms := searchTerms(q.Text); len(terms) > 0 {
+		// Quoted terms are all required, unquoted ones would match any
+		filter["$text"] = bson.M{"$search": `"` + strings.Join(terms, `" "`) + `"`}
+	}
+	if q.CompanyID != "" {
+		filter["companyId"] = q.CompanyID
+	}
+	if q.Source != "" {
+		filter["source"] = q.Source
+	}
+	if len(q.URLs) > 0 {
+		and = append(and, urlFilter(q.URLs))
+	}
+	if location := strings.TrimSpace(q.Location); location != "" {
+		contains := bson.M{"$regex": regexp.QuoteMeta(location), "$options": "i"}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"location": contains},
+			bson.M{"locations.name": contains},
+			bson.M{"locations.country": contains},
+			bson.M{"locations.region": contains},
+			bson.M{"locations.countryCode": strings.ToUpper(location)},
+		}})
+	}
+	skills := bson.M{}
+	if all := NormalizeSkills(q.SkillsAll); len(all) > 0 {
+		skills["$all"] = all
+	}
+	if anyOf := NormalizeSkills(q.SkillsAny); len(anyOf) > 0 {
+		skills["$in"] = anyOf
+	}
+	if len(skills) > 0 {
+		filter["skills"] = skills
+	}
+	if q.SalaryMin > 0 || q.SalaryMax > 0 || q.SalaryKnown {
+		// salaryMax of 0 is an open-ended range
+		and = append(and, bson.M{"$or": bson.A{bson.M{"salaryMin": bson.M{"$gt": 0}}, bson.M{"salaryMax": bson.M{"$gt": 0}}}})
+		if q.SalaryMin > 0 {
+			and = append(and, bson.M{"$or": bson.A{bson.M{"salaryMax": 0}, bson.M{"salaryMax": bson.M{"$gte": q.SalaryMin}}}})
+		}
+		if q.SalaryMax > 0 {
+			filter["salaryMin"] = bson.M{"$lte": q.SalaryMax}
+		}
+	}
+	if q.Experience != nil {
+		years := *q.Experience
+		filter["experienceRange.min"] = bson.M{"$lte": years}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"experienceRange.max": bson.M{"$in": bson.A{nil, 0}}},
+			bson.M{"experienceRange.max": bson.M{"$gte": years}},
+		}})
+	}
+
+	posted := bson.M{}
+	if !q.PostedSince.IsZero() {
+		posted["$gte"] = q.PostedSince
+	}
+	if !q.PostedUntil.IsZero() {
+		posted["$lt"] = q.PostedUntil
+	}
+	if len(posted) > 0 {
+		filter["postedOn"] = posted
+	}
+
+	if q.Status != "" {
+		filter["status"] = statusFilter(q.Status)
+	}
+	if q.OpenOnly {
+		if q.Status == "" {
+			filter["status"] = bson.M{"$ne": StatusClosed}
+		}
+		filter["expireAt"] = bson.M{"$gt": time.Now()}
+	}
+	if !q.ClosedSince.IsZero() {
+		filter["closedAt"] = bson.M{"$gte": q.ClosedSince}
+	}
+	if !q.OpenAt.IsZero() {
+		and = append(and, bson.M{"$or": bson.A{bson.M{"closedAt": nil}, bson.M{"closedAt": bson.M{"$gte": q.OpenAt}}}})
+	}
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+	return filter
+}
+
+// mongoAfter is the keyset condition that continues q after its cursor, nil
+// when there is none.
+func mongoAfter(q JobQuery) bson.M {
+	c := q.After
+	if c == nil {
+		return nil
+	}
+	id := idFilter(c.ID)["_id"]
+	keyset := func(field string, value interface{}, op string) bson.M {
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{op: value}},
+			bson.M{field: value, "_id": bson.M{op: id}},
+		}}
+	}
+	switch q.sortOrder() {
+	case SortOldest:
+		return keyset("postedOn", c.PostedOn, "$gt")
+	case SortSalary:
+		return keyset("salaryMin", c.Salary, "$lt")
+	case SortRelevance:
+		return nil // paged by offset
+	default:
+		return keyset("postedOn", c.PostedOn, "$lt")
+	}
+}
+
+// statusFilter matches a lifecycle status. Postings without one are open.
+func statusFilter(status string) interface{} {
+	if status == StatusOpen {
+		return bson.M{"$in": bson.A{StatusOpen, nil, ""}}
+	}
+	return status
+}
+
+// idFilter matches a document by the string form of its _id, which is a hex
+// ObjectID for documents inserted by Mongo.
+func idFilter(id string) bson.M {
+	if oid, err := bson.ObjectIDFromHex(id); err == nil {
+		return bson.M{"_id": oid}
+	}
+	return bson.M{"_id": id}
+}
+
+func idString(id interface{}) string {
+	if oid, ok := id.(bson.ObjectID); ok {
+		return oid.Hex()
+	}
+	return fmt.Sprint(id)
+}
@@ -0,0 +1,298 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// bulkOp is one write of a batch. Several postings may share an op: a posting
+// seen twice in the batch, or a near-duplicate of a posting the batch inserts.
+type bulkOp struct {
+	model  mongo.WriteModel
+	insert *JobPosting // set for inserts, built into a model once the batch is planned
+	id     string      // _id of the written posting, when already known
+}
+
+// UpsertJobs upserts a batch with one lookup for exact matches, one for
+// near-duplicate candidates and a single unordered BulkWrite. New postings are
+// upserted on the unique hash index, so concurrent writers can't insert the
+// same posting twice.
+func (s *MongoStore) UpsertJobs(ctx context.Context, jobs []JobPosting) (BatchResult, error) {
+	result := newBatchResult(len(jobs))
+	if len(jobs) == 0 {
+		return result, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	now := time.Now()
+
+	// Canonicalize companies and compute hashes, one lookup per company spelling
+	companies := make(map[string]*Company)
+	prepared := make([]*JobPosting, len(jobs)) // nil where the posting already failed
+	for i, job := range jobs {
+		company, ok := companies[job.Company]
+		if !ok {
+			var err error
+			company, err = s.resolveCompany(ctx, job.Company, job.ApplyURL, now)
+			if err != nil {
+				result.record(i, job, UpsertResult{}, fmt.Errorf("resolve company %q: %w", job.Company, err))
+				continue
+			}
+			companies[job.Company] = company
+		}
+		prepareJob(&job, company, now)
+		prepared[i] = &job
+	}
+
+	byHash, candidates, err := s.loadBatchMatches(ctx, prepared)
+	if err != nil {
+		return result, err
+	}
+
+	var ops []*bulkOp
+	opOf := make([]*bulkOp, len(jobs))
+	actions := make([]UpsertAction, len(jobs))
+	pending := make(map[string]*bulkOp) // hash -> insert op of this batch
+	var revisions []interface{}
+
+	for i, job := range prepared {
+		if job == nil {
+			continue
+		}
+
+		if existing, ok := byHash[job.Hash]; ok {
+			if op, ok := pending[existing.Hash]; ok && existing.ID == "" {
+				// Same posting twice in this batch
+				opOf[i], actions[i] = op, UpsertUnchanged
+				continue
+			}
+			op := &bulkOp{id: existing.ID}
+			if changes := materialChanges(*existing, *job); len(changes) > 0 {
+				revisions = append(revisions, newRevision(existing.ID, *job, changes, now))
+				doc := replacementFor(*existing, *job, now)
+				*existing = doc // later postings of the batch compare against this version
+				doc.ID = ""     // _id is immutable and stored as an ObjectID
+				op.model = mongo.NewReplaceOneModel().SetFilter(idFilter(existing.ID)).SetReplacement(doc)
+				actions[i] = UpsertUpdated
+			} else {
+				op.model = mongo.NewUpdateOneModel().SetFilter(idFilter(existing.ID)).
+					SetUpdate(seenUpdate(now, bson.M{"lastUpdated": now}))
+				actions[i] = UpsertUnchanged
+			}
+			ops = append(ops, op)
+			opOf[i] = op
+			continue
+		}
+
+		if canonical := nearDuplicateOf(*job, candidates[job.ClusterKey]); canonical != nil {
+			if !containsString(canonical.AliasHashes, job.Hash) {
+				canonical.AliasHashes = append(canonical.AliasHashes, job.Hash)
+			}
+			pushSource := !hasSource(canonical.Sources, job.URL)
+			if pushSource {
+				canonical.Sources = append(canonical.Sources, sourceLink(*job, now))
+			}
+			byHash[job.Hash] = canonical
+
+			if canonical.ID == "" {
+				// Near-duplicate of a posting this batch inserts: canonical is that insert
+				opOf[i], actions[i] = pending[canonical.Hash], UpsertMerged
+				continue
+			}
+
+			update := seenUpdate(now, bson.M{"lastUpdated": now})
+			update["$addToSet"] = bson.M{"aliasHashes": job.Hash}
+			if pushSource {
+				update["$push"] = bson.M{"sources": sourceLink(*job, now)}
+			}
+			op := &bulkOp{id: canonical.ID, model: mongo.NewUpdateOneModel().SetFilter(idFilter(canonical.ID)).SetUpdate(update)}
+			ops = append(ops, op)
+			opOf[i], actions[i] = op, UpsertMerged
+			continue
+		}
+
+		doc := newJobDocument(*job, now)
+		op := &bulkOp{insert: &doc}
+		ops = append(ops, op)
+		opOf[i], actions[i] = op, UpsertInserted
+		pending[doc.Hash] = op
+		// The insert, its exact matches and its near-duplicates all share doc
+		candidates[doc.ClusterKey] = append(candidates[doc.ClusterKey], &doc)
+		byHash[doc.Hash] = &doc
+	}
+
+	if len(revisions) > 0 {
+		if _, err := s.history.InsertMany(ctx, revisions, options.InsertMany().SetOrdered(false)); err != nil {
+			return result, fmt.Errorf("record revisions: %w", err)
+		}
+	}
+
+	models := make([]mongo.WriteModel, len(ops))
+	index := make(map[*bulkOp]int, len(ops))
+	for n, op := range ops {
+		if op.insert != nil {
+			op.model = mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"hash": op.insert.Hash}).
+				SetUpdate(bson.M{"$setOnInsert": op.insert}).
+				SetUpsert(true)
+		}
+		models[n] = op.model
+		index[op] = n
+	}
+
+	failed := make(map[int]error)
+	var res *mongo.BulkWriteResult
+	if len(models) > 0 {
+		res, err = s.jobs.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		var bwe mongo.BulkWriteException
+		switch {
+		case errors.As(err, &bwe) && bwe.WriteConcernError == nil:
+			for _, we := range bwe.WriteErrors {
+				failed[we.Index] = we
+			}
+		case err != nil:
+			return result, err
+		}
+	}
+
+	// Inserts that found the hash already taken lost a race with another writer
+	var raced []string
+	for _, op := range ops {
+		n := index[op]
+		if op.insert == nil || failed[n] != nil {
+			continue
+		}
+		if id, ok := res.UpsertedIDs[int64(n)]; ok {
+			op.id = idString(id)
+		} else {
+			raced = append(raced, op.insert.Hash)
+		}
+	}
+	racedIDs, err := s.idsByHash(ctx, raced)
+	if err != nil {
+		return result, err
+	}
+
+	for i, job := range prepared {
+		if job == nil {
+			continue
+		}
+		op := opOf[i]
+		if err := failed[index[op]]; err != nil {
+			result.record(i, *job, UpsertResult{}, err)
+			continue
+		}
+		action := actions[i]
+		if op.id == "" {
+			op.id = racedIDs[op.insert.Hash]
+			if action == UpsertInserted {
+				action = UpsertUnchanged
+			}
+		}
+		result.record(i, *job, UpsertResult{ID: op.id, Action: action}, nil)
+	}
+
+	log.Printf("[mongo] Bulk upsert of %d jobs: %d inserted, %d updated, %d unchanged, %d merged, %d failed",
+		len(jobs), result.Inserted, result.Updated, result.Unchanged, result.Merged, result.Failed)
+	return result, nil
+}
+
+// loadBatchMatches fetches the stored postings a batch can match: exact hash or
+// alias-hash matches keyed by hash, and near-duplicate candidates by cluster key.
+// A posting found both ways is one value, so that what the batch merges into it
+// is seen by either lookup.
+func (s *MongoStore) loadBatchMatches(ctx context.Context, jobs []*JobPosting) (map[string]*JobPosting, map[string][]*JobPosting, error) {
+	var hashes, keys bson.A
+	var earliest time.Time
+	for _, job := range jobs {
+		if job == nil {
+			continue
+		}
+		hashes = append(hashes, job.Hash)
+		keys = append(keys, job.ClusterKey)
+		if earliest.IsZero() || job.PostedOn.Before(earliest) {
+			earliest = job.PostedOn
+		}
+	}
+
+	byHash := make(map[string]*JobPosting)
+	candidates := make(map[string][]*JobPosting)
+	if len(hashes) == 0 {
+		return byHash, candidates, nil
+	}
+
+	var existing []JobPosting
+	if err := s.findAll(ctx, bson.M{"$or": bson.A{
+		bson.M{"hash": bson.M{"$in": hashes}},
+		bson.M{"aliasHashes": bson.M{"$in": hashes}},
+	}}, &existing); err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]*JobPosting, len(existing))
+	for i := range existing {
+		job := &existing[i]
+		byID[job.ID] = job
+		byHash[job.Hash] = job
+		for _, alias := range job.AliasHashes {
+			byHash[alias] = job
+		}
+	}
+
+	var clustered []JobPosting
+	if err := s.findAll(ctx, bson.M{
+		"clusterKey": bson.M{"$in": keys},
+		"postedOn":   bson.M{"$gte": earliest.Add(-NearDuplicateWindow)},
+	}, &clustered); err != nil {
+		return nil, nil, err
+	}
+	for i := range clustered {
+		job := &clustered[i]
+		if found, ok := byID[job.ID]; ok {
+			job = found
+		}
+		candidates[job.ClusterKey] = append(candidates[job.ClusterKey], job)
+	}
+	return byHash, candidates, nil
+}
+
+// nearDuplicateOf is FindNearDuplicate over the shared candidates of a batch.
+func nearDuplicateOf(job JobPosting, candidates []*JobPosting) *JobPosting {
+	for _, candidate := range candidates {
+		if IsNearDuplicate(job, *candidate) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) findAll(ctx context.Context, filter bson.M, out *[]JobPosting) error {
+	opts := options.Find().SetSort(bson.D{{Key: "postedOn", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := s.jobs.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, out)
+}
+
+func (s *MongoStore) idsByHash(ctx context.Context, hashes []string) (map[string]string, error) {
+	ids := make(map[string]string, len(hashes))
+	if len(hashes) == 0 {
+		return ids, nil
+	}
+	var jobs []JobPosting
+	if err := s.findAll(ctx, bson.M{"hash": bson.M{"$in": hashes}}, &jobs); err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		ids[job.Hash] = job.ID
+	}
+	return ids, nil
+}
@@ -0,0 +1,519 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// mongoMigrationRecord is a document of the migrations collection.
+type mongoMigrationRecord struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+func (s *MongoStore) Migrate(ctx context.Context, opts MigrateOptions) ([]MigrationResult, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runMigrations(ctx, s.migrations(), applied, func(ctx context.Context, m migration, at time.Time) error {
+		_, err := s.db.Collection("migrations").InsertOne(ctx, mongoMigrationRecord{
+			Version: m.Version, Description: m.Description, AppliedAt: at,
+		})
+		return err
+	}, opts)
+}
+
+func (s *MongoStore) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migrationStatus(s.migrations(), applied), nil
+}
+
+func (s *MongoStore) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	cursor, err := s.db.Collection("migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []mongoMigrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time, len(records))
+	for _, r := range records {
+		applied[r.Version] = r.AppliedAt
+	}
+	return applied, nil
+}
+
+// migrations lists the MongoDB schema history. Never edit an applied
+// migration; add a new one instead.
+func (s *MongoStore) migrations() []migration {
+	return []migration{
+		{1, "TTL index on expireAt instead of the unused CreatedAt key", func(ctx context.Context, run *migrationRun) error {
+			if err := s.dropIndexIfExists(ctx, run, s.jobs, "CreatedAt_TTL"); err != nil {
+				return err
+			}
+			return s.createIndexes(ctx, run, s.jobs, mongo.IndexModel{
+				Keys: bson.D{{Key: "expireAt", Value: 1}},
+				// expireAt already includes the retention period
+				Options: options.Index().SetExpireAfterSeconds(0).SetName("expireAt_TTL"),
+			})
+		}},
+		{2, "Backfill lifecycle fields on postings stored before they existed", func(ctx context.Context, run *migrationRun) error {
+			filter := bson.M{"status": bson.M{"$exists": false}}
+			seen := bson.M{"$ifNull": bson.A{"$lastUpdated", "$createdAt", "$$NOW"}}
+			return s.updateMany(ctx, run, s.jobs, filter, "set status open, lastSeenAt and expireAt", mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{"status": StatusOpen, "lastSeenAt": seen}}},
+				{{Key: "$set", Value: bson.M{"expireAt": bson.M{"$add": bson.A{"$lastSeenAt", JobRetention.Milliseconds()}}}}},
+			})
+		}},
+		{3, "Remove duplicate postings sharing a hash, keeping the most recently updated", func(ctx context.Context, run *migrationRun) error {
+			return s.removeDuplicateHashes(ctx, run)
+		}},
+		{4, "Unique hash index and dedupe lookup indexes", func(ctx context.Context, run *migrationRun) error {
+			return s.createIndexes(ctx, run, s.jobs,
+				mongo.IndexModel{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true).SetName("hash_unique")},
+				mongo.IndexModel{Keys: bson.D{{Key: "aliasHashes", Value: 1}}, Options: options.Index().SetName("aliasHashes")},
+				mongo.IndexModel{Keys: bson.D{{Key: "clusterKey", Value: 1}, {Key: "postedOn", Value: -1}}, Options: options.Index().SetName("clusterKey_postedOn")},
+				mongo.IndexModel{Keys: bson.D{{Key: "url", Value: 1}}, Options: options.Index().SetName("url")},
+				mongo.IndexModel{Keys: bson.D{{Key: "sources.url", Value: 1}}, Options: options.Index().SetName("sources_url")},
+			)
+		}},
+		{5, "Query indexes: postedOn, skills, company, status and full-text search", func(ctx context.Context, run *migrationRun) error {
+			return s.createIndexes(ctx, run, s.jobs,
+				mongo.IndexModel{Keys: bson.D{{Key: "postedOn", Value: -1}, {Key: "_id", Value: -1}}, Options: options.Index().SetName("postedOn")},
+				mongo.IndexModel{Keys: bson.D{{Key: "skills", Value: 1}}, Options: options.Index().SetName("skills")},
+				mongo.IndexModel{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "postedOn", Value: -1}}, Options: options.Index().SetName("companyId_postedOn")},
+				mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lastSeenAt", Value: 1}}, Options: options.Index().SetName("status_lastSeenAt")},
+				mongo.IndexModel{
+					Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+					// A match in the title says more than one in the description
+					Options: options.Index().SetName("title_description_text").
+						SetWeights(bson.D{{Key: "title", Value: 10}, {Key: "description", Value: 1}}),
+				},
+			)
+		}},
+		{6, "Job history and company alias indexes", func(ctx context.Context, run *migrationRun) error {
+			if err := s.createIndexes(ctx, run, s.history, mongo.IndexModel{
+				Keys: bson.D{{Key: "jobId", Value: 1}, {Key: "changedAt", Value: 1}}, Options: options.Index().SetName("jobId_changedAt"),
+			}); err != nil {
+				return err
+			}
+			return s.createIndexes(ctx, run, s.companies, mongo.IndexModel{
+				Keys: bson.D{{Key: "aliasKeys", Value: 1}}, Options: options.Index().SetName("aliasKeys"),
+			})
+		}},
+		{7, "Normalize skills on stored postings", func(ctx context.Context, run *migrationRun) error {
+			return s.normalizeStoredSkills(ctx, run)
+		}},
+		{8, "Parse salary ranges and index salary and experience for search", func(ctx context.Context, run *migrationRun) error {
+			if err := s.parseStoredSalaries(ctx, run); err != nil {
+				return err
+			}
+			return s.createIndexes(ctx, run, s.jobs,
+				mongo.IndexModel{Keys: bson.D{{Key: "salaryMin", Value: -1}, {Key: "_id", Value: -1}}, Options: options.Index().SetName("salaryMin")},
+				mongo.IndexModel{Keys: bson.D{{Key: "experienceRange.min", Value: 1}}, Options: options.Index().SetName("experienceRange_min")},
+			)
+		}},
+		{9, "Webhook delivery log indexes, expiring after DeliveryRetention", func(ctx context.Context, run *migrationRun) error {
+			return s.createIndexes(ctx, run, s.deliveries,
+				mongo.IndexModel{Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "deliveredAt", Value: -1}}, Options: options.Index().SetName("webhookId_deliveredAt")},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "deliveredAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(int32(DeliveryRetention.Seconds())).SetName("deliveredAt_TTL"),
+				},
+			)
+		}},
+		{10, "Crawl queue indexes, finished tasks expiring after TaskRetention", func(ctx context.Context, run *migrationRun) error {
+			return s.createIndexes(ctx, run, s.crawls,
+				mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "enqueuedAt", Value: 1}}, Options: options.Index().SetName("status_enqueuedAt")},
+				mongo.IndexModel{Keys: bson.D{{Key: "roleKey", Value: 1}, {Key: "status", Value: 1}}, Options: options.Index().SetName("roleKey_status")},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "finishedAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(int32(TaskRetention.Seconds())).SetName("finishedAt_TTL"),
+				},
+			)
+		}},
+		{11, "Classify stored postings into taxonomy roles and index roles", func(ctx context.Context, run *migrationRun) error {
+			if err := s.classifyStoredRoles(ctx, run); err != nil {
+				return err
+			}
+			return s.createIndexes(ctx, run, s.jobs, mongo.IndexModel{
+				Keys: bson.D{{Key: "roles", Value: 1}}, Options: options.Index().SetName("roles"),
+			})
+		}},
+		{12, "Trend result cache, expiring on expireAt", func(ctx context.Context, run *migrationRun) error {
+			return s.createIndexes(ctx, run, s.trends, mongo.IndexModel{
+				Keys:    bson.D{{Key: "expireAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0).SetName("expireAt_TTL"),
+			})
+		}},
+		{13, "Drop the unused company aliasKeys index", func(ctx context.Context, run *migrationRun) error {
+			return s.dropIndexIfExists(ctx, run, s.companies, "aliasKeys")
+		}},
+		{14, "Backfill companyId on postings stored before company resolution and recompute their dedupe keys, merging collisions", func(ctx context.Context, run *migrationRun) error {
+			return s.backfillCompanyIDs(ctx, run)
+		}},
+		{15, "Allow one active crawl task per role", func(ctx context.Context, run *migrationRun) error {
+			if err := s.failDuplicateCrawls(ctx, run); err != nil {
+				return err
+			}
+			return s.createIndexes(ctx, run, s.crawls, mongo.IndexModel{
+				Keys: bson.D{{Key: "roleKey", Value: 1}},
+				Options: options.Index().SetName("roleKey_active").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"status": bson.M{"$in": bson.A{TaskQueued, TaskRunning}}}),
+			})
+		}},
+		{16, "Index postings by when they were closed", func(ctx context.Context, run *migrationRun) error {
+			return s.createIndexes(ctx, run, s.jobs, mongo.IndexModel{
+				Keys: bson.D{{Key: "closedAt", Value: 1}}, Options: options.Index().SetName("closedAt").SetSparse(true),
+			})
+		}},
+	}
+}
+
+func (s *MongoStore) createIndexes(ctx context.Context, run *migrationRun, coll *mongo.Collection, models ...mongo.IndexModel) error {
+	for _, model := range models {
+		name := indexName(model)
+		err := run.step(fmt.Sprintf("create index %s on %s", name, coll.Name()), func() error {
+			_, err := coll.Indexes().CreateOne(ctx, model)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexName(model mongo.IndexModel) string {
+	opts := &options.IndexOptions{}
+	if model.Options != nil {
+		for _, set := range model.Options.List() {
+			_ = set(opts)
+		}
+	}
+	if opts.Name == nil {
+		return fmt.Sprint(model.Keys)
+	}
+	return *opts.Name
+}
+
+func (s *MongoStore) dropIndexIfExists(ctx context.Context, run *migrationRun, coll *mongo.Collection, name string) error {
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 26 { // 26: NamespaceNotFound, nothing to drop
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if spec.Name == name {
+			return run.step(fmt.Sprintf("drop index %s on %s", name, coll.Name()), func() error {
+				return coll.Indexes().DropOne(ctx, name)
+			})
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) updateMany(ctx context.Context, run *migrationRun, coll *mongo.Collection, filter bson.M, what string, update interface{}) error {
+	n, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	return run.step(fmt.Sprintf("%s on %d %s documents", what, n, coll.Name()), func() error {
+		_, err := coll.UpdateMany(ctx, filter, update)
+		return err
+	})
+}
+
+func (s *MongoStore) removeDuplicateHashes(ctx context.Context, run *migrationRun) error {
+	cursor, err := s.jobs.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "lastUpdated", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$hash", "ids": bson.M{"$push": "$_id"}, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var extra bson.A
+	for cursor.Next(ctx) {
+		var group struct {
+			IDs []interface{} `bson:"ids"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return err
+		}
+		extra = append(extra, group.IDs[1:]...) // the first is the most recently updated
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+
+	return run.step(fmt.Sprintf("delete %d duplicate postings", len(extra)), func() error {
+		_, err := s.jobs.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": extra}})
+		return err
+	})
+}
+
+func (s *MongoStore) normalizeStoredSkills(ctx context.Context, run *migrationRun) error {
+	cursor, err := s.jobs.Find(ctx, bson.M{"skills.0": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"skills": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var models []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID     interface{} `bson:"_id"`
+			Skills []string    `bson:"skills"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		normalized := NormalizeSkills(doc.Skills)
+		if equalStrings(normalized, doc.Skills) {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"skills": normalized}}))
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return s.bulkWrite(ctx, run, "normalize skills", models)
+}
+
+func (s *MongoStore) parseStoredSalaries(ctx context.Context, run *migrationRun) error {
+	unparsed := bson.M{"salaryMin": bson.M{"$exists": false}}
+	err := s.updateMany(ctx, run, s.jobs, bson.M{"salaryMin": bson.M{"$exists": false}, "salary": bson.M{"$in": bson.A{nil, ""}}},
+		"set empty salary range", bson.M{"$set": bson.M{"salaryMin": 0, "salaryMax": 0}})
+	if err != nil {
+		return err
+	}
+
+	cursor, err := s.jobs.Find(ctx, unparsed, options.Find().SetProjection(bson.M{"salary": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var models []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID     interface{} `bson:"_id"`
+			Salary string      `bson:"salary"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		min, max, currency := ParseSalary(doc.Salary)
+		set := bson.M{"salaryMin": min, "salaryMax": max}
+		if currency != "" {
+			set["salaryCurrency"] = currency
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": doc.ID}).SetUpdate(bson.M{"$set": set}))
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return s.bulkWrite(ctx, run, "parse salary ranges", models)
+}
+
+func (s *MongoStore) classifyStoredRoles(ctx context.Context, run *migrationRun) error {
+	cursor, err := s.jobs.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"title": 1, "skills": 1, "roles": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var models []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID     interface{} `bson:"_id"`
+			Title  string      `bson:"title"`
+			Skills []string    `bson:"skills"`
+			Roles  []string    `bson:"roles"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		roles := ClassifyRoles(doc.Title, doc.Skills)
+		if equalStrings(roles, doc.Roles) {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"roles": roles}}))
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return s.bulkWrite(ctx, run, "classify roles", models)
+}
+
+// backfillCompanyIDs resolves the company of postings stored before they had
+// a companyId, then recomputes their hash and cluster key from it. A posting
+// whose new hash is already taken is folded into the one holding it.
+func (s *MongoStore) backfillCompanyIDs(ctx context.Context, run *migrationRun) error {
+	filter := bson.M{"$or": bson.A{bson.M{"companyId": bson.M{"$exists": false}}, bson.M{"companyId": ""}}}
+	n, err := s.jobs.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	return run.step(fmt.Sprintf("resolve companies and rehash %d postings", n), func() error {
+		cursor, err := s.jobs.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "postedOn", Value: 1}, {Key: "_id", Value: 1}}))
+		if err != nil {
+			return err
+		}
+		var legacy []JobPosting
+		if err := cursor.All(ctx, &legacy); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, job := range legacy {
+			if err := s.rehashJob(ctx, job, now); err != nil {
+				return fmt.Errorf("posting %s: %w", job.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// rehashJob gives job its companyId and dedupe keys, or folds it into the
+// posting that already has its new hash.
+func (s *MongoStore) rehashJob(ctx context.Context, job JobPosting, now time.Time) error {
+	company, err := s.resolveCompany(ctx, job.Company, job.ApplyURL, now)
+	if err != nil {
+		return err
+	}
+	job.Company, job.CompanyID = company.Name, company.ID
+	setDedupeKeys(&job)
+
+	var canonical JobPosting
+	err = s.jobs.FindOne(ctx, bson.M{
+		"_id": bson.M{"$ne": idFilter(job.ID)["_id"]},
+		"$or": bson.A{bson.M{"hash": job.Hash}, bson.M{"aliasHashes": job.Hash}},
+	}).Decode(&canonical)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		_, err := s.jobs.UpdateOne(ctx, idFilter(job.ID), bson.M{"$set": bson.M{
+			"company":    job.Company,
+			"companyId":  job.CompanyID,
+			"hash":       job.Hash,
+			"clusterKey": job.ClusterKey,
+		}})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	absorbDuplicate(&canonical, job)
+	_, err = s.jobs.UpdateOne(ctx, idFilter(canonical.ID), bson.M{"$set": bson.M{
+		"aliasHashes": canonical.AliasHashes,
+		"sources":     canonical.Sources,
+	}})
+	if err != nil {
+		return err
+	}
+	if _, err := s.history.UpdateMany(ctx, bson.M{"jobId": job.ID}, bson.M{"$set": bson.M{"jobId": canonical.ID}}); err != nil {
+		return err
+	}
+	_, err = s.jobs.DeleteOne(ctx, idFilter(job.ID))
+	return err
+}
+
+// bulkWrite applies models as one step, in unordered batches of 500.
+func (s *MongoStore) bulkWrite(ctx context.Context, run *migrationRun, what string, models []mongo.WriteModel) error {
+	if len(models) == 0 {
+		return nil
+	}
+
+	return run.step(fmt.Sprintf("%s on %d postings", what, len(models)), func() error {
+		for start := 0; start < len(models); start += 500 {
+			end := min(start+500, len(models))
+			if _, err := s.jobs.BulkWrite(ctx, models[start:end], options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// failDuplicateCrawls fails every active crawl task but the first of its role,
+// so that a role's active task can be made unique.
+func (s *MongoStore) failDuplicateCrawls(ctx context.Context, run *migrationRun) error {
+	cursor, err := s.crawls.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$in": bson.A{TaskQueued, TaskRunning}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "enqueuedAt", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$roleKey", "ids": bson.M{"$push": "$_id"}, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var groups []struct {
+		IDs []bson.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+	var duplicates bson.A
+	for _, g := range groups {
+		for _, id := range g.IDs[1:] {
+			duplicates = append(duplicates, id)
+		}
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	return run.step(fmt.Sprintf("fail %d crawl tasks duplicating an active task of their role", len(duplicates)), func() error {
+		_, err := s.crawls.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}}, bson.M{
+			"$set":   bson.M{"status": TaskFailed, "error": duplicateTaskError, "finishedAt": time.Now()},
+			"$unset": bson.M{"leaseUntil": ""},
+		})
+		return err
+	})
+}
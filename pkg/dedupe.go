@@ -0,0 +1,157 @@
+package pkg
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// NearDuplicateMaxDistance is the largest SimHash Hamming distance at which two
+	// descriptions are considered the same posting.
+	NearDuplicateMaxDistance = 10
+	// NearDuplicateWindow is how far apart two postings may be posted and still be
+	// treated as the same job (re-posts, cross-posts on other boards).
+	NearDuplicateWindow = 30 * 24 * time.Hour
+
+	shingleSize = 3
+)
+
+// SourceLink records one place a clustered posting was seen.
+type SourceLink struct {
+	Source    string    `bson:"source" json:"source"`
+	URL       string    `bson:"url" json:"url"`
+	ApplyURL  string    `bson:"applyUrl" json:"apply_url"`
+	Hash      string    `bson:"hash" json:"hash"`
+	PostedOn  time.Time `bson:"postedOn" json:"posted_on"`
+	FirstSeen time.Time `bson:"firstSeen" json:"first_seen"`
+}
+
+var (
+	titleParenthetical = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+	titleNoise         = regexp.MustCompile(`\b(remote|fully remote|100% remote|worldwide|anywhere|m/f/d|f/m/d|w/m/d)\b`)
+	wordPattern        = regexp.MustCompile(`[a-z0-9+#]+`)
+)
+
+// NormalizeTitle reduces a job title to the words that identify the job, dropping
+// case, punctuation, parenthesised notes and "remote"-style noise.
+// "Senior DevOps Engineer (Azure) - Remote" becomes "senior devops engineer".
+func NormalizeTitle(title string) string {
+	t := strings.ToLower(title)
+	t = titleParenthetical.ReplaceAllString(t, " ")
+	t = titleNoise.ReplaceAllString(t, " ")
+	return strings.Join(wordPattern.FindAllString(t, -1), " ")
+}
+
+// ClusterKey groups postings that could be the same job: same company, same title.
+func ClusterKey(companyID, title string) string {
+	return companyID + "|" + NormalizeTitle(title)
+}
+
+// SimHash computes a 64-bit SimHash over word shingles of text. Similar texts
+// produce hashes with a small Hamming distance.
+func SimHash(text string) uint64 {
+	words := wordPattern.FindAllString(strings.ToLower(text), -1)
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	addShingle := func(s string) {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		sum := fmix64(h.Sum64())
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(words) < shingleSize {
+		addShingle(strings.Join(words, " "))
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		addShingle(strings.Join(words[i:i+shingleSize], " "))
+	}
+
+	var hash uint64
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// fmix64 is the MurmurHash3 finalizer. FNV alone barely changes the high bits
+// when only the last byte differs, which would make unrelated shingles agree.
+func fmix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// HammingDistance counts the bits that differ between two SimHashes.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// IsNearDuplicate reports whether candidate is the same job as job: same cluster
+// key, posted close together, and near-identical descriptions. Postings without
+// a description only match on the cluster key.
+func IsNearDuplicate(job, candidate JobPosting) bool {
+	if job.ClusterKey == "" || job.ClusterKey != candidate.ClusterKey {
+		return false
+	}
+
+	gap := job.PostedOn.Sub(candidate.PostedOn)
+	if gap < 0 {
+		gap = -gap
+	}
+	if gap > NearDuplicateWindow {
+		return false
+	}
+
+	if job.Description == "" || candidate.Description == "" {
+		return true
+	}
+	return HammingDistance(uint64(job.SimHash), uint64(candidate.SimHash)) <= NearDuplicateMaxDistance
+}
+
+// FindNearDuplicate returns the first candidate that is the same job, or nil.
+func FindNearDuplicate(job JobPosting, candidates []JobPosting) *JobPosting {
+	for i := range candidates {
+		if IsNearDuplicate(job, candidates[i]) {
+			return &candidates[i]
+		}
+	}
+	return nil
+}
+
+func sourceLink(job JobPosting, seen time.Time) SourceLink {
+	return SourceLink{
+		Source:    job.Source,
+		URL:       job.URL,
+		ApplyURL:  job.ApplyURL,
+		Hash:      job.Hash,
+		PostedOn:  job.PostedOn,
+		FirstSeen: seen,
+	}
+}
+
+func hasSource(links []SourceLink, url string) bool {
+	for _, l := range links {
+		if l.URL == url {
+			return true
+		}
+	}
+	return false
+}
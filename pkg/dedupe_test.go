@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	cases := map[string]string{
+		"Senior DevOps Engineer (Azure) - Remote": "senior devops engineer",
+		"senior devops engineer":                  "senior devops engineer",
+		"C# / .NET Developer [Worldwide]":         "c# net developer",
+	}
+	for title, want := range cases {
+		if got := NormalizeTitle(title); got != want {
+			t.Errorf("%q: expected %q, got %q", title, want, got)
+		}
+	}
+}
+
+func TestSimHashSimilarity(t *testing.T) {
+	base := "We are looking for a DevOps engineer to run our Kubernetes clusters on AWS, " +
+		"automate infrastructure with Terraform, improve CI/CD pipelines, and be part of an on-call rotation. " +
+		"You will work closely with product engineers and own observability with Prometheus and Grafana."
+	reposted := base + " Apply today!"
+	different := "Our marketing team needs a copywriter to produce blog posts, newsletters and landing pages " +
+		"for a consumer audience, working with designers on campaigns across social channels."
+
+	if d := HammingDistance(SimHash(base), SimHash(reposted)); d > NearDuplicateMaxDistance {
+		t.Errorf("expected near-identical descriptions to be close, distance %d", d)
+	}
+	if d := HammingDistance(SimHash(base), SimHash(different)); d <= NearDuplicateMaxDistance {
+		t.Errorf("expected unrelated descriptions to be far apart, distance %d", d)
+	}
+}
+
+func TestIsNearDuplicate(t *testing.T) {
+	now := time.Now()
+	desc := "Build and operate Go microservices, PostgreSQL and Kafka pipelines for our payments platform."
+	job := JobPosting{
+		ClusterKey:  ClusterKey("acme", "Backend Engineer (Remote)"),
+		Description: desc,
+		SimHash:     int64(SimHash(desc)),
+		PostedOn:    now,
+	}
+
+	repost := job
+	repost.PostedOn = now.AddDate(0, 0, -1)
+	if !IsNearDuplicate(job, repost) {
+		t.Error("expected a re-post a day later to be a near-duplicate")
+	}
+
+	other := job
+	other.ClusterKey = ClusterKey("acme", "Frontend Engineer")
+	if IsNearDuplicate(job, other) {
+		t.Error("different titles must not be merged")
+	}
+
+	old := job
+	old.PostedOn = now.Add(-2 * NearDuplicateWindow)
+	if IsNearDuplicate(job, old) {
+		t.Error("postings far apart in time must not be merged")
+	}
+
+	candidates := []JobPosting{other, repost}
+	if got := FindNearDuplicate(job, candidates); got == nil || got.ClusterKey != job.ClusterKey {
+		t.Errorf("expected to find the re-post, got %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// HistoryStore returns the recorded revisions of a posting.
+type HistoryStore interface {
+	// JobHistory lists the revisions of a posting, oldest first.
+	JobHistory(ctx context.Context, jobID string) ([]Revision, error)
+}
+
+// Revision records one material change to a stored posting, written by
+// UpsertJob before the posting is replaced.
+type Revision struct {
+	ID        string        `bson:"_id,omitempty" json:"id,omitempty"`
+	JobID     string        `bson:"jobId" json:"job_id"`
+	ChangedAt time.Time     `bson:"changedAt" json:"changed_at"`
+	SourceURL string        `bson:"sourceUrl" json:"source_url"` // listing the changed version was crawled from
+	Changes   []FieldChange `bson:"changes" json:"changes"`
+}
+
+// FieldChange is the diff of one field. Scalar fields set Old and New, list
+// fields such as skills set Added and Removed.
+type FieldChange struct {
+	Field   string   `bson:"field" json:"field"`
+	Old     string   `bson:"old,omitempty" json:"old,omitempty"`
+	New     string   `bson:"new,omitempty" json:"new,omitempty"`
+	Added   []string `bson:"added,omitempty" json:"added,omitempty"`
+	Removed []string `bson:"removed,omitempty" json:"removed,omitempty"`
+}
+
+// trackedFields are the fields whose changes are material, named by their
+// stored path. Derived fields (hashes, classification) follow from these.
+var trackedFields = []struct {
+	name string
+	get  func(JobPosting) string
+}{
+	{"title", func(j JobPosting) string { return j.Title }},
+	{"location", func(j JobPosting) string { return j.Location }},
+	{"salary", func(j JobPosting) string { return j.Salary }},
+	{"description", func(j JobPosting) string { return j.Description }},
+	{"applyUrl", func(j JobPosting) string { return j.ApplyURL }},
+	{"experience", func(j JobPosting) string { return j.Experience }},
+	{"employmentType", func(j JobPosting) string { return j.EmploymentType }},
+}
+
+// DiffJobs lists the material differences between a stored posting and a newly
+// crawled version of it. No changes means the posting can be left as is.
+func DiffJobs(old, new JobPosting) []FieldChange {
+	var changes []FieldChange
+	for _, f := range trackedFields {
+		if o, n := f.get(old), f.get(new); o != n {
+			changes = append(changes, FieldChange{Field: f.name, Old: o, New: n})
+		}
+	}
+
+	// Skill order depends on the extractor, not on the posting
+	added, removed := setDiff(old.Skills, new.Skills)
+	if len(added) > 0 || len(removed) > 0 {
+		changes = append(changes, FieldChange{Field: "skills", Added: added, Removed: removed})
+	}
+	return changes
+}
+
+func setDiff(old, new []string) (added, removed []string) {
+	for _, s := range new {
+		if !containsString(old, s) && !containsString(added, s) {
+			added = append(added, s)
+		}
+	}
+	for _, s := range old {
+		if !containsString(new, s) && !containsString(removed, s) {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func newRevision(jobID string, job JobPosting, changes []FieldChange, now time.Time) Revision {
+	return Revision{
+		JobID:     jobID,
+		ChangedAt: now,
+		SourceURL: job.URL,
+		Changes:   changes,
+	}
+}
+
+// materialChanges is DiffJobs as UpsertJob applies it. A posting matched through
+// an alias hash is a near-duplicate from another listing; it never replaces the
+// canonical posting, so its differences are not changes.
+func materialChanges(existing, job JobPosting) []FieldChange {
+	if existing.Hash != job.Hash {
+		return nil
+	}
+	return DiffJobs(existing, job)
+}
@@ -0,0 +1,118 @@
+package pkg
+
+import (
+	"context"
+	"time"
+)
+
+// Posting lifecycle. A posting is open while crawls keep finding it, becomes
+// not-seen-recently when it drops out of listings, and is closed once it has been
+// gone long enough or its page returns 404/410.
+const (
+	StatusOpen    = "open"
+	StatusNotSeen = "not-seen-recently"
+	StatusClosed  = "closed"
+
+	ClosedGone     = "gone"     // posting page returned 404 or 410
+	ClosedDelisted = "delisted" // absent from listings for LifecyclePolicy.CloseAfter
+
+	// JobRetention is how long a posting is kept after it was last seen or
+	// closed. ExpireAt is set from it and the TTL index deletes on ExpireAt.
+	JobRetention = 90 * 24 * time.Hour
+)
+
+// LifecycleStore moves postings through their lifecycle.
+type LifecycleStore interface {
+	// MarkSeen records that the postings at urls are still listed, reopening
+	// them if needed. It returns the number of postings touched.
+	MarkSeen(ctx context.Context, urls []string, at time.Time) (int, error)
+	// CloseJobs closes the postings at urls, e.g. after their page returned 404.
+	CloseJobs(ctx context.Context, urls []string, reason string, at time.Time) (int, error)
+	// SweepLifecycle demotes postings matching q that have not been seen recently.
+	SweepLifecycle(ctx context.Context, q JobQuery, policy LifecyclePolicy, now time.Time) (LifecycleSweep, error)
+}
+
+// LifecyclePolicy says how long a posting may go unseen before it changes state.
+type LifecyclePolicy struct {
+	StaleAfter time.Duration // open -> not-seen-recently
+	CloseAfter time.Duration // -> closed (delisted)
+}
+
+func DefaultLifecyclePolicy() LifecyclePolicy {
+	return LifecyclePolicy{
+		StaleAfter: 3 * 24 * time.Hour,
+		CloseAfter: 14 * 24 * time.Hour,
+	}
+}
+
+// LifecycleSweep counts the postings a sweep moved.
+type LifecycleSweep struct {
+	NotSeen   int      `json:"not_seen"`
+	Closed    int      `json:"closed"`
+	ClosedIDs []string `json:"closed_ids,omitempty"`
+}
+
+// JobStatus returns the lifecycle status of job. Postings stored before the
+// lifecycle existed have no status and count as open.
+func JobStatus(job JobPosting) string {
+	if job.Status == "" {
+		return StatusOpen
+	}
+	return job.Status
+}
+
+// lastSeen falls back to lastUpdated for postings stored before lastSeenAt existed.
+func lastSeen(job JobPosting) time.Time {
+	if job.LastSeenAt.IsZero() {
+		return job.LastUpdated
+	}
+	return job.LastSeenAt
+}
+
+func markSeen(job *JobPosting, at time.Time) {
+	job.Status = StatusOpen
+	job.LastSeenAt = at
+	job.ClosedAt = nil
+	job.ClosedReason = ""
+	job.ExpireAt = at.Add(JobRetention)
+}
+
+func closeJob(job *JobPosting, reason string, at time.Time) {
+	job.Status = StatusClosed
+	job.ClosedAt = &at
+	job.ClosedReason = reason
+	job.ExpireAt = at.Add(JobRetention)
+}
+
+// sweepJob applies policy to job and reports whether its status changed.
+// Delisted postings are closed as of when they were last seen.
+func sweepJob(job *JobPosting, policy LifecyclePolicy, now time.Time) bool {
+	status := JobStatus(*job)
+	if status == StatusClosed {
+		return false
+	}
+
+	unseen := now.Sub(lastSeen(*job))
+	switch {
+	case unseen >= policy.CloseAfter:
+		closeJob(job, ClosedDelisted, lastSeen(*job))
+		return true
+	case unseen >= policy.StaleAfter && status == StatusOpen:
+		job.Status = StatusNotSeen
+		return true
+	}
+	return false
+}
+
+// hasURL reports whether job was crawled from any of urls.
+func hasURL(job JobPosting, urls []string) bool {
+	if containsString(urls, job.URL) {
+		return true
+	}
+	for _, s := range job.Sources {
+		if containsString(urls, s.URL) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package pkg
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	RemotePolicyRemote = "remote"
+	RemotePolicyHybrid = "hybrid"
+	RemotePolicyOnsite = "onsite"
+)
+
+// LocationEntry is one structured place a posting is open to. Name is the
+// canonical label used in trend reports, e.g. "Worldwide", "Germany" or
+// "Berlin, Germany". Unrecognised places only carry Name.
+type LocationEntry struct {
+	Name        string `bson:"name" json:"name"`
+	CountryCode string `bson:"countryCode,omitempty" json:"country_code,omitempty"` // ISO 3166-1 alpha-2
+	Country     string `bson:"country,omitempty" json:"country,omitempty"`
+	Region      string `bson:"region,omitempty" json:"region,omitempty"`
+	City        string `bson:"city,omitempty" json:"city,omitempty"`
+}
+
+//go:embed data/gazetteer.json
+var gazetteerJSON []byte
+
+type gazetteerCountry struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Region  string   `json:"region"`
+	Aliases []string `json:"aliases"`
+}
+
+type gazetteer struct {
+	Regions []struct {
+		Name    string   `json:"name"`
+		Aliases []string `json:"aliases"`
+	} `json:"regions"`
+	Countries []gazetteerCountry `json:"countries"`
+	Cities    []struct {
+		Name    string   `json:"name"`
+		Country string   `json:"country"`
+		Aliases []string `json:"aliases"`
+	} `json:"cities"`
+}
+
+// places maps every lowercased name and alias in the gazetteer to its entry.
+// countryCodes maps lowercased ISO codes to their country; they are kept apart
+// because a bare code is as often a US state, e.g. "CA" in "San Francisco, CA".
+var places, countryCodes = loadGazetteer()
+
+func loadGazetteer() (map[string]LocationEntry, map[string]LocationEntry) {
+	var g gazetteer
+	if err := json.Unmarshal(gazetteerJSON, &g); err != nil {
+		panic(fmt.Sprintf("pkg: invalid embedded gazetteer: %v", err))
+	}
+
+	lookup := make(map[string]LocationEntry)
+	add := func(alias string, e LocationEntry) {
+		alias = strings.ToLower(alias)
+		if _, exists := lookup[alias]; !exists {
+			lookup[alias] = e
+		}
+	}
+
+	// Regions first so that e.g. "europe" never resolves to something narrower
+	for _, r := range g.Regions {
+		e := LocationEntry{Name: r.Name, Region: r.Name}
+		add(r.Name, e)
+		for _, a := range r.Aliases {
+			add(a, e)
+		}
+	}
+
+	countries := make(map[string]gazetteerCountry)
+	codes := make(map[string]LocationEntry)
+	for _, c := range g.Countries {
+		countries[c.Code] = c
+		e := LocationEntry{Name: c.Name, CountryCode: c.Code, Country: c.Name, Region: c.Region}
+		codes[strings.ToLower(c.Code)] = e
+		add(c.Name, e)
+		for _, a := range c.Aliases {
+			add(a, e)
+		}
+	}
+
+	for _, c := range g.Cities {
+		country := countries[c.Country]
+		e := LocationEntry{
+			Name:        c.Name + ", " + country.Name,
+			CountryCode: country.Code,
+			Country:     country.Name,
+			Region:      country.Region,
+			City:        c.Name,
+		}
+		add(c.Name, e)
+		for _, a := range c.Aliases {
+			add(a, e)
+		}
+	}
+
+	return lookup, codes
+}
+
+var (
+	locationSplitter = regexp.MustCompile(`\s*(?:,|;|\||/|\(|\)|\bor\b|\band\b|&)\s*`)
+	locationNoise    = regexp.MustCompile(`\b(only|remote|based|hq|headquarters|preferred|anywhere in)\b`)
+)
+
+// ParseLocations turns a free-text location such as "Anywhere in the World, USA Only"
+// into structured entries using the embedded gazetteer. Countries already implied
+// by a city in the same string are dropped.
+func ParseLocations(raw string) []LocationEntry {
+	var entries []LocationEntry
+	seen := make(map[string]bool)
+	var previous *LocationEntry
+	for _, token := range locationSplitter.Split(raw, -1) {
+		e, ok := lookupPlace(token)
+		if !ok {
+			continue
+		}
+		if code := strings.ToLower(e.Name); len(code) == 2 && e.Country == "" && e.Region == "" {
+			// An unknown two-letter token is a state after a US city, and
+			// otherwise a country code if it is the first place or follows one
+			// outside the US, as in "Milan, IT"
+			if previous != nil && previous.City != "" && previous.CountryCode == "US" {
+				continue
+			}
+			if country, isCode := countryCodes[code]; isCode {
+				e = country
+			}
+		}
+		previous = &e
+		if !seen[e.Name] {
+			seen[e.Name] = true
+			entries = append(entries, e)
+		}
+	}
+
+	cityCountries := make(map[string]bool)
+	for _, e := range entries {
+		if e.City != "" {
+			cityCountries[e.CountryCode] = true
+		}
+	}
+	result := entries[:0]
+	for _, e := range entries {
+		if e.City == "" && e.CountryCode != "" && cityCountries[e.CountryCode] {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+func lookupPlace(token string) (LocationEntry, bool) {
+	original := strings.Trim(strings.TrimSpace(token), ".-–:")
+	if original == "" {
+		return LocationEntry{}, false
+	}
+
+	key := strings.ToLower(original)
+	if e, ok := places[key]; ok {
+		return e, true
+	}
+	if isTimezone(key) {
+		// Time zones are extracted separately by ExtractTimezones
+		return LocationEntry{}, false
+	}
+
+	key = strings.Join(strings.Fields(locationNoise.ReplaceAllString(key, " ")), " ")
+	key = strings.Trim(key, ".-–:")
+	if key == "" {
+		// The token was only noise such as "Remote"
+		return LocationEntry{}, false
+	}
+	if e, ok := places[key]; ok {
+		return e, true
+	}
+
+	// Keep unknown places so that nothing the posting said is lost
+	return LocationEntry{Name: original}, true
+}
+
+var (
+	hybridPattern = regexp.MustCompile(`\bhybrid\b`)
+	onsitePattern = regexp.MustCompile(`\b(on-?site|in[- ]office|office[- ]based|in[- ]person)\b`)
+	remotePattern = regexp.MustCompile(`\b(remote|work from home|wfh|distributed team)\b`)
+)
+
+// remoteOnlySources are job boards that only list remote positions.
+var remoteOnlySources = map[string]bool{
+	"weworkremotely.com": true,
+}
+
+// ClassifyRemotePolicy decides whether a posting is remote, hybrid or onsite. The
+// location and title are trusted over the description, which often mentions
+// offices in passing. It returns "" when nothing is known.
+func ClassifyRemotePolicy(location, title, description, source string) string {
+	headline := strings.ToLower(location + " " + title)
+	switch {
+	case hybridPattern.MatchString(headline):
+		return RemotePolicyHybrid
+	case onsitePattern.MatchString(headline):
+		return RemotePolicyOnsite
+	case remotePattern.MatchString(headline), remoteOnlySources[source]:
+		if hybridPattern.MatchString(strings.ToLower(description)) {
+			return RemotePolicyHybrid
+		}
+		return RemotePolicyRemote
+	}
+
+	desc := strings.ToLower(description)
+	switch {
+	case hybridPattern.MatchString(desc):
+		return RemotePolicyHybrid
+	case remotePattern.MatchString(desc):
+		return RemotePolicyRemote
+	case onsitePattern.MatchString(desc):
+		return RemotePolicyOnsite
+	}
+	return ""
+}
+
+var (
+	utcOffsetPattern = regexp.MustCompile(`\b(?:utc|gmt)\s*([+\-−–])\s*(\d{1,2})(?::?(\d{2}))?`)
+	tzAbbreviation   = `(pst|pdt|pt|mst|mdt|mt|cst|cdt|ct|est|edt|et|gmt|bst|cet|cest|eet|eest|wet|ist|sgt|jst|aest|aedt|nzst)`
+	// In a description, only trust abbreviations that sit next to time zone wording
+	tzNearWording = regexp.MustCompile(`\b` + tzAbbreviation + `\b\s*(?:time\s*zones?|timezones?|hours|business hours|overlap)|(?:time\s*zones?|timezones?|overlap with)\s+(?:\w+\s+){0,3}?` + tzAbbreviation + `\b`)
+	tzInLocation  = regexp.MustCompile(`\b` + tzAbbreviation + `\b`)
+	tzOnly        = regexp.MustCompile(`^` + tzAbbreviation + `$`)
+)
+
+func isTimezone(s string) bool {
+	return tzOnly.MatchString(s) || utcOffsetPattern.MatchString(s)
+}
+
+// ExtractTimezones finds time zone constraints such as "UTC-5", "UTC+01:00" or
+// "CET". Abbreviations are read from the location as-is, but from the description
+// only when they appear next to words like "time zone" or "hours".
+func ExtractTimezones(location, description string) []string {
+	set := make(map[string]bool)
+
+	for _, text := range []string{location, description} {
+		for _, m := range utcOffsetPattern.FindAllStringSubmatch(strings.ToLower(text), -1) {
+			sign := "+"
+			if m[1] != "+" {
+				sign = "-"
+			}
+			tz := "UTC" + sign + strings.TrimLeft(m[2], "0")
+			if strings.TrimLeft(m[2], "0") == "" {
+				tz = "UTC"
+			} else if m[3] != "" && m[3] != "00" {
+				tz += ":" + m[3]
+			}
+			set[tz] = true
+		}
+	}
+
+	for _, m := range tzInLocation.FindAllStringSubmatch(strings.ToLower(location), -1) {
+		set[strings.ToUpper(m[1])] = true
+	}
+	for _, m := range tzNearWording.FindAllStringSubmatch(strings.ToLower(description), -1) {
+		abbr := m[1]
+		if abbr == "" {
+			abbr = m[2]
+		}
+		set[strings.ToUpper(abbr)] = true
+	}
+
+	if len(set) == 0 {
+		return nil
+	}
+	zones := make([]string, 0, len(set))
+	for tz := range set {
+		zones = append(zones, tz)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// NormalizeLocation fills the structured location fields of a posting from its
+// free-text location, title and description.
+func NormalizeLocation(job *JobPosting) {
+	job.Locations = ParseLocations(job.Location)
+	job.RemotePolicy = ClassifyRemotePolicy(job.Location, job.Title, job.Description, job.Source)
+	job.Timezones = ExtractTimezones(job.Location, job.Description)
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLocations(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want []string
+	}{
+		{"Anywhere in the World, USA Only", []string{"Worldwide", "United States"}},
+		{"Europe Only, UK Only", []string{"Europe", "United Kingdom"}},
+		{"Berlin, Germany", []string{"Berlin, Germany"}},
+		{"Remote (LATAM)", []string{"Latin America"}},
+		{"Americas Only (EST)", []string{"Americas"}},
+		{"Latin America Only / Canada", []string{"Latin America", "Canada"}},
+		{"Atlantis", []string{"Atlantis"}},
+		{"San Francisco, CA", []string{"San Francisco, United States"}},
+		{"Milan, IT", []string{"Milan", "Italy"}},
+		{"DE", []string{"Germany"}},
+		{"", nil},
+	}
+
+	for _, c := range cases {
+		var got []string
+		for _, e := range ParseLocations(c.raw) {
+			got = append(got, e.Name)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: expected %v, got %v", c.raw, c.want, got)
+		}
+	}
+
+	us := ParseLocations("USA Only")[0]
+	if us.CountryCode != "US" || us.Region != "North America" {
+		t.Errorf("unexpected USA entry: %+v", us)
+	}
+	berlin := ParseLocations("Berlin")[0]
+	if berlin.City != "Berlin" || berlin.CountryCode != "DE" {
+		t.Errorf("unexpected Berlin entry: %+v", berlin)
+	}
+}
+
+func TestClassifyRemotePolicy(t *testing.T) {
+	cases := []struct {
+		location, title, desc, source string
+		want                          string
+	}{
+		{"Anywhere in the World", "devops engineer", "", "weworkremotely.com", RemotePolicyRemote},
+		{"Berlin", "backend engineer (hybrid)", "", "", RemotePolicyHybrid},
+		{"London", "backend engineer", "You will work on-site in our office", "", RemotePolicyOnsite},
+		{"USA Only", "designer", "This is a fully remote role", "", RemotePolicyRemote},
+		{"USA Only", "designer", "", "weworkremotely.com", RemotePolicyRemote},
+		{"", "designer", "", "", ""},
+	}
+
+	for _, c := range cases {
+		if got := ClassifyRemotePolicy(c.location, c.title, c.desc, c.source); got != c.want {
+			t.Errorf("%q/%q: expected %q, got %q", c.location, c.title, c.want, got)
+		}
+	}
+}
+
+func TestExtractTimezones(t *testing.T) {
+	got := ExtractTimezones("Americas Only (EST)", "You must overlap with CET hours; we work UTC-5 to UTC+1:30 and GMT+0.")
+	want := []string{"CET", "EST", "UTC", "UTC+1:30", "UTC-5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if zones := ExtractTimezones("Worldwide", "Our ET team is great"); zones != nil {
+		t.Errorf("abbreviations without time zone wording should be ignored, got %v", zones)
+	}
+}
@@ -0,0 +1,610 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps postings in process. It implements the same upsert and
+// dedupe semantics as MongoStore, which makes it suitable for tests and for
+// running the pipeline without a database.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	jobs       map[string]JobPosting
+	companies  map[string]*Company
+	history    map[string][]Revision
+	searches   map[string]SavedSearch
+	webhooks   map[string]Webhook
+	deliveries []Delivery
+	schedules  map[string]Schedule
+	crawls     map[string]CrawlTask
+	roles      map[string]Role
+	trends     map[string]TrendResult
+	nextID     int
+
+	now func() time.Time // overridable clock for tests
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:      make(map[string]JobPosting),
+		companies: make(map[string]*Company),
+		history:   make(map[string][]Revision),
+		searches:  make(map[string]SavedSearch),
+		webhooks:  make(map[string]Webhook),
+		schedules: make(map[string]Schedule),
+		crawls:    make(map[string]CrawlTask),
+		roles:     make(map[string]Role),
+		trends:    make(map[string]TrendResult),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) UpsertJob(_ context.Context, job JobPosting) (UpsertResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	company, err := resolveCompanyIn(s.companies, job.Company, job.ApplyURL, now)
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("resolve company %q: %w", job.Company, err)
+	}
+	prepareJob(&job, company, now)
+
+	if existing, ok := s.findByHash(job.Hash); ok {
+		if changes := materialChanges(existing, job); len(changes) > 0 {
+			s.nextID++
+			rev := newRevision(existing.ID, job, changes, now)
+			rev.ID = fmt.Sprintf("%024x", s.nextID)
+			s.history[existing.ID] = append(s.history[existing.ID], rev)
+			s.jobs[existing.ID] = replacementFor(existing, job, now)
+			return UpsertResult{ID: existing.ID, Action: UpsertUpdated}, nil
+		}
+		existing.LastUpdated = now
+		markSeen(&existing, now)
+		s.jobs[existing.ID] = existing
+		return UpsertResult{ID: existing.ID, Action: UpsertUnchanged}, nil
+	}
+
+	var candidates []JobPosting
+	for _, j := range s.jobs {
+		if j.ClusterKey == job.ClusterKey {
+			candidates = append(candidates, j)
+		}
+	}
+	sortJobs(candidates)
+	if canonical := FindNearDuplicate(job, candidates); canonical != nil {
+		merged := *canonical
+		if !containsString(merged.AliasHashes, job.Hash) {
+			merged.AliasHashes = append(merged.AliasHashes, job.Hash)
+		}
+		if !hasSource(merged.Sources, job.URL) {
+			merged.Sources = append(merged.Sources, sourceLink(job, now))
+		}
+		merged.LastUpdated = now
+		markSeen(&merged, now)
+		s.jobs[merged.ID] = merged
+		return UpsertResult{ID: merged.ID, Action: UpsertMerged}, nil
+	}
+
+	s.nextID++
+	job.ID = fmt.Sprintf("%024x", s.nextID)
+	s.jobs[job.ID] = newJobDocument(job, now)
+	return UpsertResult{ID: job.ID, Action: UpsertInserted}, nil
+}
+
+func (s *MemoryStore) UpsertJobs(ctx context.Context, jobs []JobPosting) (BatchResult, error) {
+	return upsertEach(ctx, s, jobs), nil
+}
+
+func (s *MemoryStore) findByHash(hash string) (JobPosting, bool) {
+	for _, j := range s.jobs {
+		if j.Hash == hash || containsString(j.AliasHashes, hash) {
+			return j, true
+		}
+	}
+	return JobPosting{}, false
+}
+
+func (s *MemoryStore) GetJob(_ context.Context, id string) (*JobPosting, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (s *MemoryStore) QueryJobs(_ context.Context, q JobQuery) ([]JobPosting, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := s.matching(q)
+	if skip := q.skip(); skip > 0 {
+		if skip >= len(jobs) {
+			return nil, nil
+		}
+		jobs = jobs[skip:]
+	}
+	if q.Limit > 0 && len(jobs) > q.Limit {
+		jobs = jobs[:q.Limit]
+	}
+	return jobs, nil
+}
+
+func (s *MemoryStore) CountJobs(_ context.Context, q JobQuery) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.matching(q)), nil
+}
+
+func (s *MemoryStore) CountBy(_ context.Context, field string, q JobQuery, limit int) ([]CountResult, error) {
+	if err := checkCountableField(field); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return countValues(s.matching(q), field, limit), nil
+}
+
+func (s *MemoryStore) DeleteJob(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *MemoryStore) GetCompany(_ context.Context, name string) (*Company, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	company := s.companies[NormalizeCompanyKey(name)]
+	if company == nil {
+		return nil, ErrCompanyNotFound
+	}
+	c := *company
+	return &c, nil
+}
+
+func (s *MemoryStore) JobHistory(_ context.Context, jobID string) ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Revision(nil), s.history[jobID]...), nil
+}
+
+func (s *MemoryStore) CreateSearch(_ context.Context, search SavedSearch) (SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	search.ID = fmt.Sprintf("%024x", s.nextID)
+	search.CreatedAt = s.now()
+	s.searches[search.ID] = search
+	return search, nil
+}
+
+func (s *MemoryStore) ListSearches(_ context.Context) ([]SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	searches := make([]SavedSearch, 0, len(s.searches))
+	for _, search := range s.searches {
+		searches = append(searches, search)
+	}
+	sortSearches(searches)
+	return searches, nil
+}
+
+func (s *MemoryStore) DeleteSearch(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.searches[id]; !ok {
+		return ErrSearchNotFound
+	}
+	delete(s.searches, id)
+	return nil
+}
+
+func (s *MemoryStore) CreateWebhook(_ context.Context, hook Webhook) (Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	hook.ID = fmt.Sprintf("%024x", s.nextID)
+	hook.CreatedAt = s.now()
+	s.webhooks[hook.ID] = hook
+	return hook, nil
+}
+
+func (s *MemoryStore) GetWebhook(_ context.Context, id string) (*Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hook, ok := s.webhooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return &hook, nil
+}
+
+func (s *MemoryStore) ListWebhooks(_ context.Context) ([]Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hooks := make([]Webhook, 0, len(s.webhooks))
+	for _, hook := range s.webhooks {
+		hooks = append(hooks, hook)
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		if !hooks[i].CreatedAt.Equal(hooks[j].CreatedAt) {
+			return hooks[i].CreatedAt.Before(hooks[j].CreatedAt)
+		}
+		return hooks[i].ID < hooks[j].ID
+	})
+	return hooks, nil
+}
+
+func (s *MemoryStore) DeleteWebhook(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(s.webhooks, id)
+	kept := s.deliveries[:0]
+	for _, d := range s.deliveries {
+		if d.WebhookID != id {
+			kept = append(kept, d)
+		}
+	}
+	s.deliveries = kept
+	return nil
+}
+
+func (s *MemoryStore) RecordDelivery(_ context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	d.ID = fmt.Sprintf("%024x", s.nextID)
+	s.deliveries = append(s.deliveries, d)
+	return nil
+}
+
+func (s *MemoryStore) ListDeliveries(_ context.Context, webhookID string, limit int) ([]Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cutoff := s.now().Add(-DeliveryRetention)
+	deliveries := []Delivery{}
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		d := s.deliveries[i]
+		if d.WebhookID != webhookID || d.DeliveredAt.Before(cutoff) {
+			continue
+		}
+		deliveries = append(deliveries, d)
+		if limit > 0 && len(deliveries) == limit {
+			break
+		}
+	}
+	return deliveries, nil
+}
+
+func (s *MemoryStore) CreateSchedule(_ context.Context, schedule Schedule) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	schedule.ID = fmt.Sprintf("%024x", s.nextID)
+	schedule.CreatedAt = s.now()
+	schedule.UpdatedAt = schedule.CreatedAt
+	schedule.LastRun = nil
+	s.schedules[schedule.ID] = schedule
+	return schedule, nil
+}
+
+func (s *MemoryStore) GetSchedule(_ context.Context, id string) (*Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	return &schedule, nil
+}
+
+func (s *MemoryStore) ListSchedules(_ context.Context) ([]Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedules := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedules = append(schedules, schedule)
+	}
+	sortSchedules(schedules)
+	return schedules, nil
+}
+
+func (s *MemoryStore) UpdateSchedule(_ context.Context, schedule Schedule) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.schedules[schedule.ID]
+	if !ok {
+		return Schedule{}, ErrScheduleNotFound
+	}
+	schedule.CreatedAt = existing.CreatedAt
+	schedule.LastRun = existing.LastRun
+	schedule.UpdatedAt = s.now()
+	s.schedules[schedule.ID] = schedule
+	return schedule, nil
+}
+
+func (s *MemoryStore) DeleteSchedule(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schedules[id]; !ok {
+		return ErrScheduleNotFound
+	}
+	delete(s.schedules, id)
+	return nil
+}
+
+func (s *MemoryStore) RecordScheduleRun(_ context.Context, id string, run ScheduleRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.schedules[id]
+	if !ok {
+		return ErrScheduleNotFound
+	}
+	schedule.LastRun = &run
+	s.schedules[id] = schedule
+	return nil
+}
+
+func (s *MemoryStore) EnqueueCrawl(_ context.Context, task CrawlTask) (CrawlTask, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task = newCrawlTask(task, s.now())
+	for _, existing := range s.crawls {
+		if existing.RoleKey == task.RoleKey && existing.Active() {
+			return existing, false, nil
+		}
+	}
+	s.nextID++
+	task.ID = fmt.Sprintf("%024x", s.nextID)
+	s.crawls[task.ID] = task
+	return task, true, nil
+}
+
+func (s *MemoryStore) LeaseCrawl(_ context.Context, worker string, lease time.Duration) (*CrawlTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var next *CrawlTask
+	for id, task := range s.crawls {
+		ok, expired := leasable(task, now)
+		if expired {
+			finishTask(&task, expiredLeaseError, now)
+			s.crawls[id] = task
+			continue
+		}
+		if ok && (next == nil || task.EnqueuedAt.Before(next.EnqueuedAt) ||
+			task.EnqueuedAt.Equal(next.EnqueuedAt) && task.ID < next.ID) {
+			task := task
+			next = &task
+		}
+	}
+	if next == nil {
+		return nil, ErrQueueEmpty
+	}
+	leaseTask(next, worker, lease, now)
+	s.crawls[next.ID] = *next
+	return next, nil
+}
+
+func (s *MemoryStore) RenewLease(_ context.Context, id, worker string, lease time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.crawls[id]
+	if !ok {
+		return ErrCrawlTaskNotFound
+	}
+	if task.Status != TaskRunning || task.Worker != worker {
+		return ErrLeaseLost
+	}
+	until := s.now().Add(lease)
+	task.LeaseUntil = &until
+	s.crawls[id] = task
+	return nil
+}
+
+func (s *MemoryStore) FinishCrawl(_ context.Context, id, worker, errMsg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.crawls[id]
+	if !ok {
+		return ErrCrawlTaskNotFound
+	}
+	if task.Status != TaskRunning || task.Worker != worker {
+		return ErrLeaseLost
+	}
+	finishTask(&task, errMsg, s.now())
+	s.crawls[id] = task
+	return nil
+}
+
+func (s *MemoryStore) GetCrawl(_ context.Context, id string) (*CrawlTask, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.crawls[id]
+	if !ok {
+		return nil, ErrCrawlTaskNotFound
+	}
+	return &task, nil
+}
+
+func (s *MemoryStore) ListCrawls(_ context.Context, status string, limit int) ([]CrawlTask, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cutoff := s.now().Add(-TaskRetention)
+	tasks := []CrawlTask{}
+	for _, task := range s.crawls {
+		if status != "" && task.Status != status {
+			continue
+		}
+		if task.FinishedAt != nil && task.FinishedAt.Before(cutoff) {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	sortTasks(tasks)
+	if limit > 0 && len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks, nil
+}
+
+func (s *MemoryStore) MarkSeen(_ context.Context, urls []string, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, j := range s.jobs {
+		if hasURL(j, urls) {
+			markSeen(&j, at)
+			s.jobs[id] = j
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) CloseJobs(_ context.Context, urls []string, reason string, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, j := range s.jobs {
+		if JobStatus(j) != StatusClosed && hasURL(j, urls) {
+			closeJob(&j, reason, at)
+			s.jobs[id] = j
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) SweepLifecycle(_ context.Context, q JobQuery, policy LifecyclePolicy, now time.Time) (LifecycleSweep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sweep LifecycleSweep
+	for _, j := range s.matching(q) {
+		if sweepJob(&j, policy, now) {
+			s.jobs[j.ID] = j
+			if j.Status == StatusClosed {
+				sweep.Closed++
+				sweep.ClosedIDs = append(sweep.ClosedIDs, j.ID)
+			} else {
+				sweep.NotSeen++
+			}
+		}
+	}
+	return sweep, nil
+}
+
+func (s *MemoryStore) Close(context.Context) error {
+	return nil
+}
+
+// matching returns the postings that pass q, newest first. Callers hold s.mu.
+func (s *MemoryStore) matching(q JobQuery) []JobPosting {
+	now := s.now()
+	var jobs []JobPosting
+	for _, j := range s.jobs {
+		if q.Matches(j, now) && q.isAfter(j) {
+			jobs = append(jobs, j)
+		}
+	}
+	q.sortJobs(jobs)
+	return jobs
+}
+
+// sortJobs orders postings newest first, breaking ties by ID so results are stable.
+func sortJobs(jobs []JobPosting) {
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].PostedOn.Equal(jobs[j].PostedOn) {
+			return jobs[i].PostedOn.After(jobs[j].PostedOn)
+		}
+		return jobs[i].ID > jobs[j].ID
+	})
+}
+
+func (s *MemoryStore) ListRoles(_ context.Context) ([]Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roles := make([]Role, 0, len(s.roles))
+	for _, role := range s.roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Slug < roles[j].Slug })
+	return roles, nil
+}
+
+func (s *MemoryStore) SaveRole(_ context.Context, role Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roles[role.Slug] = role
+	return nil
+}
+
+func (s *MemoryStore) GetTrendResult(_ context.Context, key string) (*TrendResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, ok := s.trends[key]
+	if !ok || !result.ExpireAt.After(s.now()) {
+		return nil, ErrTrendResultNotFound
+	}
+	return &result, nil
+}
+
+func (s *MemoryStore) SaveTrendResult(_ context.Context, result TrendResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.trends[result.Key] = result
+	return nil
+}
+
+func (s *MemoryStore) ClearTrendResults(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.trends)
+	return nil
+}
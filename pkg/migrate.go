@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// Migrator is implemented by stores with a versioned schema. Migrations create
+// and evolve indexes and backfill fields on stored postings; each is applied
+// once, in version order, and recorded in the store.
+type Migrator interface {
+	Migrate(ctx context.Context, opts MigrateOptions) ([]MigrationResult, error)
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+}
+
+type MigrateOptions struct {
+	DryRun bool // report what pending migrations would do without changing anything
+	To     int  // apply up to and including this version; 0 means all
+}
+
+// MigrationResult describes one pending migration that was applied, or would
+// be in a dry run.
+type MigrationResult struct {
+	Version     int      `json:"version"`
+	Description string   `json:"description"`
+	Steps       []string `json:"steps"`
+	Applied     bool     `json:"applied"`
+}
+
+// MigrationStatus is a known migration and when it was applied, if it was.
+type MigrationStatus struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// migration is one versioned change. apply describes each step on run and only
+// performs it when run is not a dry run.
+type migration struct {
+	Version     int
+	Description string
+	apply       func(ctx context.Context, run *migrationRun) error
+}
+
+type migrationRun struct {
+	dryRun bool
+	steps  []string
+}
+
+// step records description and runs fn unless this is a dry run.
+func (r *migrationRun) step(description string, fn func() error) error {
+	r.steps = append(r.steps, description)
+	if r.dryRun {
+		return nil
+	}
+	return fn()
+}
+
+// runMigrations applies the migrations not in applied, recording each one
+// with record as soon as it succeeds.
+func runMigrations(ctx context.Context, migrations []migration, applied map[int]time.Time,
+	record func(ctx context.Context, m migration, at time.Time) error, opts MigrateOptions) ([]MigrationResult, error) {
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	var results []MigrationResult
+	for _, m := range migrations {
+		if _, done := applied[m.Version]; done {
+			continue
+		}
+		if opts.To > 0 && m.Version > opts.To {
+			break
+		}
+
+		run := &migrationRun{dryRun: opts.DryRun}
+		if err := m.apply(ctx, run); err != nil {
+			return results, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		result := MigrationResult{Version: m.Version, Description: m.Description, Steps: run.steps}
+		if !opts.DryRun {
+			if err := record(ctx, m, time.Now()); err != nil {
+				return results, fmt.Errorf("record migration %d: %w", m.Version, err)
+			}
+			result.Applied = true
+			log.Printf("[migrate] Applied %d: %s", m.Version, m.Description)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func migrationStatus(migrations []migration, applied map[int]time.Time) []MigrationStatus {
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Description: m.Description}
+		if at, ok := applied[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status
+}
@@ -0,0 +1,218 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunMigrations(t *testing.T) {
+	ctx := context.Background()
+	var ran []int
+	migrations := []migration{
+		{2, "second", func(ctx context.Context, run *migrationRun) error {
+			return run.step("do 2", func() error { ran = append(ran, 2); return nil })
+		}},
+		{1, "first", func(ctx context.Context, run *migrationRun) error {
+			return run.step("do 1", func() error { ran = append(ran, 1); return nil })
+		}},
+		{3, "third", func(ctx context.Context, run *migrationRun) error {
+			return run.step("do 3", func() error { ran = append(ran, 3); return nil })
+		}},
+	}
+	applied := map[int]time.Time{1: time.Now()}
+	record := func(ctx context.Context, m migration, at time.Time) error {
+		applied[m.Version] = at
+		return nil
+	}
+
+	results, err := runMigrations(ctx, migrations, applied, record, MigrateOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Applied || results[0].Steps[0] != "do 2" || len(ran) != 0 || len(applied) != 1 {
+		t.Fatalf("dry run changed something: %+v, ran %v", results, ran)
+	}
+
+	if _, err := runMigrations(ctx, migrations, applied, record, MigrateOptions{To: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ran, []int{2}) {
+		t.Errorf("expected only migration 2 to run, ran %v", ran)
+	}
+
+	status := migrationStatus(migrations, applied)
+	if len(status) != 3 || status[1].AppliedAt == nil || status[2].AppliedAt != nil {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestSQLiteMigrationsNormalizeSkills(t *testing.T) {
+	ctx := context.Background()
+	store, err := openSQLiteStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	if _, err := store.Migrate(ctx, MigrateOptions{To: 1}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := store.UpsertJob(ctx, testJob("backend engineer", "Acme", time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As stored before skills were normalized at ingest
+	job, _ := store.GetJob(ctx, res.ID)
+	job.Skills = []string{"Golang", "K8s", "AWS"}
+	if err := updateJobRow(ctx, store.db, *job); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := store.Migrate(ctx, MigrateOptions{To: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Version != 2 || len(results[0].Steps) != 1 {
+		t.Fatalf("expected migration 2 with one step, got %+v", results)
+	}
+	job, _ = store.GetJob(ctx, res.ID)
+	if !reflect.DeepEqual(job.Skills, []string{"aws", "go", "kubernetes"}) {
+		t.Errorf("skills not normalized: %v", job.Skills)
+	}
+
+	// Stored before salaries were parsed; migration 3 parses it and indexes
+	// the existing posting for search
+	job.Salary = "$90k - $110k"
+	if err := updateJobRow(ctx, store.db, *job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Migrate(ctx, MigrateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	found, err := store.QueryJobs(ctx, JobQuery{Text: "backend", SalaryMin: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].SalaryMin != 90000 || found[0].SalaryMax != 110000 {
+		t.Errorf("expected the backfilled posting, got %+v", found)
+	}
+
+	if results, _ := store.Migrate(ctx, MigrateOptions{}); len(results) != 0 {
+		t.Errorf("expected nothing left to migrate, got %+v", results)
+	}
+}
+
+func TestSQLiteMigrationBackfillsCompanyIDs(t *testing.T) {
+	ctx := context.Background()
+	store, err := openSQLiteStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	if _, err := store.Migrate(ctx, MigrateOptions{To: 10}); err != nil {
+		t.Fatal(err)
+	}
+	posted := time.Now().Truncate(24 * time.Hour)
+	current := testJob("backend engineer", "Acme", posted)
+	legacyDup := testJob("backend engineer", "Acme", posted)
+	legacyDup.Location, legacyDup.URL = "Remote", "https://remoteok.com/jobs/acme-backend"
+	legacyDup.Description = "Design and scale our payment APIs in Go."
+	legacy := testJob("devops engineer", "Globex Corp", posted)
+
+	ids := map[string]string{}
+	for name, job := range map[string]JobPosting{"current": current, "legacyDup": legacyDup, "legacy": legacy} {
+		res, err := store.UpsertJob(ctx, job)
+		if err != nil || res.Action != UpsertInserted {
+			t.Fatalf("%s: %+v (%v)", name, res, err)
+		}
+		ids[name] = res.ID
+	}
+
+	// As stored before postings had a company key: hashed on the raw company
+	// name, with no company record
+	for _, name := range []string{"legacyDup", "legacy"} {
+		job, _ := store.GetJob(ctx, ids[name])
+		if name == "legacyDup" {
+			job.Company, job.Location = "Acme, Inc.", current.Location
+		}
+		job.CompanyID, job.ClusterKey = "", ""
+		job.Hash = GenerateHash(job.Title, job.Company, job.Location, job.PostedOn.Format("2006-01-02"))
+		if err := updateJobRow(ctx, store.db, *job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.db.ExecContext(ctx, `DELETE FROM companies WHERE id = 'globex'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Migrate(ctx, MigrateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ := store.CountJobs(ctx, JobQuery{}); n != 2 {
+		t.Errorf("expected the colliding posting to be merged, %d postings left", n)
+	}
+	merged, err := store.GetJob(ctx, ids["current"])
+	if err != nil || !hasSource(merged.Sources, legacyDup.URL) {
+		t.Errorf("expected the merged posting's source to be kept, got %+v (%v)", merged, err)
+	}
+	if _, err := store.GetCompany(ctx, "Globex Corp"); err != nil {
+		t.Errorf("expected the company to be resolved, got %v", err)
+	}
+	if found, _ := store.QueryJobs(ctx, JobQuery{CompanyID: "globex"}); len(found) != 1 || found[0].ID != ids["legacy"] {
+		t.Errorf("expected the legacy posting under its company, got %+v", found)
+	}
+
+	// A re-crawl now matches the backfilled posting instead of duplicating it
+	if res, err := store.UpsertJob(ctx, legacy); err != nil || res.ID != ids["legacy"] || res.Action != UpsertUnchanged {
+		t.Errorf("expected the re-crawl to match, got %+v (%v)", res, err)
+	}
+}
+
+func TestSQLiteMigrationUniqueActiveCrawls(t *testing.T) {
+	ctx := context.Background()
+	store, err := openSQLiteStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	if _, err := store.Migrate(ctx, MigrateOptions{To: 11}); err != nil {
+		t.Fatal(err)
+	}
+	// Two schedulers enqueued the same role at once
+	first, _, err := store.EnqueueCrawl(ctx, CrawlTask{Role: "backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := newCrawlTask(CrawlTask{Role: "backend"}, first.EnqueuedAt.Add(time.Second))
+	second.ID = "second"
+	data, _ := json.Marshal(second)
+	if _, err := store.db.ExecContext(ctx, `INSERT INTO crawl_tasks (id, role_key, status, enqueued_at, data) VALUES (?, ?, ?, ?, ?)`,
+		second.ID, second.RoleKey, second.Status, second.EnqueuedAt.UnixMilli(), string(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Migrate(ctx, MigrateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	failed, err := store.GetCrawl(ctx, second.ID)
+	if err != nil || failed.Status != TaskFailed || failed.Error != duplicateTaskError || failed.FinishedAt == nil {
+		t.Errorf("expected the later task failed, got %+v (%v)", failed, err)
+	}
+	if task, created, err := store.EnqueueCrawl(ctx, CrawlTask{Role: "backend"}); err != nil || created || task.ID != first.ID {
+		t.Errorf("expected the first task to stay active, got %+v, %v (%v)", task, created, err)
+	}
+
+	// A task slipping past the check is turned away by the index
+	second.ID = "third"
+	if _, err := store.db.ExecContext(ctx, `INSERT INTO crawl_tasks (id, role_key, status, enqueued_at, data) VALUES (?, ?, ?, ?, ?)`,
+		second.ID, second.RoleKey, second.Status, second.EnqueuedAt.UnixMilli(), string(data)); err == nil {
+		t.Error("expected a second active task of the role to be rejected")
+	}
+}
@@ -3,22 +3,46 @@ package pkg
 import "time"
 
 type JobPosting struct {
-	ID          string    `bson:"_id,omitempty"`
-	Title       string    `bson:"title"`
-	Company     string    `bson:"company"`
-	Location    string    `bson:"location"`
-	Salary      string    `bson:"salary"`
-	PostedOn    time.Time `bson:"postedOn"`
-	Description string    `bson:"description"`
-	URL         string    `bson:"url"`
-	Source      string    `bson:"source"`
-	ApplyURL    string    `bson:"applyUrl"`
-	Skills      []string  `bson:"skills"`
-	Experience  string    `bson:"experience"`
-
-	Hash            string    `bson:"hash"`            // hash of Title+Company+Location+PostedOn
-	DescriptionHash string    `bson:"descriptionHash"` // hash of Description + Salary
-	LastUpdated     time.Time `bson:"lastUpdated"`
-	CreatedAt       time.Time `bson:"createdAt"`
-	ExpireAt        time.Time `bson:"expireAt"` // TTL field (PostedOn + 30 days)
+	ID          string    `bson:"_id,omitempty" json:"id,omitempty"`
+	Title       string    `bson:"title" json:"title"`
+	Company     string    `bson:"company" json:"company"`      // canonical company name
+	CompanyID   string    `bson:"companyId" json:"company_id"` // key of the canonical Company record
+	Location    string    `bson:"location" json:"location"`
+	Salary      string    `bson:"salary" json:"salary"`
+	PostedOn    time.Time `bson:"postedOn" json:"posted_on"`
+	Description string    `bson:"description" json:"description"`
+	URL         string    `bson:"url" json:"url"`
+	Source      string    `bson:"source" json:"source"`
+	ApplyURL    string    `bson:"applyUrl" json:"apply_url"`
+	Skills      []string  `bson:"skills" json:"skills"`
+	Experience  string    `bson:"experience" json:"experience"`
+	Roles       []string  `bson:"roles,omitempty" json:"roles,omitempty"` // slugs of the taxonomy roles, see ClassifyRoles
+
+	SalaryMin      int    `bson:"salaryMin" json:"salary_min,omitempty"`                     // annualized, parsed from Salary; 0 if unknown
+	SalaryMax      int    `bson:"salaryMax" json:"salary_max,omitempty"`                     // 0 if open-ended or unknown
+	SalaryCurrency string `bson:"salaryCurrency,omitempty" json:"salary_currency,omitempty"` // ISO code, e.g. "USD"
+
+	ExperienceRange *ExperienceRange `bson:"experienceRange,omitempty" json:"experience_range,omitempty"`
+	Seniority       string           `bson:"seniority" json:"seniority"`            // intern, junior, mid, senior, staff, principal, lead, manager
+	EmploymentType  string           `bson:"employmentType" json:"employment_type"` // full-time, part-time, contract, freelance
+
+	Locations    []LocationEntry `bson:"locations,omitempty" json:"locations,omitempty"` // structured form of Location
+	RemotePolicy string          `bson:"remotePolicy" json:"remote_policy"`              // remote, hybrid, onsite
+	Timezones    []string        `bson:"timezones,omitempty" json:"timezones,omitempty"` // e.g. "UTC-5", "CET"
+
+	Hash            string    `bson:"hash" json:"hash"`                                    // hash of Title+CompanyID+Location+PostedOn
+	DescriptionHash string    `bson:"descriptionHash" json:"description_hash"`             // hash of Description + Salary
+	ClusterKey      string    `bson:"clusterKey" json:"cluster_key"`                       // CompanyID + normalized title
+	SimHash         int64     `bson:"simHash" json:"sim_hash"`                             // SimHash of Description, for near-duplicate detection
+	AliasHashes     []string  `bson:"aliasHashes,omitempty" json:"alias_hashes,omitempty"` // hashes of near-duplicates merged into this posting
+	LastUpdated     time.Time `bson:"lastUpdated" json:"last_updated"`
+	CreatedAt       time.Time `bson:"createdAt" json:"created_at"`
+	ExpireAt        time.Time `bson:"expireAt" json:"expire_at"` // TTL field (last seen or closed + JobRetention)
+
+	Status       string     `bson:"status" json:"status"`                                  // open, not-seen-recently, closed
+	LastSeenAt   time.Time  `bson:"lastSeenAt" json:"last_seen_at"`                        // last crawl that found the posting
+	ClosedAt     *time.Time `bson:"closedAt,omitempty" json:"closed_at,omitempty"`         // when the posting was taken down
+	ClosedReason string     `bson:"closedReason,omitempty" json:"closed_reason,omitempty"` // gone, delisted
+
+	Sources []SourceLink `bson:"sources" json:"sources"` // every board/URL this job was seen on
 }
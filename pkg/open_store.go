@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// DefaultSQLitePath is where the SQLite store lives when SQLITE_PATH is unset.
+const DefaultSQLitePath = "jobcrawler.db"
+
+// OpenStore opens the Store selected by STORE_BACKEND:
+//
+//	mongo  - MongoDB at DATABASE_URL
+//	sqlite - a local file at SQLITE_PATH (default jobcrawler.db)
+//	memory - in-process, lost on exit
+//
+// Without STORE_BACKEND, Mongo is used when DATABASE_URL is set and SQLite
+// otherwise, so the crawler and API run with no external services. Pending
+// migrations are applied and the stored roles loaded into the taxonomy (see
+// LoadRoles) before the store is returned.
+func OpenStore() (Store, error) {
+	store, err := openStore(true)
+	if err != nil {
+		return nil, err
+	}
+	if err := LoadRoles(context.Background(), store); err != nil {
+		store.Close(context.Background())
+		return nil, fmt.Errorf("load roles: %w", err)
+	}
+	return store, nil
+}
+
+// OpenStoreUnmigrated opens the store selected by STORE_BACKEND without
+// applying migrations, for tools that inspect or run them.
+func OpenStoreUnmigrated() (Store, error) {
+	return openStore(false)
+}
+
+func openStore(migrate bool) (Store, error) {
+	backend := os.Getenv("STORE_BACKEND")
+	if backend == "" {
+		backend = "sqlite"
+		if os.Getenv("DATABASE_URL") != "" {
+			backend = "mongo"
+		}
+	}
+
+	switch backend {
+	case "mongo":
+		store, err := ConnectMongo()
+		if err != nil {
+			return nil, err
+		}
+		if !migrate {
+			return store, nil
+		}
+		if _, err := store.Migrate(context.Background(), MigrateOptions{}); err != nil {
+			store.Close(context.Background())
+			return nil, fmt.Errorf("migrate store: %w", err)
+		}
+		return store, nil
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = DefaultSQLitePath
+		}
+		if migrate {
+			return NewSQLiteStore(path)
+		}
+		return openSQLiteStore(path)
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORE_BACKEND %q (want mongo, sqlite or memory)", backend)
+	}
+}
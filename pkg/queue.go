@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrQueueEmpty is returned by LeaseCrawl when no task is ready.
+	ErrQueueEmpty = errors.New("no crawl task to lease")
+	// ErrLeaseLost is returned when a worker no longer holds the lease on a
+	// task, because it ran out and the task went to another worker.
+	ErrLeaseLost = errors.New("crawl task lease lost")
+	// ErrCrawlTaskNotFound is returned when no crawl task has the requested ID.
+	ErrCrawlTaskNotFound = errors.New("crawl task not found")
+)
+
+// Crawl task statuses.
+const (
+	TaskQueued  = "queued"
+	TaskRunning = "running" // leased by a worker
+	TaskDone    = "done"
+	TaskFailed  = "failed"
+)
+
+// MaxTaskAttempts is how many leases a task gets: a task whose lease ran out
+// this many times, because its workers died, fails instead of being retried.
+const MaxTaskAttempts = 3
+
+// TaskRetention is how long finished crawl tasks are kept.
+const TaskRetention = 7 * 24 * time.Hour
+
+// QueueStore is the shared frontier of crawl work. The API and the scheduler
+// enqueue tasks; workers lease them, renew the lease while they crawl, and
+// finish them. A task whose lease runs out is handed to the next worker.
+type QueueStore interface {
+	// EnqueueCrawl adds task, unless a task for the same role is already
+	// queued or running; then it returns that task and false.
+	EnqueueCrawl(ctx context.Context, task CrawlTask) (CrawlTask, bool, error)
+	// LeaseCrawl hands worker the oldest queued task, or a running one whose
+	// lease ran out, until lease from now. It returns ErrQueueEmpty when there
+	// is none.
+	LeaseCrawl(ctx context.Context, worker string, lease time.Duration) (*CrawlTask, error)
+	// RenewLease extends worker's lease on a running task.
+	RenewLease(ctx context.Context, id, worker string, lease time.Duration) error
+	// FinishCrawl records the outcome of a task worker leased; an empty
+	// errMsg means it succeeded.
+	FinishCrawl(ctx context.Context, id, worker, errMsg string) error
+	GetCrawl(ctx context.Context, id string) (*CrawlTask, error)
+	// ListCrawls returns tasks with status, or every task if status is empty,
+	// newest first.
+	ListCrawls(ctx context.Context, status string, limit int) ([]CrawlTask, error)
+}
+
+// CrawlTask is a request to crawl one role, waiting in or leased from the
+// queue.
+type CrawlTask struct {
+	ID         string     `bson:"_id,omitempty" json:"id"`
+	Role       string     `bson:"role" json:"role"`
+	RoleKey    string     `bson:"roleKey" json:"-"`                           // slug of Role, to find a role's active task
+	Sources    []string   `bson:"sources,omitempty" json:"sources,omitempty"` // board hosts; empty means every board
+	MaxJobs    int        `bson:"maxJobs,omitempty" json:"max_jobs,omitempty"`
+	ScheduleID string     `bson:"scheduleId,omitempty" json:"schedule_id,omitempty"` // the schedule that enqueued it, if any
+	Status     string     `bson:"status" json:"status"`
+	Attempts   int        `bson:"attempts" json:"attempts"` // leases so far
+	Worker     string     `bson:"worker,omitempty" json:"worker,omitempty"`
+	LeaseUntil *time.Time `bson:"leaseUntil,omitempty" json:"lease_until,omitempty"`
+	Error      string     `bson:"error,omitempty" json:"error,omitempty"`
+	EnqueuedAt time.Time  `bson:"enqueuedAt" json:"enqueued_at"`
+	StartedAt  *time.Time `bson:"startedAt,omitempty" json:"started_at,omitempty"`   // of the latest lease
+	FinishedAt *time.Time `bson:"finishedAt,omitempty" json:"finished_at,omitempty"` // TTL field
+}
+
+// Active reports whether the task is queued or running.
+func (t CrawlTask) Active() bool {
+	return t.Status == TaskQueued || t.Status == TaskRunning
+}
+
+// newCrawlTask fills in the queue fields of a task being enqueued.
+func newCrawlTask(task CrawlTask, now time.Time) CrawlTask {
+	task.RoleKey = RoleSlug(task.Role)
+	task.Status = TaskQueued
+	task.Attempts = 0
+	task.Worker = ""
+	task.LeaseUntil = nil
+	task.Error = ""
+	task.EnqueuedAt = now
+	task.StartedAt, task.FinishedAt = nil, nil
+	return task
+}
+
+// leasable reports whether a worker may lease the task at now, and expired
+// whether it is instead out of attempts and must fail.
+func leasable(task CrawlTask, now time.Time) (ok, expired bool) {
+	switch {
+	case task.Status == TaskQueued:
+		return true, false
+	case task.Status == TaskRunning && task.LeaseUntil != nil && task.LeaseUntil.Before(now):
+		if task.Attempts >= MaxTaskAttempts {
+			return false, true
+		}
+		return true, false
+	}
+	return false, false
+}
+
+func leaseTask(task *CrawlTask, worker string, lease time.Duration, now time.Time) {
+	task.Status = TaskRunning
+	task.Worker = worker
+	task.Attempts++
+	until := now.Add(lease)
+	task.LeaseUntil = &until
+	task.StartedAt = &now
+}
+
+func finishTask(task *CrawlTask, errMsg string, now time.Time) {
+	task.Status = TaskDone
+	if errMsg != "" {
+		task.Status = TaskFailed
+	}
+	task.Error = errMsg
+	task.LeaseUntil = nil
+	task.FinishedAt = &now
+}
+
+// expiredLeaseError is the error of a task that failed by running out of
+// attempts.
+const expiredLeaseError = "lease expired on every attempt; workers stopped renewing it"
+
+// duplicateTaskError is the error of a task failed by the migration that
+// made a role's active task unique, for being enqueued after another one.
+const duplicateTaskError = "enqueued while another task of its role was active"
+
+// sortTasks orders tasks newest first, then by ID.
+func sortTasks(tasks []CrawlTask) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].EnqueuedAt.Equal(tasks[j].EnqueuedAt) {
+			return tasks[i].EnqueuedAt.After(tasks[j].EnqueuedAt)
+		}
+		return tasks[i].ID > tasks[j].ID
+	})
+}
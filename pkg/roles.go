@@ -0,0 +1,275 @@
+package pkg
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// ErrRoleNotFound is returned when no role has the requested slug.
+var ErrRoleNotFound = errors.New("role not found")
+
+// RoleStore persists the roles added or changed through the API. They
+// overlay DefaultRoles: a stored role replaces the default with its slug.
+type RoleStore interface {
+	// ListRoles returns the stored roles, by slug.
+	ListRoles(ctx context.Context) ([]Role, error)
+	// SaveRole adds a role or replaces the one with its slug.
+	SaveRole(ctx context.Context, role Role) error
+}
+
+// Role is a canonical role of the taxonomy that postings are classified into.
+type Role struct {
+	Slug     string   `bson:"_id" json:"slug"` // stored on postings and used in queries
+	Name     string   `bson:"name" json:"name"`
+	Synonyms []string `bson:"synonyms,omitempty" json:"synonyms,omitempty"` // other names the role can be requested by
+	Titles   []string `bson:"titles" json:"titles"`                         // phrases that put a posting in the role when its title contains one
+	Skills   []string `bson:"skills,omitempty" json:"skills,omitempty"`     // skills that signal the role when the title is generic
+	Enabled  bool     `bson:"enabled" json:"enabled"`                       // disabled roles can't be crawled and aren't assigned to new postings
+}
+
+// GenericRole is the role of titles such as "Senior Engineer" that name no
+// specialty; skill signals may add more specific roles to those.
+const GenericRole = "software-engineer"
+
+// genericTitleWords put a title that matched no role in GenericRole.
+var genericTitleWords = []string{"engineer", "developer", "programmer", "coder"}
+
+// minSkillSignals is how many of a role's skills a generic posting needs to
+// be put in that role too.
+const minSkillSignals = 3
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+//go:embed data/roles.json
+var rolesJSON []byte
+
+// DefaultRoles is the role taxonomy of data/roles.json.
+var DefaultRoles = loadRoles()
+
+// taxonomy is the role set in use: DefaultRoles until LoadRoles or SetRoles
+// replace it.
+var taxonomy atomic.Pointer[roleSet]
+
+type roleSet struct {
+	roles []Role
+	byKey map[string]Role // every slug, lowercased name and synonym
+}
+
+func init() {
+	if err := SetRoles(DefaultRoles); err != nil {
+		panic(fmt.Sprintf("pkg: invalid embedded roles: %v", err))
+	}
+}
+
+func loadRoles() []Role {
+	var data struct {
+		Roles []Role `json:"roles"`
+	}
+	if err := json.Unmarshal(rolesJSON, &data); err != nil {
+		panic(fmt.Sprintf("pkg: invalid embedded roles: %v", err))
+	}
+	return data.Roles
+}
+
+// Validate checks the role's fields and normalizes its titles, synonyms and
+// skills. A role without titles is classified by its name.
+func (r *Role) Validate() error {
+	r.Slug = strings.TrimSpace(r.Slug)
+	r.Name = strings.TrimSpace(r.Name)
+	if !slugPattern.MatchString(r.Slug) {
+		return fmt.Errorf("invalid slug %q: use lowercase letters, digits and dashes", r.Slug)
+	}
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(r.Titles) == 0 {
+		r.Titles = []string{r.Name}
+	}
+	titles := make([]string, 0, len(r.Titles))
+	for _, title := range r.Titles {
+		if t := normalizeTitle(title); t != "" && !slices.Contains(titles, t) {
+			titles = append(titles, t)
+		}
+	}
+	if len(titles) == 0 {
+		return errors.New("titles must contain letters or digits")
+	}
+	r.Titles = titles
+
+	synonyms := make([]string, 0, len(r.Synonyms))
+	for _, synonym := range r.Synonyms {
+		if s := strings.ToLower(strings.TrimSpace(synonym)); s != "" && !slices.Contains(synonyms, s) {
+			synonyms = append(synonyms, s)
+		}
+	}
+	r.Synonyms = synonyms
+	r.Skills = NormalizeSkills(r.Skills)
+	return nil
+}
+
+// keys are what the role resolves from: its slug, lowercased name and
+// synonyms.
+func (r Role) keys() []string {
+	return append([]string{r.Slug, strings.ToLower(r.Name)}, r.Synonyms...)
+}
+
+// SetRoles validates roles and makes them the taxonomy in use. No two roles
+// may share a slug, name or synonym.
+func SetRoles(roles []Role) error {
+	set, err := newRoleSet(roles)
+	if err != nil {
+		return err
+	}
+	taxonomy.Store(set)
+	return nil
+}
+
+// CheckRoles reports why roles can't be the taxonomy in use, like SetRoles,
+// without installing them.
+func CheckRoles(roles []Role) error {
+	_, err := newRoleSet(roles)
+	return err
+}
+
+func newRoleSet(roles []Role) (*roleSet, error) {
+	set := &roleSet{roles: make([]Role, 0, len(roles)), byKey: make(map[string]Role)}
+	for _, role := range roles {
+		if err := role.Validate(); err != nil {
+			return nil, fmt.Errorf("role %q: %w", role.Slug, err)
+		}
+		if _, ok := set.byKey[role.Slug]; ok {
+			return nil, fmt.Errorf("duplicate role %q", role.Slug)
+		}
+		for _, key := range role.keys() {
+			if other, ok := set.byKey[key]; ok && other.Slug != role.Slug {
+				return nil, fmt.Errorf("role %q: %q already names role %q", role.Slug, key, other.Slug)
+			}
+			set.byKey[key] = role
+		}
+		set.roles = append(set.roles, role)
+	}
+	return set, nil
+}
+
+// MergeRoles overlays stored roles on defaults: a stored role replaces the
+// default with its slug in place, and the others follow the defaults.
+func MergeRoles(defaults, stored []Role) []Role {
+	merged := slices.Clone(defaults)
+	for _, role := range stored {
+		if i := slices.IndexFunc(merged, func(r Role) bool { return r.Slug == role.Slug }); i >= 0 {
+			merged[i] = role
+		} else {
+			merged = append(merged, role)
+		}
+	}
+	return merged
+}
+
+// LoadRoles makes DefaultRoles with the roles in store the taxonomy in use.
+func LoadRoles(ctx context.Context, store RoleStore) error {
+	stored, err := store.ListRoles(ctx)
+	if err != nil {
+		return err
+	}
+	return SetRoles(MergeRoles(DefaultRoles, stored))
+}
+
+// Roles returns the taxonomy in use, enabled or not.
+func Roles() []Role {
+	return slices.Clone(taxonomy.Load().roles)
+}
+
+// ResolveRole finds a role by slug, name or synonym, ignoring case and
+// surrounding space. Disabled roles resolve too, so postings classified
+// before a role was disabled can still be found.
+func ResolveRole(role string) (Role, bool) {
+	r, ok := taxonomy.Load().byKey[strings.ToLower(strings.TrimSpace(role))]
+	return r, ok
+}
+
+// RoleSlug returns the slug of role, or role lowercased if it names none, so
+// that an unknown role matches no posting.
+func RoleSlug(role string) string {
+	if r, ok := ResolveRole(role); ok {
+		return r.Slug
+	}
+	return strings.ToLower(strings.TrimSpace(role))
+}
+
+// ClassifyRoles returns the slugs of the enabled roles a posting belongs to:
+// every role with a title phrase in the title, or, for a generic title,
+// GenericRole and the roles the posting's skills signal.
+func ClassifyRoles(title string, skills []string) []string {
+	t := " " + normalizeTitle(title) + " "
+	set := taxonomy.Load()
+
+	var roles []string
+	generic := true
+	for _, role := range set.roles {
+		if !role.Enabled {
+			continue
+		}
+		for _, phrase := range role.Titles {
+			if strings.Contains(t, " "+phrase+" ") {
+				roles = append(roles, role.Slug)
+				if role.Slug != GenericRole {
+					generic = false
+				}
+				break
+			}
+		}
+	}
+	if !generic {
+		return roles
+	}
+
+	if len(roles) == 0 && set.byKey[GenericRole].Enabled {
+		for _, word := range genericTitleWords {
+			if strings.Contains(t, " "+word+" ") || strings.Contains(t, " "+word+"s ") {
+				roles = append(roles, GenericRole)
+				break
+			}
+		}
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+
+	have := make(map[string]bool, len(skills))
+	for _, s := range skills {
+		have[NormalizeSkill(s)] = true
+	}
+	for _, role := range set.roles {
+		if !role.Enabled {
+			continue
+		}
+		n := 0
+		for _, s := range role.Skills {
+			if have[s] {
+				n++
+			}
+		}
+		if n >= minSkillSignals {
+			roles = append(roles, role.Slug)
+		}
+	}
+	return roles
+}
+
+// normalizeTitle lowercases a title and turns everything but letters, digits,
+// "+" and "#" into single spaces, so "Front-End / UI Engineer" reads
+// "front end ui engineer".
+func normalizeTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	}), " ")
+}
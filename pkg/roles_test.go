@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClassifyRoles(t *testing.T) {
+	cases := []struct {
+		title  string
+		skills []string
+		want   []string
+	}{
+		{"Senior Backend Engineer", nil, []string{"backend"}},
+		{"Front-End / UI Engineer", nil, []string{"frontend"}},
+		{"Full Stack Developer (React + Node)", nil, []string{"fullstack"}},
+		{"UI/UX Designer", nil, []string{"ui-ux-designer"}},
+		{"Data Engineer, Analytics", nil, []string{"data-engineer"}},
+		{"Site Reliability Engineer", nil, []string{"site-reliability-engineer"}},
+		{"Software Engineer", nil, []string{"software-engineer"}},
+		{"Staff Engineer", []string{"react", "TypeScript", "css"}, []string{"software-engineer", "frontend"}},
+		{"Senior Developer", []string{"golang", "postgres", "redis", "aws"}, []string{"software-engineer", "backend"}},
+		{"Engineer", []string{"react", "go"}, []string{"software-engineer"}},
+		{"Backend Engineer", []string{"react", "typescript", "css"}, []string{"backend"}},
+		{"Frontendish Wizard", nil, nil},
+		{"Account Executive", []string{"go", "postgresql", "redis"}, nil},
+	}
+
+	for _, c := range cases {
+		if got := ClassifyRoles(c.title, c.skills); !slices.Equal(got, c.want) {
+			t.Errorf("%q %v: expected %v, got %v", c.title, c.skills, c.want, got)
+		}
+	}
+}
+
+func TestResolveRole(t *testing.T) {
+	for _, role := range []string{"backend", "Backend developer", "  BACKEND DEVELOPER ", "back-end developer"} {
+		if r, ok := ResolveRole(role); !ok || r.Slug != "backend" {
+			t.Errorf("%q: expected backend, got %+v (%v)", role, r, ok)
+		}
+	}
+	for _, role := range []string{"", "wizard", "back.*"} {
+		if r, ok := ResolveRole(role); ok {
+			t.Errorf("%q: expected no role, got %+v", role, r)
+		}
+	}
+	if got := RoleSlug("UI/UX designer"); got != "ui-ux-designer" {
+		t.Errorf("expected ui-ux-designer, got %q", got)
+	}
+}
+
+// TestDefaultRoles checks every entry of data/roles.json: it is enabled, its
+// slug, name and synonyms resolve to it, and a title of its name classifies
+// into it.
+func TestDefaultRoles(t *testing.T) {
+	if len(DefaultRoles) == 0 {
+		t.Fatal("no default roles")
+	}
+	for _, role := range DefaultRoles {
+		if !role.Enabled {
+			t.Errorf("%s: expected enabled", role.Slug)
+		}
+		keys := append([]string{role.Slug, role.Name, strings.ToUpper(role.Name)}, role.Synonyms...)
+		for _, key := range keys {
+			if r, ok := ResolveRole(key); !ok || r.Slug != role.Slug {
+				t.Errorf("%q: expected to resolve to %s, got %+v (%v)", key, role.Slug, r, ok)
+			}
+		}
+		if got := ClassifyRoles(role.Name, nil); !slices.Contains(got, role.Slug) {
+			t.Errorf("%q: expected to classify as %s, got %v", role.Name, role.Slug, got)
+		}
+	}
+}
+
+// TestResolveRoleLegacyNames checks that every role the crawler allowed before
+// the taxonomy can still be requested, whatever its case.
+func TestResolveRoleLegacyNames(t *testing.T) {
+	for _, name := range []string{
+		"backend", "developer", "engineer", "programmer", "devops", "frontend",
+		"data scientist", "mobile developer", "fullstack engineer", "product manager",
+		"ui/ux designer", "software engineer", "system architect", "database administrator",
+		"cloud engineer", "machine learning engineer", "security engineer", "network engineer",
+		"site reliability engineer", "game developer", "business analyst",
+		"quality assurance engineer", "technical writer", "data analyst", "web developer",
+		"research scientist", "blockchain developer", "AI engineer", "IoT developer",
+		"test automation engineer", "scrum master", "product owner", "sales engineer",
+		"application support analyst", "ERP consultant", "digital marketing specialist",
+		"business intelligence developer", "data engineer", "technical support engineer",
+		"mobile application tester", "user researcher", "content strategist", "web designer",
+		"IT project manager", "system administrator", "help desk technician",
+		"e-commerce specialist", "video game tester", "data visualization expert",
+		"RPA developer", "chatbot developer", "digital product designer", "IT consultant",
+		"software development manager",
+	} {
+		if _, ok := ResolveRole(name); !ok {
+			t.Errorf("%q: expected a role", name)
+		}
+	}
+}
+
+func TestSetRolesRejectsConflicts(t *testing.T) {
+	t.Cleanup(func() { SetRoles(DefaultRoles) })
+
+	cases := map[string][]Role{
+		"bad slug":          {{Slug: "Back End", Name: "Backend"}},
+		"no name":           {{Slug: "backend"}},
+		"duplicate slug":    {{Slug: "backend", Name: "Backend"}, {Slug: "backend", Name: "Server side"}},
+		"name of another":   {{Slug: "backend", Name: "Backend"}, {Slug: "server", Name: "backend"}},
+		"synonym collision": {{Slug: "backend", Name: "Backend", Synonyms: []string{"api"}}, {Slug: "api-developer", Name: "API"}},
+	}
+	for name, roles := range cases {
+		if err := SetRoles(roles); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, ok := ResolveRole("backend"); !ok {
+		t.Error("expected a rejected taxonomy to leave the one in use")
+	}
+}
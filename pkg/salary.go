@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// salaryCurrencies maps currency symbols and codes to ISO codes. Longer
+// symbols come first so "CA$" is not read as "$".
+var salaryCurrencies = []struct{ token, code string }{
+	{"ca$", "CAD"}, {"a$", "AUD"}, {"us$", "USD"},
+	{"usd", "USD"}, {"eur", "EUR"}, {"gbp", "GBP"}, {"cad", "CAD"}, {"aud", "AUD"},
+	{"inr", "INR"}, {"chf", "CHF"}, {"sek", "SEK"}, {"pln", "PLN"},
+	{"$", "USD"}, {"€", "EUR"}, {"£", "GBP"}, {"₹", "INR"},
+}
+
+// salaryPeriods annualizes amounts quoted per hour, day, week or month.
+var salaryPeriods = []struct {
+	re     *regexp.Regexp
+	factor int
+}{
+	{regexp.MustCompile(`(?:/\s*h(?:ou)?r|\bper hour\b|\ban hour\b|\bhourly\b)`), 2080},
+	{regexp.MustCompile(`(?:/\s*day|\bper day\b|\ba day\b|\bdaily\b)`), 260},
+	{regexp.MustCompile(`(?:/\s*w(?:ee)?k|\bper week\b|\ba week\b|\bweekly\b)`), 52},
+	{regexp.MustCompile(`(?:/\s*mo(?:nth)?|\bper month\b|\ba month\b|\bmonthly\b)`), 12},
+}
+
+var (
+	salaryAmountRe    = regexp.MustCompile(`(\d[\d,.]*)\s*([km])?\b`)
+	thousandsGroupsRe = regexp.MustCompile(`^\d{1,3}([.,]\d{3})+$`)
+)
+
+// minAnnualSalary filters out numbers that cannot be a yearly salary, such as
+// the "4" of "4 days a week".
+const minAnnualSalary = 1000
+
+// ParseSalary reads the annualized range and currency of a salary text such as
+// "$75,000 - $99,999 USD", "€60k–80k", "$100,000 or more" or "$45/hour".
+// An open-ended range ("or more", "100k+") has max 0 and "up to 120k" has min
+// 0. Both are 0 when the text holds no recognizable amount.
+func ParseSalary(text string) (min, max int, currency string) {
+	lower := strings.ToLower(text)
+	for _, c := range salaryCurrencies {
+		if strings.Contains(lower, c.token) {
+			currency = c.code
+			break
+		}
+	}
+
+	factor := 1
+	for _, p := range salaryPeriods {
+		if p.re.MatchString(lower) {
+			factor = p.factor
+			break
+		}
+	}
+
+	var amounts []int
+	for _, m := range salaryAmountRe.FindAllStringSubmatch(lower, -1) {
+		n, ok := parseAmount(m[1], m[2])
+		if !ok {
+			continue
+		}
+		if n *= factor; n >= minAnnualSalary {
+			amounts = append(amounts, n)
+		}
+		if len(amounts) == 2 {
+			break
+		}
+	}
+
+	switch {
+	case len(amounts) == 0:
+		return 0, 0, ""
+	case len(amounts) == 2:
+		min, max = amounts[0], amounts[1]
+		if min > max {
+			min, max = max, min
+		}
+	case strings.Contains(lower, "up to"):
+		max = amounts[0]
+	case strings.Contains(lower, "or more") || strings.Contains(lower, "+"):
+		min = amounts[0]
+	default:
+		min, max = amounts[0], amounts[0]
+	}
+	return min, max, currency
+}
+
+// parseAmount reads "75,000", "60.000", "1.5" with suffix "m" or "80" with
+// suffix "k".
+func parseAmount(digits, suffix string) (int, bool) {
+	digits = strings.TrimRight(digits, ".,")
+	// Separators followed by exactly three digits group thousands, in either convention
+	if thousandsGroupsRe.MatchString(digits) {
+		digits = strings.NewReplacer(",", "", ".", "").Replace(digits)
+	}
+	digits = strings.ReplaceAll(digits, ",", ".")
+	f, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch suffix {
+	case "k":
+		f *= 1_000
+	case "m":
+		f *= 1_000_000
+	}
+	return int(f), true
+}
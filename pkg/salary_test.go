@@ -0,0 +1,29 @@
+package pkg
+
+import "testing"
+
+func TestParseSalary(t *testing.T) {
+	tests := []struct {
+		text     string
+		min, max int
+		currency string
+	}{
+		{"$75,000 - $99,999 USD", 75000, 99999, "USD"},
+		{"$100,000 or more USD", 100000, 0, "USD"},
+		{"€60k–80k", 60000, 80000, "EUR"},
+		{"£45.000 - £55.000", 45000, 55000, "GBP"},
+		{"CA$120K+", 120000, 0, "CAD"},
+		{"Up to $150k", 0, 150000, "USD"},
+		{"$45/hour", 93600, 93600, "USD"},
+		{"8,000 - 10,000 EUR per month", 96000, 120000, "EUR"},
+		{"Competitive", 0, 0, ""},
+		{"", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		min, max, currency := ParseSalary(tt.text)
+		if min != tt.min || max != tt.max || currency != tt.currency {
+			t.Errorf("ParseSalary(%q) = %d, %d, %q; want %d, %d, %q",
+				tt.text, min, max, currency, tt.min, tt.max, tt.currency)
+		}
+	}
+}
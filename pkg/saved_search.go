@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrSearchNotFound is returned when no saved search has the requested ID.
+var ErrSearchNotFound = errors.New("saved search not found")
+
+// Notifiers a saved search can deliver through.
+const (
+	NotifyWebhook = "webhook" // POST the matches as JSON to Target, a URL
+	NotifyEmail   = "email"   // mail the matches to Target, an address
+	NotifyFile    = "file"    // append the matches as a JSON line to Target, a path; "-" is stdout
+)
+
+// SearchStore persists saved searches.
+type SearchStore interface {
+	CreateSearch(ctx context.Context, search SavedSearch) (SavedSearch, error)
+	// ListSearches returns every saved search, oldest first.
+	ListSearches(ctx context.Context) ([]SavedSearch, error)
+	DeleteSearch(ctx context.Context, id string) error
+}
+
+// SavedSearch is a query someone wants to hear about: after each crawl, the
+// newly inserted postings it matches are sent through its notifier.
+type SavedSearch struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	Name      string    `bson:"name" json:"name"`
+	Role      string    `bson:"role,omitempty" json:"role,omitempty"`
+	Skills    []string  `bson:"skills,omitempty" json:"skills,omitempty"` // all required
+	Location  string    `bson:"location,omitempty" json:"location,omitempty"`
+	SalaryMin int       `bson:"salaryMin,omitempty" json:"salary_min,omitempty"` // annual floor
+	Notifier  string    `bson:"notifier" json:"notifier"`                        // webhook, email or file
+	Target    string    `bson:"target" json:"target"`                            // where the notifier delivers
+	CreatedAt time.Time `bson:"createdAt" json:"created_at"`
+}
+
+// Query is the JobQuery that the saved search stands for.
+func (s SavedSearch) Query() JobQuery {
+	return JobQuery{
+		Role:      s.Role,
+		SkillsAll: s.Skills,
+		Location:  s.Location,
+		SalaryMin: s.SalaryMin,
+		OpenOnly:  true,
+	}
+}
+
+// Validate checks that the search filters something and can be delivered.
+func (s SavedSearch) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("name is required")
+	}
+	if s.Role == "" && len(s.Skills) == 0 && s.Location == "" && s.SalaryMin <= 0 {
+		return errors.New("at least one of role, skills, location or salary_min is required")
+	}
+	if _, ok := ResolveRole(s.Role); s.Role != "" && !ok {
+		return fmt.Errorf("unknown role %q", s.Role)
+	}
+	if s.SalaryMin < 0 {
+		return errors.New("salary_min must not be negative")
+	}
+
+	switch s.Notifier {
+	case NotifyWebhook:
+		if u, err := url.Parse(s.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("webhook target must be an http(s) URL")
+		}
+	case NotifyEmail:
+		if _, err := mail.ParseAddress(s.Target); err != nil {
+			return errors.New("email target must be an email address")
+		}
+	case NotifyFile:
+		if s.Target == "" {
+			return errors.New(`file target must be a path or "-" for stdout`)
+		}
+	default:
+		return fmt.Errorf("notifier must be %s, %s or %s", NotifyWebhook, NotifyEmail, NotifyFile)
+	}
+	return nil
+}
+
+// sortSearches orders saved searches oldest first, then by ID.
+func sortSearches(searches []SavedSearch) {
+	sort.Slice(searches, func(i, j int) bool {
+		if !searches[i].CreatedAt.Equal(searches[j].CreatedAt) {
+			return searches[i].CreatedAt.Before(searches[j].CreatedAt)
+		}
+		return searches[i].ID < searches[j].ID
+	})
+}
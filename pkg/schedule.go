@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ErrScheduleNotFound is returned when no schedule has the requested ID.
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// DefaultScheduleJitter is the jitter of a schedule created without one.
+const DefaultScheduleJitter = 120 // seconds
+
+// Outcomes of a scheduled run.
+const (
+	RunOK      = "ok"
+	RunSkipped = "skipped" // the schedule or its roles were still being crawled
+	RunFailed  = "failed"
+)
+
+// ScheduleStore persists crawl schedules.
+type ScheduleStore interface {
+	CreateSchedule(ctx context.Context, schedule Schedule) (Schedule, error)
+	GetSchedule(ctx context.Context, id string) (*Schedule, error)
+	// ListSchedules returns every schedule, oldest first.
+	ListSchedules(ctx context.Context) ([]Schedule, error)
+	// UpdateSchedule replaces what a schedule crawls and when, keeping its
+	// creation time and last run.
+	UpdateSchedule(ctx context.Context, schedule Schedule) (Schedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	// RecordScheduleRun sets the last run of a schedule.
+	RecordScheduleRun(ctx context.Context, id string, run ScheduleRun) error
+}
+
+// Schedule crawls a set of roles on a set of boards at the times of a cron
+// expression.
+type Schedule struct {
+	ID            string       `bson:"_id,omitempty" json:"id"`
+	Name          string       `bson:"name" json:"name"`
+	Cron          string       `bson:"cron" json:"cron"` // five fields, or @hourly, @daily, @every 6h, ...
+	Roles         []string     `bson:"roles" json:"roles"`
+	Sources       []string     `bson:"sources,omitempty" json:"sources,omitempty"`  // board hosts; empty means every board
+	MaxJobs       int          `bson:"maxJobs,omitempty" json:"max_jobs,omitempty"` // per run; 0 means the crawler's default
+	JitterSeconds int          `bson:"jitterSeconds" json:"jitter_seconds"`         // runs start up to this much after their time
+	Enabled       bool         `bson:"enabled" json:"enabled"`
+	CreatedAt     time.Time    `bson:"createdAt" json:"created_at"`
+	UpdatedAt     time.Time    `bson:"updatedAt" json:"updated_at"`
+	LastRun       *ScheduleRun `bson:"lastRun,omitempty" json:"last_run,omitempty"`
+}
+
+// ScheduleRun is how a scheduled crawl went.
+type ScheduleRun struct {
+	StartedAt time.Time `bson:"startedAt" json:"started_at"`
+	Duration  float64   `bson:"duration" json:"duration_seconds"`
+	Status    string    `bson:"status" json:"status"` // ok, skipped or failed
+	Error     string    `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// Validate checks the schedule's fields, but not whether its roles and sources
+// exist; the crawler knows those.
+func (s Schedule) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("name is required")
+	}
+	if _, err := cron.ParseStandard(s.Cron); err != nil {
+		return fmt.Errorf("invalid cron expression: %w", err)
+	}
+	if len(s.Roles) == 0 {
+		return errors.New("at least one role is required")
+	}
+	if s.MaxJobs < 0 {
+		return errors.New("max_jobs must not be negative")
+	}
+	if s.JitterSeconds < 0 || s.JitterSeconds > 3600 {
+		return errors.New("jitter_seconds must be between 0 and 3600")
+	}
+	return nil
+}
+
+// Next returns the first time after t that the schedule fires, before jitter.
+// It is zero if the cron expression is invalid.
+func (s Schedule) Next(t time.Time) time.Time {
+	spec, err := cron.ParseStandard(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return spec.Next(t)
+}
+
+// sortSchedules orders schedules oldest first, then by ID.
+func sortSchedules(schedules []Schedule) {
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].CreatedAt.Equal(schedules[j].CreatedAt) {
+			return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+}
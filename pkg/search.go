@@ -0,0 +1,372 @@
+package pkg
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Orders of JobQuery.Sort.
+const (
+	SortNewest    = "newest"    // most recently posted first, the default
+	SortOldest    = "oldest"    // least recently posted first
+	SortSalary    = "salary"    // highest salary minimum first
+	SortRelevance = "relevance" // best text match first, requires JobQuery.Text
+)
+
+var (
+	// ErrInvalidCursor is returned by ParseCursor for a cursor it did not issue.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidQuery matches the errors of ParseJobQuery for bad parameters.
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+// Cursor marks where a page of results ended. Keyset orders carry the sort key
+// and ID of the last posting, so a page is not shifted by postings inserted or
+// removed meanwhile; relevance, whose score the stores can't filter on, carries
+// an offset.
+type Cursor struct {
+	Sort     string    `json:"s"`
+	PostedOn time.Time `json:"p,omitempty"`
+	Salary   int       `json:"m,omitempty"`
+	ID       string    `json:"i,omitempty"`
+	Offset   int       `json:"o,omitempty"`
+}
+
+// CheckSort validates a JobQuery.Sort value; "" is the default order.
+func CheckSort(order string, text string) error {
+	switch order {
+	case "", SortNewest, SortOldest, SortSalary:
+		return nil
+	case SortRelevance:
+		if len(searchTerms(text)) == 0 {
+			return errors.New("sorting by relevance needs a text query")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown sort order %q", order)
+}
+
+// NextCursor returns the cursor continuing q after page, the postings q just
+// returned.
+func (q JobQuery) NextCursor(page []JobPosting) Cursor {
+	order := q.sortOrder()
+	if order == SortRelevance {
+		offset := len(page)
+		if q.After != nil {
+			offset += q.After.Offset
+		}
+		return Cursor{Sort: order, Offset: offset}
+	}
+	last := page[len(page)-1]
+	return Cursor{Sort: order, PostedOn: last.PostedOn, Salary: last.SalaryMin, ID: last.ID}
+}
+
+// Encode renders the cursor as an opaque URL-safe token.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a token made by Encode.
+func ParseCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	switch c.Sort {
+	case SortNewest, SortOldest, SortSalary, SortRelevance:
+		return &c, nil
+	}
+	return nil, ErrInvalidCursor
+}
+
+// ParseJobQuery reads the search filters shared by the jobs API, exports and
+// the CLI. Every parameter is optional:
+//
+//	q              words that must all appear in the title or description
+//	skills         comma-separated skills a posting must all require
+//	any_skills     comma-separated skills a posting must require at least one of
+//	role           slug, name or synonym of a role in Roles()
+//	location       city, region, country or country code
+//	company        any spelling of the company name
+//	source         job board host, e.g. weworkremotely.com
+//	posted_since   date (2006-01-02) or RFC 3339 timestamp
+//	salary_min     annual amount the salary range must reach
+//	salary_max     annual amount the salary range must start at or below
+//	experience     years of experience the posting must accept
+//	status         open, not-seen-recently or closed; closed postings are
+//	               left out unless asked for
+//	sort           newest (default), oldest, salary or relevance
+//
+// Invalid parameters return an error matching ErrInvalidQuery, with a message
+// fit to show the caller; other errors come from looking up the company.
+func ParseJobQuery(ctx context.Context, companies CompanyStore, params url.Values) (JobQuery, error) {
+	q := JobQuery{
+		Text:      strings.TrimSpace(params.Get("q")),
+		Source:    strings.TrimSpace(params.Get("source")),
+		Location:  strings.TrimSpace(params.Get("location")),
+		SkillsAll: splitList(params.Get("skills")),
+		SkillsAny: splitList(params.Get("any_skills")),
+		Status:    params.Get("status"),
+		Sort:      params.Get("sort"),
+		OpenOnly:  true,
+	}
+	invalid := func(format string, args ...interface{}) (JobQuery, error) {
+		return q, queryError(fmt.Sprintf(format, args...))
+	}
+
+	switch q.Status {
+	case "":
+	case StatusClosed:
+		q.OpenOnly = false
+	case StatusOpen, StatusNotSeen:
+	default:
+		return invalid("status must be open, not-seen-recently or closed")
+	}
+	if err := CheckSort(q.Sort, q.Text); err != nil {
+		return invalid("%v", err)
+	}
+	if v := strings.TrimSpace(params.Get("role")); v != "" {
+		role, ok := ResolveRole(v)
+		if !ok {
+			return invalid("unknown role %q", v)
+		}
+		q.Role = role.Slug
+	}
+
+	if v := params.Get("posted_since"); v != "" {
+		since, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			since, err = time.Parse(time.RFC3339, v)
+		}
+		if err != nil {
+			return invalid("posted_since must be a date (2006-01-02) or RFC 3339 timestamp")
+		}
+		q.PostedSince = since
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"salary_min", &q.SalaryMin}, {"salary_max", &q.SalaryMax}} {
+		if v := params.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return invalid("%s must be a non-negative integer", p.name)
+			}
+			*p.dst = n
+		}
+	}
+	if v := params.Get("experience"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return invalid("experience must be a non-negative integer")
+		}
+		q.Experience = &n
+	}
+
+	if name := strings.TrimSpace(params.Get("company")); name != "" {
+		company, err := companies.GetCompany(ctx, name)
+		switch {
+		case errors.Is(err, ErrCompanyNotFound):
+			// An unknown company has no postings; its key matches none
+			q.CompanyID = NormalizeCompanyKey(name)
+		case err != nil:
+			return q, fmt.Errorf("look up company: %w", err)
+		default:
+			q.CompanyID = company.ID
+		}
+	}
+	return q, nil
+}
+
+// queryError is a bad parameter of ParseJobQuery. Its message is meant for
+// whoever sent the parameter.
+type queryError string
+
+func (e queryError) Error() string { return string(e) }
+
+func (e queryError) Is(target error) bool { return target == ErrInvalidQuery }
+
+// splitList reads a comma-separated parameter, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (q JobQuery) sortOrder() string {
+	if q.Sort == "" {
+		return SortNewest
+	}
+	return q.Sort
+}
+
+// sortJobs orders postings the way q asks, breaking ties by newest and then ID
+// so that results are stable.
+func (q JobQuery) sortJobs(jobs []JobPosting) {
+	newest := func(a, b JobPosting) bool {
+		if !a.PostedOn.Equal(b.PostedOn) {
+			return a.PostedOn.After(b.PostedOn)
+		}
+		return a.ID > b.ID
+	}
+
+	switch q.sortOrder() {
+	case SortOldest:
+		sort.Slice(jobs, func(i, j int) bool { return newest(jobs[j], jobs[i]) })
+	case SortSalary:
+		sort.Slice(jobs, func(i, j int) bool {
+			if jobs[i].SalaryMin != jobs[j].SalaryMin {
+				return jobs[i].SalaryMin > jobs[j].SalaryMin
+			}
+			return jobs[i].ID > jobs[j].ID
+		})
+	case SortRelevance:
+		terms := searchTerms(q.Text)
+		scores := make(map[string]int, len(jobs))
+		for _, job := range jobs {
+			scores[job.ID] = textScore(job, terms)
+		}
+		sort.Slice(jobs, func(i, j int) bool {
+			if si, sj := scores[jobs[i].ID], scores[jobs[j].ID]; si != sj {
+				return si > sj
+			}
+			return newest(jobs[i], jobs[j])
+		})
+	default:
+		sort.Slice(jobs, func(i, j int) bool { return newest(jobs[i], jobs[j]) })
+	}
+}
+
+// isAfter reports whether job comes after the cursor in q's order. Relevance
+// pages by offset and never filters.
+func (q JobQuery) isAfter(job JobPosting) bool {
+	c := q.After
+	if c == nil {
+		return true
+	}
+	switch q.sortOrder() {
+	case SortOldest:
+		return job.PostedOn.After(c.PostedOn) || job.PostedOn.Equal(c.PostedOn) && job.ID > c.ID
+	case SortSalary:
+		return job.SalaryMin < c.Salary || job.SalaryMin == c.Salary && job.ID < c.ID
+	case SortRelevance:
+		return true
+	default:
+		return job.PostedOn.Before(c.PostedOn) || job.PostedOn.Equal(c.PostedOn) && job.ID < c.ID
+	}
+}
+
+// skip is how many matching postings to pass over: Skip, plus the offset of a
+// relevance cursor.
+func (q JobQuery) skip() int {
+	if q.After != nil && q.sortOrder() == SortRelevance {
+		return q.Skip + q.After.Offset
+	}
+	return q.Skip
+}
+
+// searchTerms splits a text query into lowercase words. Every store matches
+// each word on its own and requires all of them.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func matchesTerms(job JobPosting, terms []string) bool {
+	title, description := strings.ToLower(job.Title), strings.ToLower(job.Description)
+	for _, term := range terms {
+		if !strings.Contains(title, term) && !strings.Contains(description, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// textScore weighs a match in the title ten times one in the description, as
+// the title_description_text index does.
+func textScore(job JobPosting, terms []string) int {
+	title, description := strings.ToLower(job.Title), strings.ToLower(job.Description)
+	score := 0
+	for _, term := range terms {
+		score += 10*strings.Count(title, term) + strings.Count(description, term)
+	}
+	return score
+}
+
+// matchesLocation is a case-insensitive partial match on the location text and
+// the names of its structured entries, or an exact country code.
+func matchesLocation(job JobPosting, location string) bool {
+	location = strings.ToLower(strings.TrimSpace(location))
+	if strings.Contains(strings.ToLower(job.Location), location) {
+		return true
+	}
+	for _, e := range job.Locations {
+		for _, v := range []string{e.Name, e.Country, e.Region} {
+			if v != "" && strings.Contains(strings.ToLower(v), location) {
+				return true
+			}
+		}
+		if strings.EqualFold(e.CountryCode, location) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSalary checks the salary bounds of q against the posting's parsed
+// range, in which a max of 0 is open-ended. Postings without a salary never
+// match a bound.
+func (q JobQuery) matchesSalary(job JobPosting) bool {
+	if q.SalaryMin <= 0 && q.SalaryMax <= 0 && !q.SalaryKnown {
+		return true
+	}
+	if job.SalaryMin <= 0 && job.SalaryMax <= 0 {
+		return false
+	}
+	if q.SalaryMin > 0 && job.SalaryMax > 0 && job.SalaryMax < q.SalaryMin {
+		return false
+	}
+	if q.SalaryMax > 0 && job.SalaryMin > q.SalaryMax {
+		return false
+	}
+	return true
+}
+
+// matchesExperience checks that the posting's experience range, in which a max
+// of 0 is open-ended, accepts q.Experience years.
+func (q JobQuery) matchesExperience(job JobPosting) bool {
+	if q.Experience == nil {
+		return true
+	}
+	r, years := job.ExperienceRange, *q.Experience
+	return r != nil && r.Min <= years && (r.Max == 0 || r.Max >= years)
+}
+
+func containsAny(list, values []string) bool {
+	for _, v := range values {
+		if containsString(list, v) {
+			return true
+		}
+	}
+	return false
+}
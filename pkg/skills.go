@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"sort"
+	"strings"
+)
+
+// skillAliases maps common spellings to the canonical skill name.
+var skillAliases = map[string]string{
+	"golang":                "go",
+	"k8s":                   "kubernetes",
+	"js":                    "javascript",
+	"ecmascript":            "javascript",
+	"ts":                    "typescript",
+	"nodejs":                "node.js",
+	"node":                  "node.js",
+	"node js":               "node.js",
+	"reactjs":               "react",
+	"react.js":              "react",
+	"vuejs":                 "vue",
+	"vue.js":                "vue",
+	"postgres":              "postgresql",
+	"psql":                  "postgresql",
+	"mongo":                 "mongodb",
+	"elastic search":        "elasticsearch",
+	"amazon web services":   "aws",
+	"google cloud":          "gcp",
+	"google cloud platform": "gcp",
+	"microsoft azure":       "azure",
+	"ci / cd":               "ci/cd",
+	"cicd":                  "ci/cd",
+	"gh actions":            "github actions",
+	"travis ci":             "travisci",
+	"circle ci":             "circleci",
+	"argo cd":               "argocd",
+	"rest api":              "rest",
+	"restful":               "rest",
+	"c sharp":               "c#",
+	"csharp":                "c#",
+	"py":                    "python",
+	"python3":               "python",
+}
+
+// NormalizeSkill returns the canonical form of one skill: lowercase, single
+// spaces, and common aliases such as "golang" or "k8s" resolved.
+func NormalizeSkill(skill string) string {
+	s := strings.Join(strings.Fields(strings.ToLower(skill)), " ")
+	if canonical, ok := skillAliases[s]; ok {
+		return canonical
+	}
+	return s
+}
+
+// NormalizeSkills normalizes every skill and returns them sorted without
+// duplicates or empty entries.
+func NormalizeSkills(skills []string) []string {
+	if skills == nil {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(skills))
+	out := make([]string, 0, len(skills))
+	for _, skill := range skills {
+		s := NormalizeSkill(skill)
+		if _, dup := seen[s]; dup || s == "" {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
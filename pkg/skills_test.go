@@ -0,0 +1,17 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSkills(t *testing.T) {
+	got := NormalizeSkills([]string{"Golang", " K8s ", "React.js", "go", "Node  JS", "", "AWS", "Postgres"})
+	want := []string{"aws", "go", "kubernetes", "node.js", "postgresql", "react"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeSkills = %v, want %v", got, want)
+	}
+	if NormalizeSkills(nil) != nil {
+		t.Error("expected nil for no skills")
+	}
+}
@@ -0,0 +1,430 @@
+package pkg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sqliteSchema is the first migration: the tables as they were first released.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS jobs (
+		id               TEXT PRIMARY KEY,
+		hash             TEXT NOT NULL UNIQUE,
+		description_hash TEXT NOT NULL,
+		cluster_key      TEXT NOT NULL,
+		company_id       TEXT NOT NULL,
+		title            TEXT NOT NULL,
+		source           TEXT NOT NULL,
+		posted_on        INTEGER NOT NULL, -- unix milliseconds
+		expire_at        INTEGER NOT NULL, -- unix milliseconds
+		data             TEXT NOT NULL     -- JobPosting as JSON
+	)`,
+	`CREATE INDEX IF NOT EXISTS jobs_cluster ON jobs (cluster_key, posted_on)`,
+	`CREATE INDEX IF NOT EXISTS jobs_company ON jobs (company_id)`,
+	`CREATE INDEX IF NOT EXISTS jobs_posted ON jobs (posted_on)`,
+	`CREATE INDEX IF NOT EXISTS jobs_expire ON jobs (expire_at)`,
+	`CREATE TABLE IF NOT EXISTS job_aliases (
+		hash   TEXT PRIMARY KEY,
+		job_id TEXT NOT NULL REFERENCES jobs (id) ON DELETE CASCADE
+	)`,
+	`CREATE TABLE IF NOT EXISTS job_history (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		job_id     TEXT NOT NULL,
+		changed_at INTEGER NOT NULL, -- unix milliseconds
+		data       TEXT NOT NULL     -- Revision as JSON
+	)`,
+	`CREATE INDEX IF NOT EXISTS job_history_job ON job_history (job_id, changed_at)`,
+	`CREATE TABLE IF NOT EXISTS companies (
+		id   TEXT PRIMARY KEY,
+		data TEXT NOT NULL -- Company as JSON
+	)`,
+}
+
+// sqliteSearchSchema indexes postings for search: an FTS5 table over titles and
+// descriptions kept in sync by triggers, and the salary sort key. The FTS rows
+// share the rowid of their posting, which stays stable as long as the database
+// is not vacuumed.
+var sqliteSearchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS jobs_fts USING fts5 (title, description, tokenize = 'porter unicode61')`,
+	`CREATE TRIGGER IF NOT EXISTS jobs_fts_insert AFTER INSERT ON jobs BEGIN
+		INSERT INTO jobs_fts (rowid, title, description) VALUES (new.rowid, new.title, json_extract(new.data, '$.description'));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS jobs_fts_update AFTER UPDATE OF title, data ON jobs
+	WHEN old.title IS NOT new.title OR json_extract(old.data, '$.description') IS NOT json_extract(new.data, '$.description') BEGIN
+		DELETE FROM jobs_fts WHERE rowid = old.rowid;
+		INSERT INTO jobs_fts (rowid, title, description) VALUES (new.rowid, new.title, json_extract(new.data, '$.description'));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS jobs_fts_delete AFTER DELETE ON jobs BEGIN
+		DELETE FROM jobs_fts WHERE rowid = old.rowid;
+	END`,
+	`INSERT INTO jobs_fts (rowid, title, description)
+		SELECT rowid, title, json_extract(data, '$.description') FROM jobs`,
+	`ALTER TABLE jobs ADD COLUMN salary_min INTEGER
+		GENERATED ALWAYS AS (COALESCE(json_extract(data, '$.salary_min'), 0)) VIRTUAL`,
+	`CREATE INDEX IF NOT EXISTS jobs_salary ON jobs (salary_min, id)`,
+}
+
+func (s *SQLiteStore) Migrate(ctx context.Context, opts MigrateOptions) ([]MigrationResult, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runMigrations(ctx, s.migrations(), applied, func(ctx context.Context, m migration, at time.Time) error {
+		_, err := s.db.ExecContext(ctx, `INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Description, at.UnixMilli())
+		return err
+	}, opts)
+}
+
+func (s *SQLiteStore) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migrationStatus(s.migrations(), applied), nil
+}
+
+func (s *SQLiteStore) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	// The ledger itself is not versioned, it has to exist before anything else
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at  INTEGER NOT NULL -- unix milliseconds
+	)`); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at int64
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = time.UnixMilli(at)
+	}
+	return applied, rows.Err()
+}
+
+// migrations lists the SQLite schema history. Never edit an applied migration;
+// add a new one instead.
+func (s *SQLiteStore) migrations() []migration {
+	return []migration{
+		{1, "Create jobs, job_aliases, job_history and companies tables", func(ctx context.Context, run *migrationRun) error {
+			return s.exec(ctx, run, sqliteSchema...)
+		}},
+		{2, "Normalize skills on stored postings", func(ctx context.Context, run *migrationRun) error {
+			return s.normalizeStoredSkills(ctx, run)
+		}},
+		{3, "Full-text search and salary ranges", func(ctx context.Context, run *migrationRun) error {
+			if err := s.parseStoredSalaries(ctx, run); err != nil {
+				return err
+			}
+			return s.exec(ctx, run, sqliteSearchSchema...)
+		}},
+		{4, "Create saved_searches table", func(ctx context.Context, run *migrationRun) error {
+			return s.exec(ctx, run, `CREATE TABLE IF NOT EXISTS saved_searches (
+				id         TEXT PRIMARY KEY,
+				created_at INTEGER NOT NULL, -- unix milliseconds
+				data       TEXT NOT NULL     -- SavedSearch as JSON
+			)`)
+		}},
+		{5, "Create webhooks and webhook_deliveries tables", func(ctx context.Context, run *migrationRun) error {
+			return s.exec(ctx, run,
+				`CREATE TABLE IF NOT EXISTS webhooks (
+					id         TEXT PRIMARY KEY,
+					created_at INTEGER NOT NULL, -- unix milliseconds
+					data       TEXT NOT NULL     -- Webhook as JSON
+				)`,
+				`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+					id           INTEGER PRIMARY KEY AUTOINCREMENT,
+					webhook_id   TEXT NOT NULL,
+					delivered_at INTEGER NOT NULL, -- unix milliseconds
+					data         TEXT NOT NULL     -- Delivery as JSON
+				)`,
+				`CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook ON webhook_deliveries (webhook_id, delivered_at)`,
+			)
+		}},
+		{6, "Create schedules table", func(ctx context.Context, run *migrationRun) error {
+			return s.exec(ctx, run, `CREATE TABLE IF NOT EXISTS schedules (
+				id         TEXT PRIMARY KEY,
+				created_at INTEGER NOT NULL, -- unix milliseconds
+				data       TEXT NOT NULL     -- Schedule as JSON
+			)`)
+		}},
+		{7, "Create crawl queue table", func(ctx context.Context, run *migrationRun) error {
+			return s.exec(ctx, run,
+				`CREATE TABLE IF NOT EXISTS crawl_tasks (
+				id          TEXT PRIMARY KEY,
+				role_key    TEXT NOT NULL,
+				status      TEXT NOT NULL,
+				lease_until INTEGER,      -- unix milliseconds, while running
+				enqueued_at INTEGER NOT NULL,
+				finished_at INTEGER,
+				data        TEXT NOT NULL -- CrawlTask as JSON
+			)`,
+				`CREATE INDEX IF NOT EXISTS crawl_tasks_status ON crawl_tasks (status, enqueued_at)`,
+				`CREATE INDEX IF NOT EXISTS crawl_tasks_role ON crawl_tasks (role_key, status)`,
+			)
+		}},
+		{8, "Classify stored postings into taxonomy roles", func(ctx context.Context, run *migrationRun) error {
+			return s.classifyStoredRoles(ctx, run)
+		}},
+		{9, "Create roles table", func(ctx context.Context, run *migrationRun) error {
+			return s.exec(ctx, run, `CREATE TABLE IF NOT EXISTS roles (
+				slug TEXT PRIMARY KEY,
+				data TEXT NOT NULL -- Role as JSON
+			)`)
+		}},
+		{10, "Create trend_results table", func(ctx context.Context, run *migrationRun) error {
+			return s.exec(ctx, run, `CREATE TABLE IF NOT EXISTS trend_results (
+				key       TEXT PRIMARY KEY,
+				expire_at INTEGER NOT NULL, -- unix ms
+				data      TEXT NOT NULL     -- TrendResult as JSON
+			)`)
+		}},
+		{11, "Backfill company keys and recompute dedupe keys, merging postings that collide", func(ctx context.Context, run *migrationRun) error {
+			return s.backfillCompanyIDs(ctx, run)
+		}},
+		{12, "Allow one active crawl task per role", func(ctx context.Context, run *migrationRun) error {
+			if err := s.failDuplicateCrawls(ctx, run); err != nil {
+				return err
+			}
+			return s.exec(ctx, run, `CREATE UNIQUE INDEX IF NOT EXISTS crawl_tasks_active_role
+				ON crawl_tasks (role_key) WHERE status IN ('queued', 'running')`)
+		}},
+		{13, "Index postings by when they were closed", func(ctx context.Context, run *migrationRun) error {
+			return s.exec(ctx, run, `CREATE INDEX IF NOT EXISTS jobs_closed_at ON jobs (julianday(json_extract(data, '$.closed_at')))`)
+		}},
+	}
+}
+
+// exec runs each statement as its own step.
+func (s *SQLiteStore) exec(ctx context.Context, run *migrationRun, stmts ...string) error {
+	for _, stmt := range stmts {
+		err := run.step(strings.TrimSpace(firstLine(stmt)), func() error {
+			_, err := s.db.ExecContext(ctx, stmt)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func firstLine(stmt string) string {
+	for i, r := range stmt {
+		if r == '\n' || r == '(' {
+			return stmt[:i]
+		}
+	}
+	return stmt
+}
+
+// hasJobsTable is false in a dry run from scratch, before the jobs table exists.
+func (s *SQLiteStore) hasJobsTable(ctx context.Context) (bool, error) {
+	var tables int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'jobs'`).Scan(&tables)
+	return tables > 0, err
+}
+
+func (s *SQLiteStore) normalizeStoredSkills(ctx context.Context, run *migrationRun) error {
+	if ok, err := s.hasJobsTable(ctx); !ok {
+		return err
+	}
+
+	stored, err := queryJobRows(ctx, s.db, `SELECT data FROM jobs WHERE json_array_length(data, '$.skills') > 0`)
+	if err != nil {
+		return err
+	}
+	var changed []JobPosting
+	for _, job := range stored {
+		if normalized := NormalizeSkills(job.Skills); !equalStrings(normalized, job.Skills) {
+			job.Skills = normalized
+			changed = append(changed, job)
+		}
+	}
+	return s.updateJobRows(ctx, run, "normalize skills", changed)
+}
+
+func (s *SQLiteStore) parseStoredSalaries(ctx context.Context, run *migrationRun) error {
+	if ok, err := s.hasJobsTable(ctx); !ok {
+		return err
+	}
+
+	stored, err := queryJobRows(ctx, s.db, `SELECT data FROM jobs WHERE json_extract(data, '$.salary') != ''`)
+	if err != nil {
+		return err
+	}
+	var changed []JobPosting
+	for _, job := range stored {
+		min, max, currency := ParseSalary(job.Salary)
+		if min != job.SalaryMin || max != job.SalaryMax || currency != job.SalaryCurrency {
+			job.SalaryMin, job.SalaryMax, job.SalaryCurrency = min, max, currency
+			changed = append(changed, job)
+		}
+	}
+	return s.updateJobRows(ctx, run, "parse salary ranges", changed)
+}
+
+func (s *SQLiteStore) classifyStoredRoles(ctx context.Context, run *migrationRun) error {
+	if ok, err := s.hasJobsTable(ctx); !ok {
+		return err
+	}
+
+	stored, err := queryJobRows(ctx, s.db, `SELECT data FROM jobs`)
+	if err != nil {
+		return err
+	}
+	var changed []JobPosting
+	for _, job := range stored {
+		if roles := ClassifyRoles(job.Title, job.Skills); !equalStrings(roles, job.Roles) {
+			job.Roles = roles
+			changed = append(changed, job)
+		}
+	}
+	return s.updateJobRows(ctx, run, "classify roles", changed)
+}
+
+// backfillCompanyIDs resolves the company of postings stored without a company
+// key and recomputes every hash and cluster key not derived from one. A
+// posting whose new hash is already taken is folded into the one holding it.
+func (s *SQLiteStore) backfillCompanyIDs(ctx context.Context, run *migrationRun) error {
+	if ok, err := s.hasJobsTable(ctx); !ok {
+		return err
+	}
+
+	stored, err := queryJobRows(ctx, s.db, `SELECT data FROM jobs ORDER BY posted_on, id`)
+	if err != nil {
+		return err
+	}
+	var stale []JobPosting
+	for _, job := range stored {
+		rehashed := job
+		if rehashed.CompanyID == "" {
+			rehashed.CompanyID = NormalizeCompanyKey(CleanCompanyName(job.Company))
+		}
+		setDedupeKeys(&rehashed)
+		if rehashed.CompanyID != job.CompanyID || rehashed.Hash != job.Hash || rehashed.ClusterKey != job.ClusterKey {
+			stale = append(stale, job)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	return run.step(fmt.Sprintf("resolve companies and rehash %d postings", len(stale)), func() error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		now := time.Now()
+		for _, job := range stale {
+			if err := rehashJobRow(ctx, tx, job, now); err != nil {
+				return fmt.Errorf("posting %s: %w", job.ID, err)
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// rehashJobRow gives job its company key and dedupe keys, or folds it into
+// the posting that already has its new hash.
+func rehashJobRow(ctx context.Context, tx *sql.Tx, job JobPosting, now time.Time) error {
+	if job.CompanyID == "" {
+		company, err := resolveCompanySQLite(ctx, tx, job.Company, job.ApplyURL, now)
+		if err != nil {
+			return err
+		}
+		job.Company, job.CompanyID = company.Name, company.ID
+	}
+	setDedupeKeys(&job)
+
+	canonical, err := scanJob(tx.QueryRowContext(ctx, `
+		SELECT data FROM jobs WHERE hash = ? AND id <> ?
+		UNION ALL
+		SELECT j.data FROM jobs j JOIN job_aliases a ON a.job_id = j.id WHERE a.hash = ? AND j.id <> ?
+		LIMIT 1`, job.Hash, job.ID, job.Hash, job.ID))
+	if errors.Is(err, ErrJobNotFound) {
+		return updateJobRow(ctx, tx, job)
+	}
+	if err != nil {
+		return err
+	}
+
+	absorbDuplicate(canonical, job)
+	if err := updateJobRow(ctx, tx, *canonical); err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		`UPDATE job_aliases SET job_id = ? WHERE job_id = ?`,
+		`UPDATE job_history SET job_id = ?1, data = json_set(data, '$.job_id', ?1) WHERE job_id = ?2`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, canonical.ID, job.ID); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM jobs WHERE id = ?`, job.ID)
+	return err
+}
+
+// updateJobRows rewrites changed postings in one transaction, as a single step.
+func (s *SQLiteStore) updateJobRows(ctx context.Context, run *migrationRun, what string, changed []JobPosting) error {
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return run.step(fmt.Sprintf("%s on %d postings", what, len(changed)), func() error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		for _, job := range changed {
+			if err := updateJobRow(ctx, tx, job); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// sqliteDuplicateCrawl matches the active crawl tasks enqueued after another
+// active task of their role.
+const sqliteDuplicateCrawl = `status IN (?1, ?2) AND EXISTS (
+	SELECT 1 FROM crawl_tasks AS first WHERE first.role_key = crawl_tasks.role_key AND first.status IN (?1, ?2)
+		AND (first.enqueued_at < crawl_tasks.enqueued_at OR first.enqueued_at = crawl_tasks.enqueued_at AND first.id < crawl_tasks.id))`
+
+// failDuplicateCrawls fails every active crawl task but the first of its role,
+// so that a role's active task can be made unique.
+func (s *SQLiteStore) failDuplicateCrawls(ctx context.Context, run *migrationRun) error {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM crawl_tasks WHERE `+sqliteDuplicateCrawl,
+		TaskQueued, TaskRunning).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	return run.step(fmt.Sprintf("fail %d crawl tasks duplicating an active task of their role", n), func() error {
+		now := time.Now()
+		_, err := s.db.ExecContext(ctx, `UPDATE crawl_tasks SET status = ?3, lease_until = NULL, finished_at = ?4,
+			data = json_remove(json_set(data, '$.status', ?3, '$.error', ?5, '$.finished_at', ?6), '$.lease_until')
+			WHERE `+sqliteDuplicateCrawl,
+			TaskQueued, TaskRunning, TaskFailed, now.UnixMilli(), duplicateTaskError, now.UTC().Format(time.RFC3339Nano))
+		return err
+	})
+}
@@ -0,0 +1,1275 @@
+package pkg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore is a single-file Store for running without external services.
+// Postings are kept as JSON documents with the columns that dedupe, filtering
+// and TTL need pulled out and indexed.
+type SQLiteStore struct {
+	db   *sql.DB
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// sqlitePurgeInterval is how often expired postings are deleted, mirroring the
+// Mongo TTL monitor.
+const sqlitePurgeInterval = time.Hour
+
+// NewSQLiteStore opens (or creates) the database file at path and applies
+// pending migrations.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	s, err := openSQLiteStore(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Migrate(context.Background(), MigrateOptions{}); err != nil {
+		s.Close(context.Background())
+		return nil, err
+	}
+	if err := s.purgeExpired(context.Background()); err != nil {
+		log.Printf("[sqlite] Failed to purge expired jobs: %v", err)
+	}
+	return s, nil
+}
+
+// openSQLiteStore opens the database without touching its schema.
+func openSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// One connection serializes writers, which is what SQLite wants anyway
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{db: db, stop: make(chan struct{})}
+	s.done.Add(1)
+	go s.purgeLoop()
+
+	log.Printf("[sqlite] Opened %s", path)
+	return s, nil
+}
+
+func (s *SQLiteStore) Close(context.Context) error {
+	close(s.stop)
+	s.done.Wait()
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) purgeLoop() {
+	defer s.done.Done()
+	ticker := time.NewTicker(sqlitePurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.purgeExpired(context.Background()); err != nil {
+				log.Printf("[sqlite] Failed to purge expired jobs: %v", err)
+			}
+		}
+	}
+}
+
+// purgeExpired deletes postings past expireAt, deliveries older than
+// DeliveryRetention and crawl tasks finished before TaskRetention, like the
+// Mongo TTL indexes.
+func (s *SQLiteStore) purgeExpired(ctx context.Context) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM jobs WHERE expire_at <= ?`, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("[sqlite] Purged %d expired jobs", n)
+	}
+	_, err = s.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE delivered_at <= ?`,
+		time.Now().Add(-DeliveryRetention).UnixMilli())
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `DELETE FROM crawl_tasks WHERE finished_at <= ?`,
+		time.Now().Add(-TaskRetention).UnixMilli())
+	return err
+}
+
+func (s *SQLiteStore) UpsertJob(ctx context.Context, job JobPosting) (UpsertResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := s.upsertTx(ctx, tx, job)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	return result, tx.Commit()
+}
+
+// UpsertJobs writes the batch in one transaction, with a savepoint per posting
+// so that a failing posting only rolls back itself.
+func (s *SQLiteStore) UpsertJobs(ctx context.Context, jobs []JobPosting) (BatchResult, error) {
+	result := newBatchResult(len(jobs))
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	for i, job := range jobs {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT item`); err != nil {
+			return result, err
+		}
+		res, err := s.upsertTx(ctx, tx, job)
+		if err != nil {
+			if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO item`); rbErr != nil {
+				return result, rbErr
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE item`); err != nil {
+			return result, err
+		}
+		result.record(i, job, res, err)
+	}
+	return result, tx.Commit()
+}
+
+func (s *SQLiteStore) upsertTx(ctx context.Context, tx *sql.Tx, job JobPosting) (UpsertResult, error) {
+	now := time.Now()
+	company, err := resolveCompanySQLite(ctx, tx, job.Company, job.ApplyURL, now)
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("resolve company %q: %w", job.Company, err)
+	}
+	prepareJob(&job, company, now)
+
+	// Exact match on our own hash or on the hash of a posting merged into this one
+	existing, err := scanJob(tx.QueryRowContext(ctx, `
+		SELECT data FROM jobs WHERE hash = ?
+		UNION ALL
+		SELECT j.data FROM jobs j JOIN job_aliases a ON a.job_id = j.id WHERE a.hash = ?
+		LIMIT 1`, job.Hash, job.Hash))
+	if err == nil {
+		// Update only if a material field changed, keeping the old values as a revision
+		if changes := materialChanges(*existing, job); len(changes) > 0 {
+			if err := insertRevision(ctx, tx, newRevision(existing.ID, job, changes, now)); err != nil {
+				return UpsertResult{}, fmt.Errorf("record revision: %w", err)
+			}
+			if err := updateJobRow(ctx, tx, replacementFor(*existing, job, now)); err != nil {
+				return UpsertResult{}, err
+			}
+			return UpsertResult{ID: existing.ID, Action: UpsertUpdated}, nil
+		}
+
+		existing.LastUpdated = now
+		markSeen(existing, now)
+		if err := updateJobRow(ctx, tx, *existing); err != nil {
+			return UpsertResult{}, err
+		}
+		return UpsertResult{ID: existing.ID, Action: UpsertUnchanged}, nil
+	}
+	if !errors.Is(err, ErrJobNotFound) {
+		return UpsertResult{}, err
+	}
+
+	// Same job re-posted or seen on another board: attach it to the canonical posting
+	candidates, err := queryJobRows(ctx, tx, `
+		SELECT data FROM jobs WHERE cluster_key = ? AND posted_on BETWEEN ? AND ?
+		ORDER BY posted_on DESC, id DESC LIMIT 50`,
+		job.ClusterKey,
+		job.PostedOn.Add(-NearDuplicateWindow).UnixMilli(),
+		job.PostedOn.Add(NearDuplicateWindow).UnixMilli())
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	if canonical := FindNearDuplicate(job, candidates); canonical != nil {
+		merged := *canonical
+		if !containsString(merged.AliasHashes, job.Hash) {
+			merged.AliasHashes = append(merged.AliasHashes, job.Hash)
+		}
+		if !hasSource(merged.Sources, job.URL) {
+			merged.Sources = append(merged.Sources, sourceLink(job, now))
+		}
+		merged.LastUpdated = now
+		markSeen(&merged, now)
+		if err := updateJobRow(ctx, tx, merged); err != nil {
+			return UpsertResult{}, err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO job_aliases (hash, job_id) VALUES (?, ?)`, job.Hash, merged.ID); err != nil {
+			return UpsertResult{}, err
+		}
+		return UpsertResult{ID: merged.ID, Action: UpsertMerged}, nil
+	}
+
+	doc := newJobDocument(job, now)
+	doc.ID = bson.NewObjectID().Hex()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO jobs (id, hash, description_hash, cluster_key, company_id, title, source, posted_on, expire_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		doc.ID, doc.Hash, doc.DescriptionHash, doc.ClusterKey, doc.CompanyID, doc.Title, doc.Source,
+		doc.PostedOn.UnixMilli(), doc.ExpireAt.UnixMilli(), string(data))
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	return UpsertResult{ID: doc.ID, Action: UpsertInserted}, nil
+}
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func updateJobRow(ctx context.Context, db sqlExecer, job JobPosting) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		UPDATE jobs SET hash = ?, description_hash = ?, cluster_key = ?, company_id = ?, title = ?,
+			source = ?, posted_on = ?, expire_at = ?, data = ?
+		WHERE id = ?`,
+		job.Hash, job.DescriptionHash, job.ClusterKey, job.CompanyID, job.Title,
+		job.Source, job.PostedOn.UnixMilli(), job.ExpireAt.UnixMilli(), string(data), job.ID)
+	return err
+}
+
+func scanJob(row *sql.Row) (*JobPosting, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	var job JobPosting
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func queryJobRows(ctx context.Context, db sqlExecer, query string, args ...any) ([]JobPosting, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []JobPosting
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var job JobPosting
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *SQLiteStore) GetJob(ctx context.Context, id string) (*JobPosting, error) {
+	return scanJob(s.db.QueryRowContext(ctx, `SELECT data FROM jobs WHERE id = ?`, id))
+}
+
+func (s *SQLiteStore) QueryJobs(ctx context.Context, q JobQuery) ([]JobPosting, error) {
+	where, args := sqliteWhere(q)
+	if after, afterArgs := sqliteAfter(q); after != "" {
+		if where == "" {
+			where = " WHERE " + after
+		} else {
+			where += " AND " + after
+		}
+		args = append(args, afterArgs...)
+	}
+
+	query := `SELECT data FROM jobs` + where + ` ORDER BY `
+	switch q.sortOrder() {
+	case SortOldest:
+		query += `jobs.posted_on ASC, jobs.id ASC`
+	case SortSalary:
+		query += `jobs.salary_min DESC, jobs.id DESC`
+	case SortRelevance:
+		// bm25 is lower for better matches; weigh the title as the Mongo text index does
+		query += `(SELECT bm25(jobs_fts, 10.0, 1.0) FROM jobs_fts WHERE jobs_fts MATCH ? AND jobs_fts.rowid = jobs.rowid),
+			jobs.posted_on DESC, jobs.id DESC`
+		args = append(args, ftsQuery(searchTerms(q.Text)))
+	default:
+		query += `jobs.posted_on DESC, jobs.id DESC`
+	}
+
+	if skip := q.skip(); q.Limit > 0 || skip > 0 {
+		limit := q.Limit
+		if limit <= 0 {
+			limit = -1 // SQLite: no limit
+		}
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, skip)
+	}
+	return queryJobRows(ctx, s.db, query, args...)
+}
+
+func (s *SQLiteStore) CountJobs(ctx context.Context, q JobQuery) (int, error) {
+	where, args := sqliteWhere(q)
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs`+where, args...).Scan(&n)
+	return n, err
+}
+
+// sqliteFields maps CountableFields to JSON paths in the stored document. When
+// array is set the array is expanded with json_each and path is read from each
+// element, the SQL counterpart of $unwind.
+var sqliteFields = map[string]struct{ array, path string }{
+	"skills":                {array: "$.skills"},
+	"location":              {path: "$.location"},
+	"company":               {path: "$.company"},
+	"companyId":             {path: "$.company_id"},
+	"source":                {path: "$.source"},
+	"experience":            {path: "$.experience"},
+	"seniority":             {path: "$.seniority"},
+	"employmentType":        {path: "$.employment_type"},
+	"remotePolicy":          {path: "$.remote_policy"},
+	"timezones":             {array: "$.timezones"},
+	"roles":                 {array: "$.roles"},
+	"salaryMin":             {path: "$.salary_min"}, // left out of the document when 0
+	"salaryMax":             {path: "$.salary_max"},
+	"salaryCurrency":        {path: "$.salary_currency"},
+	"locations.name":        {array: "$.locations", path: "$.name"},
+	"locations.country":     {array: "$.locations", path: "$.country"},
+	"locations.countryCode": {array: "$.locations", path: "$.country_code"},
+	"locations.region":      {array: "$.locations", path: "$.region"},
+}
+
+func (s *SQLiteStore) CountBy(ctx context.Context, field string, q JobQuery, limit int) ([]CountResult, error) {
+	if err := checkCountableField(field); err != nil {
+		return nil, err
+	}
+	f := sqliteFields[field]
+
+	var from, value string
+	switch {
+	case f.array != "" && f.path != "":
+		from = `jobs, json_each(jobs.data, '` + f.array + `') AS e`
+		value = `json_extract(e.value, '` + f.path + `')`
+	case f.array != "":
+		from = `jobs, json_each(jobs.data, '` + f.array + `') AS e`
+		value = `e.value`
+	default:
+		from = `jobs`
+		value = `json_extract(jobs.data, '` + f.path + `')`
+	}
+
+	where, args := sqliteWhere(q)
+	if where == "" {
+		where = " WHERE "
+	} else {
+		where += " AND "
+	}
+	where += value + ` IS NOT NULL AND ` + value + ` != ''` // skip postings where the field is unknown
+
+	query := `SELECT ` + value + ` AS v, COUNT(*) AS c FROM ` + from + where + ` GROUP BY v ORDER BY c DESC, v ASC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []CountResult
+	for rows.Next() {
+		var r CountResult
+		if err := rows.Scan(&r.Value, &r.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteJob(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM jobs WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// sqliteClosedAt is when a posting was closed, as a Julian day number that is
+// NULL while it is open. The jobs_closed_at index covers it.
+const sqliteClosedAt = `julianday(json_extract(jobs.data, '$.closed_at'))`
+
+// sqliteStatus is JobStatus in SQL.
+const sqliteStatus = `COALESCE(NULLIF(json_extract(jobs.data, '$.status'), ''), '` + StatusOpen + `')`
+
+// sqliteWhere translates a JobQuery into a WHERE clause over the jobs table.
+func sqliteWhere(q JobQuery) (string, []any) {
+	var conds []string
+	var args []any
+	if q.Role != "" {
+		conds = append(conds, `EXISTS (SELECT 1 FROM json_each(jobs.data, '$.roles') r WHERE r.value = ?)`)
+		args = append(args, RoleSlug(q.Role))
+	}
+	if terms := searchTerms(q.Text); len(terms) > 0 {
+		conds = append(conds, `jobs.rowid IN (SELECT rowid FROM jobs_fts WHERE jobs_fts MATCH ?)`)
+		args = append(args, ftsQuery(terms))
+	}
+	if q.CompanyID != "" {
+		conds = append(conds, `jobs.company_id = ?`)
+		args = append(args, q.CompanyID)
+	}
+	if q.Source != "" {
+		conds = append(conds, `jobs.source = ?`)
+		args = append(args, q.Source)
+	}
+	if len(q.URLs) > 0 {
+		in := strings.TrimSuffix(strings.Repeat("?,", len(q.URLs)), ",")
+		conds = append(conds, `(json_extract(jobs.data, '$.url') IN (`+in+`) OR EXISTS (
+			SELECT 1 FROM json_each(jobs.data, '$.sources') AS s WHERE json_extract(s.value, '$.url') IN (`+in+`)))`)
+		for range 2 {
+			for _, u := range q.URLs {
+				args = append(args, u)
+			}
+		}
+	}
+	if location := strings.ToLower(strings.TrimSpace(q.Location)); location != "" {
+		pattern := likePattern(location)
+		conds = append(conds, `(json_extract(jobs.data, '$.location') LIKE ? ESCAPE '\' OR EXISTS (
+			SELECT 1 FROM json_each(jobs.data, '$.locations') AS l
+			WHERE json_extract(l.value, '$.name') LIKE ? ESCAPE '\'
+				OR json_extract(l.value, '$.country') LIKE ? ESCAPE '\'
+				OR json_extract(l.value, '$.region') LIKE ? ESCAPE '\'
+				OR lower(json_extract(l.value, '$.country_code')) = ?))`)
+		args = append(args, pattern, pattern, pattern, pattern, location)
+	}
+	for _, skill := range NormalizeSkills(q.SkillsAll) {
+		conds = append(conds, `EXISTS (SELECT 1 FROM json_each(jobs.data, '$.skills') WHERE value = ?)`)
+		args = append(args, skill)
+	}
+	if anyOf := NormalizeSkills(q.SkillsAny); len(anyOf) > 0 {
+		conds = append(conds, `EXISTS (SELECT 1 FROM json_each(jobs.data, '$.skills') WHERE value IN (?`+
+			strings.Repeat(`, ?`, len(anyOf)-1)+`))`)
+		for _, skill := range anyOf {
+			args = append(args, skill)
+		}
+	}
+	if q.SalaryMin > 0 || q.SalaryMax > 0 || q.SalaryKnown {
+		// salary_max of 0 is an open-ended range
+		salaryMax := `COALESCE(json_extract(jobs.data, '$.salary_max'), 0)`
+		conds = append(conds, `(jobs.salary_min > 0 OR `+salaryMax+` > 0)`)
+		if q.SalaryMin > 0 {
+			conds = append(conds, `(`+salaryMax+` = 0 OR `+salaryMax+` >= ?)`)
+			args = append(args, q.SalaryMin)
+		}
+		if q.SalaryMax > 0 {
+			conds = append(conds, `jobs.salary_min <= ?`)
+			args = append(args, q.SalaryMax)
+		}
+	}
+	if q.Experience != nil {
+		expMax := `COALESCE(json_extract(jobs.data, '$.experience_range.max'), 0)`
+		conds = append(conds, `json_type(jobs.data, '$.experience_range') = 'object'
+			AND COALESCE(json_extract(jobs.data, '$.experience_range.min'), 0) <= ?
+			AND (`+expMax+` = 0 OR `+expMax+` >= ?)`)
+		args = append(args, *q.Experience, *q.Experience)
+	}
+	if !q.PostedSince.IsZero() {
+		conds = append(conds, `jobs.posted_on >= ?`)
+		args = append(args, q.PostedSince.UnixMilli())
+	}
+	if !q.PostedUntil.IsZero() {
+		conds = append(conds, `jobs.posted_on < ?`)
+		args = append(args, q.PostedUntil.UnixMilli())
+	}
+	if q.Status != "" {
+		conds = append(conds, sqliteStatus+` = ?`)
+		args = append(args, q.Status)
+	}
+	if q.OpenOnly {
+		conds = append(conds, sqliteStatus+` != ? AND jobs.expire_at > ?`)
+		args = append(args, StatusClosed, time.Now().UnixMilli())
+	}
+	if !q.OpenAt.IsZero() {
+		conds = append(conds, `(json_extract(jobs.data, '$.closed_at') IS NULL OR `+sqliteClosedAt+` >= julianday(?))`)
+		args = append(args, q.OpenAt.UTC().Format(time.RFC3339Nano))
+	}
+	if !q.ClosedSince.IsZero() {
+		conds = append(conds, sqliteClosedAt+` >= julianday(?)`)
+		args = append(args, q.ClosedSince.UTC().Format(time.RFC3339Nano))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// sqliteAfter is the keyset condition that continues q after its cursor.
+func sqliteAfter(q JobQuery) (string, []any) {
+	c := q.After
+	if c == nil {
+		return "", nil
+	}
+	switch q.sortOrder() {
+	case SortOldest:
+		at := c.PostedOn.UnixMilli()
+		return `(jobs.posted_on > ? OR (jobs.posted_on = ? AND jobs.id > ?))`, []any{at, at, c.ID}
+	case SortSalary:
+		return `(jobs.salary_min < ? OR (jobs.salary_min = ? AND jobs.id < ?))`, []any{c.Salary, c.Salary, c.ID}
+	case SortRelevance:
+		return "", nil // paged by offset
+	default:
+		at := c.PostedOn.UnixMilli()
+		return `(jobs.posted_on < ? OR (jobs.posted_on = ? AND jobs.id < ?))`, []any{at, at, c.ID}
+	}
+}
+
+// ftsQuery requires every term, each quoted so FTS5 reads it literally.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// likePattern is a case-insensitive LIKE pattern for "contains s".
+func likePattern(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s) + "%"
+}
+
+// jobsAtURLs loads the postings crawled from any of urls.
+func jobsAtURLs(ctx context.Context, db sqlExecer, urls []string) ([]JobPosting, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	in := strings.TrimSuffix(strings.Repeat("?,", len(urls)), ",")
+	args := make([]any, 0, 2*len(urls))
+	for _, u := range urls {
+		args = append(args, u)
+	}
+	args = append(args, args...)
+
+	return queryJobRows(ctx, db, `
+		SELECT data FROM jobs WHERE json_extract(data, '$.url') IN (`+in+`)
+		UNION
+		SELECT j.data FROM jobs j, json_each(j.data, '$.sources') s WHERE json_extract(s.value, '$.url') IN (`+in+`)`,
+		args...)
+}
+
+// updateJobs applies fn to the postings returned by load inside one
+// transaction, saving those for which it returns true.
+func (s *SQLiteStore) updateJobs(ctx context.Context, load func(tx *sql.Tx) ([]JobPosting, error), fn func(job *JobPosting) bool) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	jobs, err := load(tx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, job := range jobs {
+		if !fn(&job) {
+			continue
+		}
+		if err := updateJobRow(ctx, tx, job); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, tx.Commit()
+}
+
+func (s *SQLiteStore) MarkSeen(ctx context.Context, urls []string, at time.Time) (int, error) {
+	return s.updateJobs(ctx,
+		func(tx *sql.Tx) ([]JobPosting, error) { return jobsAtURLs(ctx, tx, urls) },
+		func(job *JobPosting) bool {
+			markSeen(job, at)
+			return true
+		})
+}
+
+func (s *SQLiteStore) CloseJobs(ctx context.Context, urls []string, reason string, at time.Time) (int, error) {
+	return s.updateJobs(ctx,
+		func(tx *sql.Tx) ([]JobPosting, error) { return jobsAtURLs(ctx, tx, urls) },
+		func(job *JobPosting) bool {
+			if JobStatus(*job) == StatusClosed {
+				return false
+			}
+			closeJob(job, reason, at)
+			return true
+		})
+}
+
+func (s *SQLiteStore) SweepLifecycle(ctx context.Context, q JobQuery, policy LifecyclePolicy, now time.Time) (LifecycleSweep, error) {
+	var sweep LifecycleSweep
+	_, err := s.updateJobs(ctx,
+		func(tx *sql.Tx) ([]JobPosting, error) {
+			where, args := sqliteWhere(q)
+			if where == "" {
+				where = " WHERE "
+			} else {
+				where += " AND "
+			}
+			return queryJobRows(ctx, tx, `SELECT data FROM jobs`+where+sqliteStatus+` != ?`, append(args, StatusClosed)...)
+		},
+		func(job *JobPosting) bool {
+			if !sweepJob(job, policy, now) {
+				return false
+			}
+			if job.Status == StatusClosed {
+				sweep.Closed++
+				sweep.ClosedIDs = append(sweep.ClosedIDs, job.ID)
+			} else {
+				sweep.NotSeen++
+			}
+			return true
+		})
+	if err != nil {
+		return LifecycleSweep{}, err
+	}
+	return sweep, nil
+}
+
+func insertRevision(ctx context.Context, tx *sql.Tx, rev Revision) error {
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO job_history (job_id, changed_at, data) VALUES (?, ?, ?)`,
+		rev.JobID, rev.ChangedAt.UnixMilli(), string(data))
+	return err
+}
+
+func (s *SQLiteStore) JobHistory(ctx context.Context, jobID string) ([]Revision, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, data FROM job_history WHERE job_id = ? ORDER BY changed_at, id`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var rev Revision
+		if err := json.Unmarshal([]byte(data), &rev); err != nil {
+			return nil, err
+		}
+		rev.ID = strconv.FormatInt(id, 10)
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *SQLiteStore) CreateSearch(ctx context.Context, search SavedSearch) (SavedSearch, error) {
+	search.ID = bson.NewObjectID().Hex()
+	search.CreatedAt = time.Now()
+	data, err := json.Marshal(search)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO saved_searches (id, created_at, data) VALUES (?, ?, ?)`,
+		search.ID, search.CreatedAt.UnixMilli(), string(data))
+	return search, err
+}
+
+func (s *SQLiteStore) ListSearches(ctx context.Context) ([]SavedSearch, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM saved_searches ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var search SavedSearch
+		if err := json.Unmarshal([]byte(data), &search); err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteSearch(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM saved_searches WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSearchNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) CreateWebhook(ctx context.Context, hook Webhook) (Webhook, error) {
+	hook.ID = bson.NewObjectID().Hex()
+	hook.CreatedAt = time.Now()
+	data, err := json.Marshal(hook)
+	if err != nil {
+		return Webhook{}, err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO webhooks (id, created_at, data) VALUES (?, ?, ?)`,
+		hook.ID, hook.CreatedAt.UnixMilli(), string(data))
+	return hook, err
+}
+
+func (s *SQLiteStore) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM webhooks WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var hook Webhook
+	if err := json.Unmarshal([]byte(data), &hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (s *SQLiteStore) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var hook Webhook
+		if err := json.Unmarshal([]byte(data), &hook); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteWebhook(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrWebhookNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) RecordDelivery(ctx context.Context, d Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, delivered_at, data) VALUES (?, ?, ?)`,
+		d.WebhookID, d.DeliveredAt.UnixMilli(), string(data))
+	return err
+}
+
+func (s *SQLiteStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]Delivery, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, data FROM webhook_deliveries
+		WHERE webhook_id = ? AND delivered_at > ?
+		ORDER BY delivered_at DESC, id DESC LIMIT ?`,
+		webhookID, time.Now().Add(-DeliveryRetention).UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var d Delivery
+		if err := json.Unmarshal([]byte(data), &d); err != nil {
+			return nil, err
+		}
+		d.ID = strconv.FormatInt(id, 10)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *SQLiteStore) CreateSchedule(ctx context.Context, schedule Schedule) (Schedule, error) {
+	schedule.ID = bson.NewObjectID().Hex()
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = schedule.CreatedAt
+	schedule.LastRun = nil
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return Schedule{}, err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO schedules (id, created_at, data) VALUES (?, ?, ?)`,
+		schedule.ID, schedule.CreatedAt.UnixMilli(), string(data))
+	return schedule, err
+}
+
+func (s *SQLiteStore) GetSchedule(ctx context.Context, id string) (*Schedule, error) {
+	return getSchedule(ctx, s.db, id)
+}
+
+// getSchedule reads a schedule through db or a transaction.
+func getSchedule(ctx context.Context, db interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}, id string) (*Schedule, error) {
+	var data string
+	err := db.QueryRowContext(ctx, `SELECT data FROM schedules WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedule Schedule
+	if err := json.Unmarshal([]byte(data), &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (s *SQLiteStore) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM schedules ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []Schedule{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var schedule Schedule
+		if err := json.Unmarshal([]byte(data), &schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func (s *SQLiteStore) UpdateSchedule(ctx context.Context, schedule Schedule) (Schedule, error) {
+	err := s.modifySchedule(ctx, schedule.ID, func(existing *Schedule) {
+		schedule.CreatedAt = existing.CreatedAt
+		schedule.LastRun = existing.LastRun
+		schedule.UpdatedAt = time.Now()
+		*existing = schedule
+	})
+	return schedule, err
+}
+
+func (s *SQLiteStore) DeleteSchedule(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM schedules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) RecordScheduleRun(ctx context.Context, id string, run ScheduleRun) error {
+	return s.modifySchedule(ctx, id, func(schedule *Schedule) {
+		schedule.LastRun = &run
+	})
+}
+
+// modifySchedule applies fn to a stored schedule in a transaction, so that
+// edits and recorded runs don't overwrite each other.
+func (s *SQLiteStore) modifySchedule(ctx context.Context, id string, fn func(*Schedule)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	schedule, err := getSchedule(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	fn(schedule)
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE schedules SET data = ? WHERE id = ?`, string(data), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) EnqueueCrawl(ctx context.Context, task CrawlTask) (CrawlTask, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return CrawlTask{}, false, err
+	}
+	defer tx.Rollback()
+
+	task = newCrawlTask(task, time.Now())
+	findActive := func() ([]CrawlTask, error) {
+		return queryCrawlRows(ctx, tx, `SELECT data FROM crawl_tasks
+			WHERE role_key = ? AND status IN (?, ?) LIMIT 1`, task.RoleKey, TaskQueued, TaskRunning)
+	}
+	active, err := findActive()
+	if err != nil {
+		return CrawlTask{}, false, err
+	}
+	if len(active) > 0 {
+		return active[0], false, nil
+	}
+
+	task.ID = bson.NewObjectID().Hex()
+	data, err := json.Marshal(task)
+	if err != nil {
+		return CrawlTask{}, false, err
+	}
+	// The unique index on a role's active task turns away another process
+	// that enqueued the role since
+	res, err := tx.ExecContext(ctx, `INSERT INTO crawl_tasks (id, role_key, status, enqueued_at, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`, task.ID, task.RoleKey, task.Status, task.EnqueuedAt.UnixMilli(), string(data))
+	if err != nil {
+		return CrawlTask{}, false, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return CrawlTask{}, false, err
+	} else if n == 0 {
+		if active, err = findActive(); err != nil {
+			return CrawlTask{}, false, err
+		}
+		if len(active) == 0 {
+			return CrawlTask{}, false, fmt.Errorf("crawl task of %s neither inserted nor found", task.RoleKey)
+		}
+		return active[0], false, nil
+	}
+	return task, true, tx.Commit()
+}
+
+func (s *SQLiteStore) LeaseCrawl(ctx context.Context, worker string, lease time.Duration) (*CrawlTask, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	ready, err := queryCrawlRows(ctx, tx, `SELECT data FROM crawl_tasks
+		WHERE status = ? OR (status = ? AND lease_until < ?)
+		ORDER BY enqueued_at, id`, TaskQueued, TaskRunning, now.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	var leased *CrawlTask
+	for i := range ready {
+		task := &ready[i]
+		ok, expired := leasable(*task, now)
+		switch {
+		case expired:
+			finishTask(task, expiredLeaseError, now)
+		case ok:
+			leaseTask(task, worker, lease, now)
+			leased = task
+		default:
+			continue
+		}
+		if err := saveCrawlRow(ctx, tx, *task); err != nil {
+			return nil, err
+		}
+		if leased != nil {
+			break
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if leased == nil {
+		return nil, ErrQueueEmpty
+	}
+	return leased, nil
+}
+
+func (s *SQLiteStore) RenewLease(ctx context.Context, id, worker string, lease time.Duration) error {
+	return s.modifyLeasedCrawl(ctx, id, worker, func(task *CrawlTask) {
+		until := time.Now().Add(lease)
+		task.LeaseUntil = &until
+	})
+}
+
+func (s *SQLiteStore) FinishCrawl(ctx context.Context, id, worker, errMsg string) error {
+	return s.modifyLeasedCrawl(ctx, id, worker, func(task *CrawlTask) {
+		finishTask(task, errMsg, time.Now())
+	})
+}
+
+// modifyLeasedCrawl applies fn to a task in a transaction, if worker still
+// holds its lease.
+func (s *SQLiteStore) modifyLeasedCrawl(ctx context.Context, id, worker string, fn func(*CrawlTask)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	task, err := getCrawl(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if task.Status != TaskRunning || task.Worker != worker {
+		return ErrLeaseLost
+	}
+	fn(task)
+	if err := saveCrawlRow(ctx, tx, *task); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) GetCrawl(ctx context.Context, id string) (*CrawlTask, error) {
+	return getCrawl(ctx, s.db, id)
+}
+
+func getCrawl(ctx context.Context, db sqlExecer, id string) (*CrawlTask, error) {
+	tasks, err := queryCrawlRows(ctx, db, `SELECT data FROM crawl_tasks WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, ErrCrawlTaskNotFound
+	}
+	return &tasks[0], nil
+}
+
+func (s *SQLiteStore) ListCrawls(ctx context.Context, status string, limit int) ([]CrawlTask, error) {
+	query := `SELECT data FROM crawl_tasks WHERE (? = '' OR status = ?) ORDER BY enqueued_at DESC, id DESC`
+	args := []any{status, status}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	return queryCrawlRows(ctx, s.db, query, args...)
+}
+
+func queryCrawlRows(ctx context.Context, db sqlExecer, query string, args ...any) ([]CrawlTask, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []CrawlTask{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var task CrawlTask
+		if err := json.Unmarshal([]byte(data), &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// saveCrawlRow writes task back along with the columns the queue queries on.
+func saveCrawlRow(ctx context.Context, db sqlExecer, task CrawlTask) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	var leaseUntil, finishedAt any
+	if task.LeaseUntil != nil {
+		leaseUntil = task.LeaseUntil.UnixMilli()
+	}
+	if task.FinishedAt != nil {
+		finishedAt = task.FinishedAt.UnixMilli()
+	}
+	_, err = db.ExecContext(ctx, `UPDATE crawl_tasks SET status = ?, lease_until = ?, finished_at = ?, data = ? WHERE id = ?`,
+		task.Status, leaseUntil, finishedAt, string(data), task.ID)
+	return err
+}
+
+func (s *SQLiteStore) ListRoles(ctx context.Context) ([]Role, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM roles ORDER BY slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var role Role
+		if err := json.Unmarshal([]byte(data), &role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (s *SQLiteStore) SaveRole(ctx context.Context, role Role) error {
+	data, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO roles (slug, data) VALUES (?, ?)
+		ON CONFLICT (slug) DO UPDATE SET data = excluded.data`, role.Slug, string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetTrendResult(ctx context.Context, key string) (*TrendResult, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM trend_results WHERE key = ? AND expire_at > ?`,
+		key, time.Now().UnixMilli()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTrendResultNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result TrendResult
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *SQLiteStore) SaveTrendResult(ctx context.Context, result TrendResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO trend_results (key, expire_at, data) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET expire_at = excluded.expire_at, data = excluded.data`,
+		result.Key, result.ExpireAt.UnixMilli(), string(data))
+	return err
+}
+
+func (s *SQLiteStore) ClearTrendResults(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM trend_results`)
+	return err
+}
+
+func (s *SQLiteStore) GetCompany(ctx context.Context, name string) (*Company, error) {
+	company, err := findCompanySQLite(ctx, s.db, NormalizeCompanyKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if company == nil {
+		return nil, ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+func findCompanySQLite(ctx context.Context, db sqlExecer, key string) (*Company, error) {
+	var data string
+	err := db.QueryRowContext(ctx, `SELECT data FROM companies WHERE id = ?`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var company Company
+	if err := json.Unmarshal([]byte(data), &company); err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// resolveCompanySQLite finds or creates the canonical company for a raw name and
+// records the spelling as an alias.
+func resolveCompanySQLite(ctx context.Context, tx *sql.Tx, name, applyURL string, now time.Time) (*Company, error) {
+	existing, err := findCompanySQLite(ctx, tx, NormalizeCompanyKey(name))
+	if err != nil {
+		return nil, err
+	}
+
+	companies := make(map[string]*Company)
+	if existing != nil {
+		companies[existing.ID] = existing
+	}
+	company, err := resolveCompanyIn(companies, name, applyURL, now)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(company)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO companies (id, data) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data`, company.ID, string(data))
+	if err != nil {
+		return nil, err
+	}
+	return company, nil
+}
@@ -0,0 +1,327 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrJobNotFound is returned when no posting has the requested ID.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStore persists job postings. UpsertJob applies the same normalization and
+// dedupe rules in every implementation: company canonicalization, exact hash
+// matches and near-duplicate merging.
+type JobStore interface {
+	UpsertJob(ctx context.Context, job JobPosting) (UpsertResult, error)
+	GetJob(ctx context.Context, id string) (*JobPosting, error)
+	QueryJobs(ctx context.Context, q JobQuery) ([]JobPosting, error)
+	CountJobs(ctx context.Context, q JobQuery) (int, error)
+	// CountBy counts the values of field (see CountableFields) across matching
+	// postings, most frequent first. A limit of 0 returns every value.
+	CountBy(ctx context.Context, field string, q JobQuery, limit int) ([]CountResult, error)
+	DeleteJob(ctx context.Context, id string) error
+}
+
+// CompanyStore looks up canonical employer records.
+type CompanyStore interface {
+	GetCompany(ctx context.Context, name string) (*Company, error)
+}
+
+// Store is everything the crawler, trend worker and API need from a backend.
+type Store interface {
+	JobStore
+	BatchStore
+	CompanyStore
+	HistoryStore
+	LifecycleStore
+	SearchStore
+	WebhookStore
+	ScheduleStore
+	QueueStore
+	RoleStore
+	TrendResultStore
+	Close(ctx context.Context) error
+}
+
+type CountResult struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type UpsertAction string
+
+const (
+	UpsertInserted  UpsertAction = "inserted"  // new posting
+	UpsertUpdated   UpsertAction = "updated"   // material fields changed, posting replaced and revision recorded
+	UpsertUnchanged UpsertAction = "unchanged" // already stored, only lastUpdated touched
+	UpsertMerged    UpsertAction = "merged"    // near-duplicate attached to an existing posting
+)
+
+type UpsertResult struct {
+	ID     string       `json:"id"`
+	Action UpsertAction `json:"action"`
+}
+
+// JobQuery filters postings. Zero values mean "no filter".
+type JobQuery struct {
+	Role        string // slug or name of a taxonomy role the postings were classified into
+	Text        string // words that must all appear in the title or description
+	CompanyID   string
+	Source      string
+	Location    string    // matched against Location and the structured Locations
+	URLs        []string  // postings crawled from any of these URLs
+	SkillsAll   []string  // postings requiring every one of these skills
+	SkillsAny   []string  // postings requiring at least one of these skills
+	SalaryMin   int       // postings whose salary range reaches this annual amount
+	SalaryMax   int       // postings whose salary range starts at or below this amount
+	SalaryKnown bool      // only postings with a salary range, implied by SalaryMin and SalaryMax
+	Experience  *int      // years of experience the posting's range must accept
+	PostedSince time.Time // inclusive
+	PostedUntil time.Time // exclusive
+	Status      string    // lifecycle status, see JobStatus
+	OpenOnly    bool      // only postings that are not closed or expired
+	OpenAt      time.Time // only postings not closed before this time
+	ClosedSince time.Time // only postings closed at or after this time
+
+	Sort  string  // one of the Sort constants, SortNewest by default
+	After *Cursor // continue after the last posting of a previous page
+	Limit int
+	Skip  int
+}
+
+// Matches reports whether job passes every filter of q. Stores without a query
+// language of their own use it directly.
+func (q JobQuery) Matches(job JobPosting, now time.Time) bool {
+	if q.Role != "" && !slices.Contains(job.Roles, RoleSlug(q.Role)) {
+		return false
+	}
+	if terms := searchTerms(q.Text); len(terms) > 0 && !matchesTerms(job, terms) {
+		return false
+	}
+	if q.CompanyID != "" && job.CompanyID != q.CompanyID {
+		return false
+	}
+	if q.Source != "" && job.Source != q.Source {
+		return false
+	}
+	if len(q.URLs) > 0 && !hasURL(job, q.URLs) {
+		return false
+	}
+	if q.Location != "" && !matchesLocation(job, q.Location) {
+		return false
+	}
+	for _, skill := range NormalizeSkills(q.SkillsAll) {
+		if !containsString(job.Skills, skill) {
+			return false
+		}
+	}
+	if anyOf := NormalizeSkills(q.SkillsAny); len(anyOf) > 0 && !containsAny(job.Skills, anyOf) {
+		return false
+	}
+	if !q.matchesSalary(job) || !q.matchesExperience(job) {
+		return false
+	}
+	if !q.PostedSince.IsZero() && job.PostedOn.Before(q.PostedSince) {
+		return false
+	}
+	if !q.PostedUntil.IsZero() && !job.PostedOn.Before(q.PostedUntil) {
+		return false
+	}
+	if q.Status != "" && JobStatus(job) != q.Status {
+		return false
+	}
+	if q.OpenOnly && (JobStatus(job) == StatusClosed || !job.ExpireAt.After(now)) {
+		return false
+	}
+	if !q.OpenAt.IsZero() && job.ClosedAt != nil && job.ClosedAt.Before(q.OpenAt) {
+		return false
+	}
+	if !q.ClosedSince.IsZero() && (job.ClosedAt == nil || job.ClosedAt.Before(q.ClosedSince)) {
+		return false
+	}
+	return true
+}
+
+// CountableFields are the fields CountBy accepts, named by their stored path.
+var CountableFields = map[string]func(JobPosting) []string{
+	"skills":         func(j JobPosting) []string { return j.Skills },
+	"roles":          func(j JobPosting) []string { return j.Roles },
+	"location":       func(j JobPosting) []string { return []string{j.Location} },
+	"company":        func(j JobPosting) []string { return []string{j.Company} },
+	"companyId":      func(j JobPosting) []string { return []string{j.CompanyID} },
+	"source":         func(j JobPosting) []string { return []string{j.Source} },
+	"experience":     func(j JobPosting) []string { return []string{j.Experience} },
+	"seniority":      func(j JobPosting) []string { return []string{j.Seniority} },
+	"employmentType": func(j JobPosting) []string { return []string{j.EmploymentType} },
+	"remotePolicy":   func(j JobPosting) []string { return []string{j.RemotePolicy} },
+	"timezones":      func(j JobPosting) []string { return j.Timezones },
+	"salaryMin":      func(j JobPosting) []string { return amountValues(j.SalaryMin) },
+	"salaryMax":      func(j JobPosting) []string { return amountValues(j.SalaryMax) },
+	"salaryCurrency": func(j JobPosting) []string { return []string{j.SalaryCurrency} },
+	"locations.name": func(j JobPosting) []string {
+		return locationValues(j, func(e LocationEntry) string { return e.Name })
+	},
+	"locations.country": func(j JobPosting) []string {
+		return locationValues(j, func(e LocationEntry) string { return e.Country })
+	},
+	"locations.countryCode": func(j JobPosting) []string {
+		return locationValues(j, func(e LocationEntry) string { return e.CountryCode })
+	},
+	"locations.region": func(j JobPosting) []string {
+		return locationValues(j, func(e LocationEntry) string { return e.Region })
+	},
+}
+
+func locationValues(j JobPosting, get func(LocationEntry) string) []string {
+	values := make([]string, 0, len(j.Locations))
+	for _, e := range j.Locations {
+		values = append(values, get(e))
+	}
+	return values
+}
+
+// amountValues counts a salary amount in decimal, leaving out 0, which is
+// unknown.
+func amountValues(amount int) []string {
+	if amount == 0 {
+		return nil
+	}
+	return []string{strconv.Itoa(amount)}
+}
+
+func checkCountableField(field string) error {
+	if _, ok := CountableFields[field]; !ok {
+		return fmt.Errorf("field %q cannot be counted", field)
+	}
+	return nil
+}
+
+// countValues tallies field over jobs the way CountBy does: empty values are
+// skipped and results are sorted by count, then value.
+func countValues(jobs []JobPosting, field string, limit int) []CountResult {
+	values := CountableFields[field]
+	counts := make(map[string]int)
+	for _, job := range jobs {
+		for _, v := range values(job) {
+			if v != "" {
+				counts[v]++
+			}
+		}
+	}
+
+	results := make([]CountResult, 0, len(counts))
+	for v, c := range counts {
+		results = append(results, CountResult{Value: v, Count: c})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count != results[j].Count {
+			return results[i].Count > results[j].Count
+		}
+		return results[i].Value < results[j].Value
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// prepareJob canonicalizes the company and computes every derived field that
+// dedupe relies on. It is the first step of UpsertJob in every store.
+func prepareJob(job *JobPosting, company *Company, now time.Time) {
+	job.Company = company.Name
+	job.CompanyID = company.ID
+	job.Skills = NormalizeSkills(job.Skills)
+	job.Roles = ClassifyRoles(job.Title, job.Skills)
+	job.SalaryMin, job.SalaryMax, job.SalaryCurrency = ParseSalary(job.Salary)
+
+	setDedupeKeys(job)
+	job.DescriptionHash = GenerateHash(job.Description)
+	job.SimHash = int64(SimHash(job.Description))
+	job.LastUpdated = now
+
+	// Crawled just now, so the posting is open whatever its previous state
+	markSeen(job, now)
+}
+
+// setDedupeKeys derives the keys that match a re-crawl or a near-duplicate of
+// job from its title, company key, location and posted date.
+func setDedupeKeys(job *JobPosting) {
+	// Hash on the company key so that every spelling of the company dedupes together
+	job.Hash = GenerateHash(job.Title, job.CompanyID, job.Location, job.PostedOn.Format("2006-01-02"))
+	job.ClusterKey = ClusterKey(job.CompanyID, job.Title)
+}
+
+// absorbDuplicate folds dup into canonical when both turn out to be the same
+// posting: canonical keeps its fields and gains dup's alias hashes and sources.
+func absorbDuplicate(canonical *JobPosting, dup JobPosting) {
+	for _, hash := range dup.AliasHashes {
+		if hash != canonical.Hash && !containsString(canonical.AliasHashes, hash) {
+			canonical.AliasHashes = append(canonical.AliasHashes, hash)
+		}
+	}
+	for _, link := range dup.Sources {
+		if !hasSource(canonical.Sources, link.URL) {
+			canonical.Sources = append(canonical.Sources, link)
+		}
+	}
+}
+
+// replacementFor builds the document that replaces existing when the posting's
+// description changed, keeping what belongs to the stored posting.
+func replacementFor(existing, job JobPosting, now time.Time) JobPosting {
+	job.ID = existing.ID
+	job.CreatedAt = existing.CreatedAt
+	job.AliasHashes = existing.AliasHashes
+	job.Sources = existing.Sources
+	if !hasSource(job.Sources, job.URL) {
+		job.Sources = append(job.Sources, sourceLink(job, now))
+	}
+	return job
+}
+
+// newJobDocument prepares a posting that is stored for the first time.
+func newJobDocument(job JobPosting, now time.Time) JobPosting {
+	job.CreatedAt = now
+	job.Sources = []SourceLink{sourceLink(job, now)}
+	return job
+}
+
+// resolveCompanyIn is the CompanyStore-independent part of company resolution:
+// it finds name's canonical record among companies or returns a new one.
+func resolveCompanyIn(companies map[string]*Company, name, applyURL string, now time.Time) (*Company, error) {
+	name = CleanCompanyName(name)
+	key := NormalizeCompanyKey(name)
+	if key == "" {
+		return nil, errors.New("empty company name")
+	}
+
+	company, ok := companies[key]
+	if !ok {
+		company = &Company{
+			ID:        key,
+			Name:      name,
+			Domain:    CompanyDomain(applyURL),
+			FirstSeen: now,
+		}
+		companies[key] = company
+	}
+	if !containsString(company.Aliases, name) {
+		company.Aliases = append(company.Aliases, name)
+	}
+	company.LastSeen = now
+	return company, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,694 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testJob(title, company string, posted time.Time) JobPosting {
+	return JobPosting{
+		Title:       title,
+		Company:     company,
+		Location:    "Anywhere in the World",
+		PostedOn:    posted,
+		Description: "Run Kubernetes on AWS with Terraform and own our CI/CD pipelines end to end.",
+		URL:         "https://weworkremotely.com/listings/" + NormalizeCompanyKey(company) + "-" + NormalizeTitle(title),
+		Source:      "weworkremotely.com",
+		Skills:      []string{"aws", "kubernetes", "terraform"},
+	}
+}
+
+// testStores runs fn against every Store that needs no external service.
+func testStores(t *testing.T, fn func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "jobs.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close(context.Background())
+		fn(t, store)
+	})
+}
+
+func TestStoreUpsert(t *testing.T) {
+	testStores(t, testStoreUpsert)
+}
+
+func testStoreUpsert(t *testing.T, store Store) {
+	ctx := context.Background()
+	posted := time.Now().AddDate(0, 0, -1)
+
+	first, err := store.UpsertJob(ctx, testJob("devops engineer", "Proxify AB", posted))
+	if err != nil || first.Action != UpsertInserted {
+		t.Fatalf("expected insert, got %+v (%v)", first, err)
+	}
+
+	// Same posting under another spelling of the company
+	again, err := store.UpsertJob(ctx, testJob("devops engineer", "proxify", posted))
+	if err != nil || again.Action != UpsertUnchanged || again.ID != first.ID {
+		t.Fatalf("expected unchanged %s, got %+v (%v)", first.ID, again, err)
+	}
+
+	changed := testJob("devops engineer", "Proxify", posted)
+	changed.Description += " Salary raised."
+	updated, err := store.UpsertJob(ctx, changed)
+	if err != nil || updated.Action != UpsertUpdated || updated.ID != first.ID {
+		t.Fatalf("expected update of %s, got %+v (%v)", first.ID, updated, err)
+	}
+
+	// Re-posted a day later on another board
+	repost := testJob("DevOps Engineer (Remote)", "Proxify AB", posted.AddDate(0, 0, 1))
+	repost.URL = "https://example-board.com/jobs/42"
+	repost.Source = "example-board.com"
+	repost.Description = changed.Description
+	merged, err := store.UpsertJob(ctx, repost)
+	if err != nil || merged.Action != UpsertMerged || merged.ID != first.ID {
+		t.Fatalf("expected merge into %s, got %+v (%v)", first.ID, merged, err)
+	}
+
+	job, err := store.GetJob(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Company != "Proxify AB" || job.CompanyID != "proxify" {
+		t.Errorf("company not canonicalized: %q / %q", job.Company, job.CompanyID)
+	}
+	if len(job.Sources) != 2 || len(job.AliasHashes) != 1 {
+		t.Errorf("expected 2 sources and 1 alias hash, got %+v / %v", job.Sources, job.AliasHashes)
+	}
+
+	if n, _ := store.CountJobs(ctx, JobQuery{}); n != 1 {
+		t.Errorf("expected 1 clustered posting, got %d", n)
+	}
+
+	company, err := store.GetCompany(ctx, "PROXIFY, Inc.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(company.Aliases) != 3 {
+		t.Errorf("expected 3 aliases, got %v", company.Aliases)
+	}
+
+	if err := store.DeleteJob(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetJob(ctx, first.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound after delete, got %v", err)
+	}
+}
+
+func TestStoreQueryAndCount(t *testing.T) {
+	testStores(t, testStoreQueryAndCount)
+}
+
+func testStoreQueryAndCount(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	jobs := []JobPosting{
+		testJob("backend engineer", "Acme", now.AddDate(0, 0, -1)),
+		testJob("frontend engineer", "Acme", now.AddDate(0, 0, -3)),
+		testJob("data engineer", "Globex", now.AddDate(0, 0, -20)),
+	}
+	jobs[1].Skills = []string{"react", "typescript", "aws"}
+	jobs[2].Skills = []string{"python", "airflow"}
+	jobs[2].Location = "Berlin, Germany"
+	for _, j := range jobs {
+		NormalizeLocation(&j)
+		if _, err := store.UpsertJob(ctx, j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recent, err := store.QueryJobs(ctx, JobQuery{PostedSince: now.AddDate(0, 0, -7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].Title != "backend engineer" {
+		t.Errorf("expected 2 recent postings newest first, got %+v", recent)
+	}
+
+	acme, _ := store.QueryJobs(ctx, JobQuery{CompanyID: "acme", Limit: 1})
+	if len(acme) != 1 {
+		t.Errorf("expected limit to apply, got %d postings", len(acme))
+	}
+
+	skills, err := store.CountBy(ctx, "skills", JobQuery{Source: "weworkremotely.com"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skills) != 2 || skills[0] != (CountResult{Value: "aws", Count: 2}) {
+		t.Errorf("unexpected skill counts: %+v", skills)
+	}
+
+	// Roles match the taxonomy, by slug or name, not the title
+	for role, want := range map[string]int{"frontend": 1, "Data Engineer": 1, "back.*": 0, "wizard": 0} {
+		if n, err := store.CountJobs(ctx, JobQuery{Role: role}); err != nil || n != want {
+			t.Errorf("role %q: expected %d postings, got %d (%v)", role, want, n, err)
+		}
+	}
+	roles, _ := store.CountBy(ctx, "roles", JobQuery{}, 0)
+	if len(roles) != 3 {
+		t.Errorf("unexpected role counts: %+v", roles)
+	}
+
+	countries, _ := store.CountBy(ctx, "locations.country", JobQuery{}, 0)
+	if len(countries) != 1 || countries[0].Value != "Germany" {
+		t.Errorf("unexpected country counts: %+v", countries)
+	}
+
+	if _, err := store.CountBy(ctx, "description", JobQuery{}, 0); err == nil {
+		t.Error("expected an error for a field that cannot be counted")
+	}
+}
+
+func TestSQLiteStorePurgesExpired(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := store.UpsertJob(ctx, testJob("devops engineer", "Acme", time.Now().AddDate(0, 0, -120)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.UpsertJob(ctx, testJob("backend engineer", "Acme", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	// Closed long enough ago that retention has run out
+	if _, err := store.CloseJobs(ctx, []string{testJob("devops engineer", "Acme", time.Time{}).URL}, ClosedGone, time.Now().Add(-JobRetention-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	store.Close(ctx)
+
+	// Expired postings are dropped when the store is reopened
+	store, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+	if n, _ := store.CountJobs(ctx, JobQuery{}); n != 1 {
+		t.Errorf("expected the expired posting to be purged, %d left", n)
+	}
+	if _, err := store.GetJob(ctx, old.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected %s to be purged, got %v", old.ID, err)
+	}
+}
+
+func TestStoreRecordsRevisions(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		posted := time.Now().AddDate(0, 0, -1)
+
+		job := testJob("backend engineer", "Acme", posted)
+		job.Salary = "$100,000"
+		first, err := store.UpsertJob(ctx, job)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Salary raised without touching the description
+		job.Salary = "$120,000"
+		job.Skills = []string{"aws", "go", "kubernetes"}
+		if res, err := store.UpsertJob(ctx, job); err != nil || res.Action != UpsertUpdated {
+			t.Fatalf("expected update, got %+v (%v)", res, err)
+		}
+		if res, _ := store.UpsertJob(ctx, job); res.Action != UpsertUnchanged {
+			t.Fatalf("expected unchanged on re-crawl, got %+v", res)
+		}
+
+		revisions, err := store.JobHistory(ctx, first.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 1 || revisions[0].SourceURL != job.URL || len(revisions[0].Changes) != 2 {
+			t.Fatalf("expected one revision with two changes, got %+v", revisions)
+		}
+		salary, skills := revisions[0].Changes[0], revisions[0].Changes[1]
+		if salary.Field != "salary" || salary.Old != "$100,000" || salary.New != "$120,000" {
+			t.Errorf("unexpected salary change: %+v", salary)
+		}
+		if skills.Field != "skills" || len(skills.Added) != 1 || skills.Added[0] != "go" || len(skills.Removed) != 1 {
+			t.Errorf("unexpected skills change: %+v", skills)
+		}
+
+		stored, _ := store.GetJob(ctx, first.ID)
+		if stored.Salary != "$120,000" {
+			t.Errorf("expected the posting to hold the new salary, got %q", stored.Salary)
+		}
+	})
+}
+
+func TestStoreLifecycle(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		now := time.Now()
+
+		listed := testJob("backend engineer", "Acme", now.AddDate(0, 0, -20))
+		delisted := testJob("frontend engineer", "Acme", now.AddDate(0, 0, -20))
+		stale := testJob("data engineer", "Globex", now.AddDate(0, 0, -20))
+		gone := testJob("ml engineer", "Globex", now.AddDate(0, 0, -20))
+		ids := make(map[string]string)
+		for _, j := range []JobPosting{listed, delisted, stale, gone} {
+			res, err := store.UpsertJob(ctx, j)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids[j.URL] = res.ID
+		}
+
+		// A crawl 15 days later finds one posting and a 404 for another;
+		// stale was seen 5 days ago, delisted not since it was stored
+		if n, err := store.MarkSeen(ctx, []string{stale.URL}, now.AddDate(0, 0, 10)); err != nil || n != 1 {
+			t.Fatalf("expected 1 posting marked seen, got %d (%v)", n, err)
+		}
+		later := now.AddDate(0, 0, 15)
+		store.MarkSeen(ctx, []string{listed.URL}, later)
+		if n, err := store.CloseJobs(ctx, []string{gone.URL}, ClosedGone, later); err != nil || n != 1 {
+			t.Fatalf("expected 1 posting closed, got %d (%v)", n, err)
+		}
+
+		sweep, err := store.SweepLifecycle(ctx, JobQuery{Source: "weworkremotely.com"}, DefaultLifecyclePolicy(), later)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sweep.NotSeen != 1 || sweep.Closed != 1 || len(sweep.ClosedIDs) != 1 || sweep.ClosedIDs[0] != ids[delisted.URL] {
+			t.Errorf("unexpected sweep: %+v", sweep)
+		}
+
+		want := map[string]string{listed.URL: StatusOpen, stale.URL: StatusNotSeen, delisted.URL: StatusClosed, gone.URL: StatusClosed}
+		for url, status := range want {
+			job, err := store.GetJob(ctx, ids[url])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if JobStatus(*job) != status {
+				t.Errorf("%s: expected %s, got %s", url, status, JobStatus(*job))
+			}
+		}
+
+		job, _ := store.GetJob(ctx, ids[delisted.URL])
+		if job.ClosedReason != ClosedDelisted || job.ClosedAt == nil || job.ClosedAt.After(now.Add(time.Minute)) {
+			t.Errorf("expected delisted as of when it was last seen, got %q at %v", job.ClosedReason, job.ClosedAt)
+		}
+
+		if n, _ := store.CountJobs(ctx, JobQuery{OpenOnly: true}); n != 2 {
+			t.Errorf("expected 2 postings still open, got %d", n)
+		}
+		if n, _ := store.CountJobs(ctx, JobQuery{OpenAt: now.AddDate(0, 0, 10)}); n != 3 {
+			t.Errorf("expected 3 postings open 10 days later, got %d", n)
+		}
+		if closed, _ := store.QueryJobs(ctx, JobQuery{ClosedSince: now.AddDate(0, 0, 10)}); len(closed) != 1 || closed[0].ID != ids[gone.URL] {
+			t.Errorf("expected only the posting closed 15 days later, got %+v", closed)
+		}
+
+		// A closed posting crawled again is open again
+		if _, err := store.UpsertJob(ctx, gone); err != nil {
+			t.Fatal(err)
+		}
+		if job, _ := store.GetJob(ctx, ids[gone.URL]); JobStatus(*job) != StatusOpen || job.ClosedAt != nil {
+			t.Errorf("expected reopened posting, got %s / %v", job.Status, job.ClosedAt)
+		}
+	})
+}
+
+func TestStoreSearch(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		now := time.Now()
+
+		seed := []struct {
+			job        JobPosting
+			experience *ExperienceRange
+		}{
+			{JobPosting{Title: "Senior Go Engineer", Company: "Acme", Description: "Build Go services on Kubernetes.",
+				Skills: []string{"golang", "kubernetes"}, Salary: "$150,000 - $180,000 USD", Location: "Berlin, Germany",
+				Locations: []LocationEntry{{Name: "Berlin", Country: "Germany", CountryCode: "DE"}}}, &ExperienceRange{Min: 5}},
+			{JobPosting{Title: "Python Developer", Company: "Beta", Description: "Django APIs and some internal tooling.",
+				Skills: []string{"python", "django"}, Salary: "$90k - $110k", Location: "Remote - US",
+				Locations: []LocationEntry{{Name: "United States", Country: "United States", CountryCode: "US"}}}, &ExperienceRange{Min: 2, Max: 4}},
+			{JobPosting{Title: "Frontend Engineer", Company: "Gamma", Description: "React and TypeScript.",
+				Skills: []string{"react", "typescript"}, Location: "London, UK"}, nil},
+			{JobPosting{Title: "Platform Engineer", Company: "Delta", Description: "Kubernetes, Terraform and a bit of Go.",
+				Skills: []string{"go", "kubernetes", "terraform"}, Salary: "$120,000 or more USD", Location: "Anywhere"}, &ExperienceRange{Min: 3, Max: 6}},
+		}
+		for i, s := range seed {
+			job := s.job
+			job.ExperienceRange = s.experience
+			job.PostedOn = now.AddDate(0, 0, -1-i)
+			job.URL = "https://example.com/jobs/" + NormalizeTitle(job.Title)
+			job.Source = "example.com"
+			if _, err := store.UpsertJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		titles := func(q JobQuery) []string {
+			t.Helper()
+			jobs, err := store.QueryJobs(ctx, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, j := range jobs {
+				titles = append(titles, j.Title)
+			}
+			return titles
+		}
+		expect := func(name string, q JobQuery, want ...string) {
+			t.Helper()
+			if got := titles(q); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %q, want %q", name, got, want)
+			}
+		}
+		years := func(n int) *int { return &n }
+
+		expect("text", JobQuery{Text: "go engineer"}, "Senior Go Engineer", "Platform Engineer")
+		expect("relevance", JobQuery{Text: "engineer go", Sort: SortRelevance}, "Senior Go Engineer", "Platform Engineer")
+		expect("all skills", JobQuery{SkillsAll: []string{"Golang", "k8s"}}, "Senior Go Engineer", "Platform Engineer")
+		expect("any skill", JobQuery{SkillsAny: []string{"python", "react"}}, "Python Developer", "Frontend Engineer")
+		expect("location", JobQuery{Location: "germany"}, "Senior Go Engineer")
+		expect("country code", JobQuery{Location: "us"}, "Python Developer")
+		expect("salary floor", JobQuery{SalaryMin: 130000}, "Senior Go Engineer", "Platform Engineer")
+		expect("salary ceiling", JobQuery{SalaryMax: 100000}, "Python Developer")
+		expect("salary known", JobQuery{SalaryKnown: true}, "Senior Go Engineer", "Python Developer", "Platform Engineer")
+		expect("experience", JobQuery{Experience: years(3)}, "Python Developer", "Platform Engineer")
+		expect("by salary", JobQuery{Sort: SortSalary}, "Senior Go Engineer", "Platform Engineer", "Python Developer", "Frontend Engineer")
+		expect("oldest", JobQuery{Sort: SortOldest, Limit: 1}, "Platform Engineer")
+
+		// Salary amounts count as decimals, leaving out unknown ones
+		mins, err := store.CountBy(ctx, "salaryMin", JobQuery{}, 0)
+		if err != nil || len(mins) != 3 || !slices.ContainsFunc(mins, func(c CountResult) bool { return c.Value == "150000" }) {
+			t.Errorf("unexpected salary floors: %+v (%v)", mins, err)
+		}
+		if maxes, _ := store.CountBy(ctx, "salaryMax", JobQuery{}, 0); len(maxes) != 2 {
+			t.Errorf("expected open-ended ranges left out, got %+v", maxes)
+		}
+
+		// Following cursors visits every posting once, in order
+		for _, q := range []JobQuery{{Limit: 3}, {Sort: SortSalary, Limit: 3}, {Sort: SortOldest, Limit: 3}, {Text: "engineer", Sort: SortRelevance, Limit: 2}} {
+			all := titles(JobQuery{Text: q.Text, Sort: q.Sort})
+			var paged []string
+			for page := 0; page < 5; page++ {
+				jobs, err := store.QueryJobs(ctx, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, j := range jobs {
+					paged = append(paged, j.Title)
+				}
+				if len(jobs) < q.Limit {
+					break
+				}
+				token := q.NextCursor(jobs).Encode()
+				if q.After, err = ParseCursor(token); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(paged, all) {
+				t.Errorf("paging by %q: got %q, want %q", q.Sort, paged, all)
+			}
+		}
+	})
+}
+
+func TestStoreSavedSearches(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		first, err := store.CreateSearch(ctx, SavedSearch{Name: "go", Skills: []string{"go"}, Notifier: NotifyFile, Target: "-"})
+		if err != nil || first.ID == "" || first.CreatedAt.IsZero() {
+			t.Fatalf("expected a stored search, got %+v (%v)", first, err)
+		}
+		second, _ := store.CreateSearch(ctx, SavedSearch{Name: "berlin", Location: "berlin", Notifier: NotifyFile, Target: "-"})
+
+		searches, err := store.ListSearches(ctx)
+		if err != nil || len(searches) != 2 || searches[0].ID != first.ID || !reflect.DeepEqual(searches[0].Skills, []string{"go"}) {
+			t.Fatalf("unexpected searches: %+v (%v)", searches, err)
+		}
+
+		if err := store.DeleteSearch(ctx, first.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.DeleteSearch(ctx, first.ID); !errors.Is(err, ErrSearchNotFound) {
+			t.Errorf("expected ErrSearchNotFound, got %v", err)
+		}
+		if searches, _ := store.ListSearches(ctx); len(searches) != 1 || searches[0].ID != second.ID {
+			t.Errorf("expected only %s left, got %+v", second.ID, searches)
+		}
+	})
+}
+
+func TestStoreWebhooks(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		hook, err := store.CreateWebhook(ctx, Webhook{URL: "https://example.com/hook", Events: []string{"job.created"}, Secret: "s"})
+		if err != nil || hook.ID == "" || hook.CreatedAt.IsZero() {
+			t.Fatalf("expected a stored webhook, got %+v (%v)", hook, err)
+		}
+		if got, err := store.GetWebhook(ctx, hook.ID); err != nil || got.Secret != "s" || !got.Wants("job.created") || got.Wants("job.closed") {
+			t.Fatalf("unexpected webhook: %+v (%v)", got, err)
+		}
+
+		start := time.Now().Truncate(time.Second)
+		for attempt := 1; attempt <= 3; attempt++ {
+			d := Delivery{WebhookID: hook.ID, EventID: "ev", EventType: "job.created", Attempt: attempt,
+				StatusCode: 500, DeliveredAt: start.Add(time.Duration(attempt) * time.Second)}
+			if err := store.RecordDelivery(ctx, d); err != nil {
+				t.Fatal(err)
+			}
+		}
+		deliveries, err := store.ListDeliveries(ctx, hook.ID, 2)
+		if err != nil || len(deliveries) != 2 || deliveries[0].Attempt != 3 || deliveries[0].ID == "" {
+			t.Fatalf("expected the two newest deliveries, got %+v (%v)", deliveries, err)
+		}
+
+		if err := store.DeleteWebhook(ctx, hook.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetWebhook(ctx, hook.ID); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("expected ErrWebhookNotFound, got %v", err)
+		}
+		if deliveries, _ := store.ListDeliveries(ctx, hook.ID, 10); len(deliveries) != 0 {
+			t.Errorf("expected deliveries to go with the webhook, got %d", len(deliveries))
+		}
+	})
+}
+
+func TestStoreSchedules(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		schedule, err := store.CreateSchedule(ctx, Schedule{Name: "nightly", Cron: "0 3 * * *", Roles: []string{"backend"}, Enabled: true})
+		if err != nil || schedule.ID == "" || schedule.CreatedAt.IsZero() {
+			t.Fatalf("expected a stored schedule, got %+v (%v)", schedule, err)
+		}
+
+		run := ScheduleRun{StartedAt: time.Now().Truncate(time.Millisecond), Duration: 1.5, Status: RunOK}
+		if err := store.RecordScheduleRun(ctx, schedule.ID, run); err != nil {
+			t.Fatal(err)
+		}
+
+		// An edit keeps the recorded run
+		schedule.Cron = "@hourly"
+		schedule.Sources = []string{"weworkremotely.com"}
+		if _, err := store.UpdateSchedule(ctx, schedule); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetSchedule(ctx, schedule.ID)
+		if err != nil || got.Cron != "@hourly" || !reflect.DeepEqual(got.Sources, schedule.Sources) ||
+			got.LastRun == nil || got.LastRun.Status != RunOK || !got.LastRun.StartedAt.Equal(run.StartedAt) {
+			t.Fatalf("unexpected schedule after update: %+v (%v)", got, err)
+		}
+
+		if schedules, err := store.ListSchedules(ctx); err != nil || len(schedules) != 1 {
+			t.Fatalf("unexpected schedules: %+v (%v)", schedules, err)
+		}
+		if err := store.DeleteSchedule(ctx, schedule.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetSchedule(ctx, schedule.ID); !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("expected ErrScheduleNotFound, got %v", err)
+		}
+		if err := store.RecordScheduleRun(ctx, schedule.ID, run); !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("expected ErrScheduleNotFound recording a run, got %v", err)
+		}
+	})
+}
+
+func TestStoreRoles(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		t.Cleanup(func() { SetRoles(DefaultRoles) })
+
+		backend, _ := ResolveRole("backend")
+		backend.Enabled = false
+		added := Role{Slug: "developer-advocate", Name: "Developer advocate", Synonyms: []string{"DevRel"}, Enabled: true}
+		for _, role := range []Role{added, backend} {
+			if err := store.SaveRole(ctx, role); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if roles, err := store.ListRoles(ctx); err != nil || len(roles) != 2 || roles[0].Slug != "backend" || roles[1].Slug != "developer-advocate" {
+			t.Fatalf("unexpected stored roles: %+v (%v)", roles, err)
+		}
+
+		if err := LoadRoles(ctx, store); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(Roles()); got != len(DefaultRoles)+1 {
+			t.Errorf("expected %d roles, got %d", len(DefaultRoles)+1, got)
+		}
+		if r, ok := ResolveRole("devrel"); !ok || r.Slug != "developer-advocate" {
+			t.Errorf("expected the synonym to resolve to the added role, got %+v (%v)", r, ok)
+		}
+		if r, ok := ResolveRole("Backend developer"); !ok || r.Enabled {
+			t.Errorf("expected backend to resolve disabled, got %+v (%v)", r, ok)
+		}
+		if got := ClassifyRoles("Senior Developer Advocate", nil); !reflect.DeepEqual(got, []string{"developer-advocate"}) {
+			t.Errorf("expected the added role to classify by its name, got %v", got)
+		}
+		if got := ClassifyRoles("Backend Engineer", nil); !reflect.DeepEqual(got, []string{GenericRole}) {
+			t.Errorf("expected a disabled role's title to classify as generic, got %v", got)
+		}
+	})
+}
+
+func TestStoreTrendResults(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		now := time.Now().UTC().Truncate(time.Second)
+
+		fresh := TrendResult{Key: "/api/trends?role=backend", Body: []byte(`{"total":2}`), ETag: `"abc"`,
+			ComputedAt: now, ExpireAt: now.Add(time.Hour)}
+		expired := TrendResult{Key: "/api/trends?role=frontend", Body: []byte(`{}`), ETag: `"def"`,
+			ComputedAt: now.Add(-2 * time.Hour), ExpireAt: now.Add(-time.Hour)}
+		for _, result := range []TrendResult{expired, fresh} {
+			if err := store.SaveTrendResult(ctx, result); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := store.GetTrendResult(ctx, fresh.Key)
+		if err != nil || string(got.Body) != string(fresh.Body) || got.ETag != fresh.ETag || !got.ComputedAt.Equal(now) {
+			t.Fatalf("unexpected result: %+v (%v)", got, err)
+		}
+		if _, err := store.GetTrendResult(ctx, expired.Key); !errors.Is(err, ErrTrendResultNotFound) {
+			t.Errorf("expected an expired result to be missing, got %v", err)
+		}
+
+		if err := store.ClearTrendResults(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetTrendResult(ctx, fresh.Key); !errors.Is(err, ErrTrendResultNotFound) {
+			t.Errorf("expected cleared results to be missing, got %v", err)
+		}
+	})
+}
+
+func TestStoreCrawlQueue(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		task, created, err := store.EnqueueCrawl(ctx, CrawlTask{Role: "Backend", MaxJobs: 10})
+		if err != nil || !created || task.ID == "" || task.Status != TaskQueued {
+			t.Fatalf("expected a queued task, got %+v created=%v (%v)", task, created, err)
+		}
+		// A role has one active task
+		again, created, err := store.EnqueueCrawl(ctx, CrawlTask{Role: "backend"})
+		if err != nil || created || again.ID != task.ID {
+			t.Fatalf("expected the queued task back, got %+v created=%v (%v)", again, created, err)
+		}
+		if _, _, err := store.EnqueueCrawl(ctx, CrawlTask{Role: "frontend"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Oldest first
+		leased, err := store.LeaseCrawl(ctx, "w1", time.Minute)
+		if err != nil || leased.ID != task.ID || leased.Status != TaskRunning || leased.Worker != "w1" ||
+			leased.Attempts != 1 || leased.MaxJobs != 10 {
+			t.Fatalf("unexpected lease: %+v (%v)", leased, err)
+		}
+		if err := store.RenewLease(ctx, task.ID, "w1", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.RenewLease(ctx, task.ID, "w2", time.Minute); !errors.Is(err, ErrLeaseLost) {
+			t.Errorf("expected ErrLeaseLost renewing another worker's lease, got %v", err)
+		}
+
+		// A lease that ran out goes to the next worker
+		frontend, err := store.LeaseCrawl(ctx, "w1", -time.Second)
+		if err != nil || frontend.Role != "frontend" {
+			t.Fatalf("unexpected lease: %+v (%v)", frontend, err)
+		}
+		retaken, err := store.LeaseCrawl(ctx, "w2", time.Minute)
+		if err != nil || retaken.ID != frontend.ID || retaken.Worker != "w2" || retaken.Attempts != 2 {
+			t.Fatalf("expected the expired task to be leased again, got %+v (%v)", retaken, err)
+		}
+		if err := store.FinishCrawl(ctx, frontend.ID, "w1", ""); !errors.Is(err, ErrLeaseLost) {
+			t.Errorf("expected ErrLeaseLost finishing a lost task, got %v", err)
+		}
+		if _, err := store.LeaseCrawl(ctx, "w3", time.Minute); !errors.Is(err, ErrQueueEmpty) {
+			t.Errorf("expected ErrQueueEmpty, got %v", err)
+		}
+
+		if err := store.FinishCrawl(ctx, task.ID, "w1", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.FinishCrawl(ctx, frontend.ID, "w2", "board unreachable"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetCrawl(ctx, frontend.ID)
+		if err != nil || got.Status != TaskFailed || got.Error != "board unreachable" || got.FinishedAt == nil || got.LeaseUntil != nil {
+			t.Fatalf("unexpected finished task: %+v (%v)", got, err)
+		}
+
+		// Once finished, the role can be enqueued again
+		if _, created, err := store.EnqueueCrawl(ctx, CrawlTask{Role: "backend"}); err != nil || !created {
+			t.Fatalf("expected a new task, created=%v (%v)", created, err)
+		}
+		if done, err := store.ListCrawls(ctx, TaskDone, 0); err != nil || len(done) != 1 || done[0].ID != task.ID {
+			t.Fatalf("unexpected done tasks: %+v (%v)", done, err)
+		}
+		if all, err := store.ListCrawls(ctx, "", 2); err != nil || len(all) != 2 || all[0].Status != TaskQueued {
+			t.Fatalf("unexpected tasks: %+v (%v)", all, err)
+		}
+		if _, err := store.GetCrawl(ctx, "missing"); !errors.Is(err, ErrCrawlTaskNotFound) {
+			t.Errorf("expected ErrCrawlTaskNotFound, got %v", err)
+		}
+	})
+}
+
+func TestStoreCrawlQueueFailsAfterMaxAttempts(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		task, _, err := store.EnqueueCrawl(ctx, CrawlTask{Role: "backend"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < MaxTaskAttempts; i++ {
+			if _, err := store.LeaseCrawl(ctx, "w1", -time.Second); err != nil {
+				t.Fatalf("lease %d: %v", i+1, err)
+			}
+		}
+		if _, err := store.LeaseCrawl(ctx, "w1", time.Minute); !errors.Is(err, ErrQueueEmpty) {
+			t.Errorf("expected ErrQueueEmpty, got %v", err)
+		}
+		got, err := store.GetCrawl(ctx, task.ID)
+		if err != nil || got.Status != TaskFailed || got.Error == "" || got.Attempts != MaxTaskAttempts {
+			t.Fatalf("expected the task to fail, got %+v (%v)", got, err)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTrendResultNotFound is returned when no unexpired trend result has the
+// requested key.
+var ErrTrendResultNotFound = errors.New("trend result not found")
+
+// TrendResultStore shares computed trend results between the processes that
+// serve them. Writers clear it once they changed postings, so a result found
+// in it is still current.
+type TrendResultStore interface {
+	// GetTrendResult returns the result stored under key, unless it expired.
+	GetTrendResult(ctx context.Context, key string) (*TrendResult, error)
+	// SaveTrendResult stores result, replacing the one with its key.
+	SaveTrendResult(ctx context.Context, result TrendResult) error
+	// ClearTrendResults drops every stored result.
+	ClearTrendResults(ctx context.Context) error
+}
+
+// TrendResult is a trend report, comparison or insight as served: its JSON
+// body and the validators HTTP clients revalidate it with.
+type TrendResult struct {
+	Key        string    `bson:"_id" json:"key"` // what was asked, e.g. the request path and query
+	Body       []byte    `bson:"body" json:"body"`
+	ETag       string    `bson:"etag" json:"etag"` // quoted, as sent in the ETag header
+	ComputedAt time.Time `bson:"computedAt" json:"computed_at"`
+	ExpireAt   time.Time `bson:"expireAt" json:"expire_at"` // recomputed after this even if nothing cleared it
+}
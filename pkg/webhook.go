@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// ErrWebhookNotFound is returned when no webhook has the requested ID.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// DeliveryRetention is how long delivery attempts are kept.
+const DeliveryRetention = 30 * 24 * time.Hour
+
+// WebhookStore persists webhook subscriptions and the log of their deliveries.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, hook Webhook) (Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*Webhook, error)
+	// ListWebhooks returns every subscription, oldest first.
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	RecordDelivery(ctx context.Context, d Delivery) error
+	// ListDeliveries returns a webhook's delivery attempts, newest first.
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]Delivery, error)
+}
+
+// Webhook subscribes a URL to events. Payloads are signed with Secret.
+type Webhook struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	URL       string    `bson:"url" json:"url"`
+	Events    []string  `bson:"events,omitempty" json:"events,omitempty"` // event types to send; empty means all
+	Secret    string    `bson:"secret" json:"secret,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"created_at"`
+}
+
+// Wants reports whether the webhook subscribed to events of type eventType.
+func (h Webhook) Wants(eventType string) bool {
+	return len(h.Events) == 0 || containsString(h.Events, eventType)
+}
+
+// Validate checks the subscription's URL.
+func (h Webhook) Validate() error {
+	if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an http(s) URL")
+	}
+	return nil
+}
+
+// Delivery is one attempt to deliver an event to a webhook.
+type Delivery struct {
+	ID          string        `bson:"_id,omitempty" json:"id"`
+	WebhookID   string        `bson:"webhookId" json:"webhook_id"`
+	EventID     string        `bson:"eventId" json:"event_id"`
+	EventType   string        `bson:"eventType" json:"event_type"`
+	Attempt     int           `bson:"attempt" json:"attempt"` // 1 for the first try
+	StatusCode  int           `bson:"statusCode,omitempty" json:"status_code,omitempty"`
+	Error       string        `bson:"error,omitempty" json:"error,omitempty"`
+	Success     bool          `bson:"success" json:"success"`
+	Duration    time.Duration `bson:"duration" json:"duration_ns"`
+	DeliveredAt time.Time     `bson:"deliveredAt" json:"delivered_at"`
+}
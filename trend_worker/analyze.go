@@ -2,124 +2,116 @@ package trend_worker
 
 import (
 	"context"
-	"log"
-	"os"
-	"time"
+	"math"
+	"slices"
+	"sync"
 
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
-	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/vx6fid/job-crawler/pkg"
 )
 
-type CountResult struct {
-	Value string `json:"value"`
-	Count int    `json:"count"`
-}
+type CountResult = pkg.CountResult
 
+// TrendReport breaks the postings matching a TrendQuery down by each of its
+// dimensions.
 type TrendReport struct {
-	TopSkills              []CountResult `json:"top_skills"`
-	TopLocations           []CountResult `json:"top_locations"`
-	TopCompanies           []CountResult `json:"top_companies"`
-	ExperienceDistribution []CountResult `json:"experience_distribution"`
+	Query      TrendQuery              `json:"query"`
+	Total      int                     `json:"total"` // postings matching the query
+	Dimensions map[string][]TrendCount `json:"dimensions"`
+
+	// Deprecated: the lists reports had before dimensions were added, kept
+	// for one release and filled from their dimension when it is reported.
+	// TopLocations counts the location text as crawled, as it always did,
+	// where the locations dimension counts the parsed places.
+	TopSkills                  []CountResult `json:"top_skills,omitempty"`
+	TopLocations               []CountResult `json:"top_locations,omitempty"`
+	TopCountries               []CountResult `json:"top_countries,omitempty"`
+	RemotePolicyDistribution   []CountResult `json:"remote_policy_distribution,omitempty"`
+	TopCompanies               []CountResult `json:"top_companies,omitempty"`
+	ExperienceDistribution     []CountResult `json:"experience_distribution,omitempty"`
+	SeniorityDistribution      []CountResult `json:"seniority_distribution,omitempty"`
+	EmploymentTypeDistribution []CountResult `json:"employment_type_distribution,omitempty"`
 }
 
-func AnalyzeTrendsByRole(role string) (*TrendReport, error) {
-	coll, err := getMongoCollection()
-	if err != nil {
-		return nil, err
-	}
-
-	match := bson.D{}
-	if role != "" {
-		// Case-insensitive partial match on title
-		match = bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: role}, {Key: "$options", Value: "i"}}}}
-	}
-
-	skills, err := countAggregation(coll, "skills", match)
-	if err != nil {
-		return nil, err
-	}
-
-	locations, err := countAggregation(coll, "location", match)
-	if err != nil {
-		return nil, err
-	}
-
-	experience, err := countAggregation(coll, "experience", match)
-	if err != nil {
-		return nil, err
-	}
-
-	companies, err := countAggregation(coll, "company", match)
-	if err != nil {
-		return nil, err
-	}
-
-	report := &TrendReport{
-		TopSkills:              skills,
-		TopLocations:           locations,
-		TopCompanies:           companies,
-		ExperienceDistribution: experience,
-	}
-
-	return report, nil
+// TrendCount is how many matching postings have a value of a dimension.
+type TrendCount struct {
+	Value   string  `json:"value"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"` // of the matching postings, to one decimal
 }
 
-func getMongoCollection() (*mongo.Collection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	database_url := os.Getenv("DATABASE_URL")
-	if database_url == "" {
-		log.Fatal("DATABASE_URL not set in .env file")
-	}
-
-	clientOptions := options.Client().ApplyURI(database_url)
-	client, err := mongo.Connect(clientOptions)
-	if err != nil {
-		return nil, err
-	}
+// Analyzer computes trend reports from a job store.
+type Analyzer struct {
+	store pkg.JobStore
+}
 
-	if err = client.Ping(ctx, readpref.Primary()); err != nil {
-		return nil, err
-	}
+func NewAnalyzer(store pkg.JobStore) *Analyzer {
+	return &Analyzer{store: store}
+}
 
-	return client.Database("job_scraper").Collection("jobs"), nil
+// AnalyzeTrendsByRole reports the default dimensions over every posting of
+// role.
+func (a *Analyzer) AnalyzeTrendsByRole(ctx context.Context, role string) (*TrendReport, error) {
+	return a.AnalyzeTrends(ctx, TrendQuery{Role: role})
 }
 
-func countAggregation(coll *mongo.Collection, field string, match bson.D) ([]CountResult, error) {
-	agg := []bson.M{}
-	if len(match) > 0 {
-		agg = append(agg, bson.M{"$match": match})
+// AnalyzeTrends counts the postings matching q and the top values of each of
+// its dimensions, all at once.
+func (a *Analyzer) AnalyzeTrends(ctx context.Context, q TrendQuery) (*TrendReport, error) {
+	q = q.withDefaults()
+	jq := q.JobQuery()
+
+	report := &TrendReport{Query: q, Dimensions: make(map[string][]TrendCount, len(q.Dimensions))}
+	counts := make(map[string][]CountResult, len(q.Dimensions))
+	var mu sync.Mutex
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		total, err := a.store.CountJobs(ctx, jq)
+		report.Total = total
+		return err
+	})
+	for _, dim := range q.Dimensions {
+		g.Go(func() error {
+			results, err := a.store.CountBy(ctx, Dimensions[dim], jq, q.Limit)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			counts[dim] = results
+			mu.Unlock()
+			return nil
+		})
 	}
-	agg = append(agg,
-		bson.M{"$unwind": "$" + field},
-		bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
-		bson.M{"$sort": bson.M{"count": -1}},
-		bson.M{"$limit": 20},
-	)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-
-	cursor, err := coll.Aggregate(ctx, agg)
-	if err != nil {
+	if slices.Contains(q.Dimensions, "locations") {
+		g.Go(func() (err error) {
+			report.TopLocations, err = a.store.CountBy(ctx, "location", jq, q.Limit)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var results []CountResult
-	for cursor.Next(ctx) {
-		var r struct {
-			ID    string `bson:"_id"`
-			Count int    `bson:"count"`
+	for dim, results := range counts {
+		values := make([]TrendCount, len(results))
+		for i, r := range results {
+			values[i] = TrendCount{Value: r.Value, Count: r.Count, Percent: percent(r.Count, report.Total)}
 		}
-		if err := cursor.Decode(&r); err != nil {
-			return nil, err
-		}
-		results = append(results, CountResult{Value: r.ID, Count: r.Count})
+		report.Dimensions[dim] = values
 	}
+	report.TopSkills, report.TopCountries = counts["skills"], counts["countries"]
+	report.RemotePolicyDistribution, report.TopCompanies = counts["remote_policy"], counts["companies"]
+	report.ExperienceDistribution, report.SeniorityDistribution = counts["experience"], counts["seniority"]
+	report.EmploymentTypeDistribution = counts["employment_type"]
+	return report, nil
+}
 
-	return results, nil
+// percent returns count as a percentage of total, to one decimal.
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(count)/float64(total)*1000) / 10
 }
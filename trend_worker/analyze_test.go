@@ -0,0 +1,164 @@
+package trend_worker
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func seedStore(t *testing.T, jobs ...pkg.JobPosting) *pkg.MemoryStore {
+	t.Helper()
+	store := pkg.NewMemoryStore()
+	for _, job := range jobs {
+		pkg.ClassifyJob(&job)
+		pkg.NormalizeLocation(&job)
+		if _, err := store.UpsertJob(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestAnalyzeTrendsByRole(t *testing.T) {
+	now := time.Now()
+	store := seedStore(t,
+		pkg.JobPosting{Title: "senior devops engineer", Company: "Acme", Location: "USA Only", PostedOn: now,
+			Description: "5+ years with Kubernetes", Skills: []string{"kubernetes", "aws"}, Source: "weworkremotely.com"},
+		pkg.JobPosting{Title: "devops engineer", Company: "Globex", Location: "Europe Only", PostedOn: now,
+			Description: "2-4 years, full-time", Skills: []string{"kubernetes", "terraform"}, Source: "weworkremotely.com"},
+		pkg.JobPosting{Title: "product designer", Company: "Acme", Location: "USA Only", PostedOn: now,
+			Description: "Figma", Skills: []string{"figma"}, Source: "weworkremotely.com"},
+	)
+
+	report, err := NewAnalyzer(store).AnalyzeTrendsByRole(context.Background(), "devops")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skills := report.Dimensions["skills"]
+	if report.Total != 2 || len(skills) != 3 || skills[0] != (TrendCount{Value: "kubernetes", Count: 2, Percent: 100}) {
+		t.Errorf("unexpected skills: %+v of %d", skills, report.Total)
+	}
+	if companies := report.Dimensions["companies"]; len(companies) != 2 || companies[0].Percent != 50 {
+		t.Errorf("expected the designer posting to be filtered out, got %+v", companies)
+	}
+	if countries := report.Dimensions["countries"]; len(countries) != 1 || countries[0].Value != "United States" {
+		t.Errorf("unexpected countries: %+v", countries)
+	}
+	if policies := report.Dimensions["remote_policy"]; len(policies) != 1 || policies[0].Count != 2 {
+		t.Errorf("unexpected remote policies: %+v", policies)
+	}
+	if seniority := report.Dimensions["seniority"]; len(seniority) != 2 {
+		t.Errorf("unexpected seniority: %+v", seniority)
+	}
+	if len(report.Dimensions) != len(DefaultDimensions) {
+		t.Errorf("expected the default dimensions, got %d", len(report.Dimensions))
+	}
+	if len(report.TopSkills) != 3 || report.TopSkills[0] != (CountResult{Value: "kubernetes", Count: 2}) ||
+		len(report.TopCompanies) != 2 || len(report.SeniorityDistribution) != 2 || len(report.EmploymentTypeDistribution) == 0 {
+		t.Errorf("expected the deprecated top lists, got %+v", report)
+	}
+	// As crawled, not as parsed
+	if len(report.TopLocations) != 2 || report.TopLocations[0] != (CountResult{Value: "Europe Only", Count: 1}) {
+		t.Errorf("unexpected deprecated locations: %+v", report.TopLocations)
+	}
+}
+
+func TestAnalyzeTrendsQuery(t *testing.T) {
+	now := time.Now()
+	store := seedStore(t,
+		pkg.JobPosting{Title: "backend engineer", Company: "Acme", Location: "Berlin, Germany", PostedOn: now.AddDate(0, 0, -2),
+			Skills: []string{"go", "aws"}, Source: "weworkremotely.com"},
+		pkg.JobPosting{Title: "backend engineer", Company: "Globex", Location: "Paris, France", PostedOn: now.AddDate(0, 0, -3),
+			Skills: []string{"go", "postgresql"}, Source: "weworkremotely.com"},
+		pkg.JobPosting{Title: "backend engineer", Company: "Initech", Location: "Berlin, Germany", PostedOn: now.AddDate(0, 0, -40),
+			Skills: []string{"java"}, Source: "weworkremotely.com"},
+		pkg.JobPosting{Title: "backend engineer", Company: "Umbrella", Location: "Berlin, Germany", PostedOn: now.AddDate(0, 0, -1),
+			Skills: []string{"rust"}, Source: "remoteok.com"},
+	)
+
+	params := url.Values{"role": {"backend"}, "since": {"30d"}, "source": {"weworkremotely.com"},
+		"limit": {"1"}, "dimensions": {"skills,companies", "sources"}}
+	q, err := ParseTrendQuery(params, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := NewAnalyzer(store).AnalyzeTrends(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 2 || len(report.Dimensions) != 3 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if skills := report.Dimensions["skills"]; len(skills) != 1 || skills[0] != (TrendCount{Value: "go", Count: 2, Percent: 100}) {
+		t.Errorf("unexpected skills: %+v", skills)
+	}
+	if companies := report.Dimensions["companies"]; len(companies) != 1 || companies[0].Percent != 50 {
+		t.Errorf("unexpected companies: %+v", companies)
+	}
+
+	q.Location, q.Source = "France", ""
+	report, err = NewAnalyzer(store).AnalyzeTrends(context.Background(), q)
+	if err != nil || report.Total != 1 || report.Dimensions["companies"][0].Value != "Globex" {
+		t.Errorf("unexpected report for France: %+v (%v)", report, err)
+	}
+}
+
+func TestParseTrendQuery(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	q, err := ParseTrendQuery(url.Values{"since": {"2025-06-01"}, "until": {"12h"}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Since.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)) || !q.Until.Equal(now.Add(-12*time.Hour)) {
+		t.Errorf("unexpected window: %v to %v", q.Since, q.Until)
+	}
+	if q.Limit != DefaultTopN || len(q.Dimensions) != len(DefaultDimensions) {
+		t.Errorf("expected defaults, got %+v", q)
+	}
+
+	for _, params := range []url.Values{
+		{"since": {"last week"}},
+		{"since": {"2025-06-02"}, "until": {"2025-06-01"}},
+		{"limit": {"0"}},
+		{"limit": {"101"}},
+		{"dimensions": {"skills,salary"}},
+	} {
+		if _, err := ParseTrendQuery(params, now); !errors.Is(err, ErrInvalidTrendQuery) {
+			t.Errorf("expected ErrInvalidTrendQuery for %v, got %v", params, err)
+		}
+	}
+}
+
+func TestAnalyzeSkillMomentum(t *testing.T) {
+	now := time.Now()
+	var jobs []pkg.JobPosting
+	for i := 0; i < 4; i++ {
+		jobs = append(jobs,
+			pkg.JobPosting{Title: "backend engineer", Company: "Recent", PostedOn: now.AddDate(0, 0, -1-i),
+				Description: "recent posting " + string(rune('a'+i)), Skills: []string{"go", "rust"}},
+			pkg.JobPosting{Title: "backend engineer", Company: "Older", PostedOn: now.AddDate(0, 0, -15-i),
+				Description: "older posting " + string(rune('a'+i)), Skills: []string{"java"}},
+		)
+	}
+	store := seedStore(t, jobs...)
+
+	opts := DefaultMomentumOptions()
+	opts.Now = now
+	report, err := NewAnalyzer(store).AnalyzeSkillMomentum(context.Background(), "backend", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.CurrentWindow.Postings != 4 || report.PreviousWindow.Postings != 4 {
+		t.Errorf("unexpected window totals: %+v / %+v", report.CurrentWindow, report.PreviousWindow)
+	}
+	if len(report.Emerging) != 2 || len(report.Declining) != 1 || report.Declining[0].Skill != "java" {
+		t.Errorf("unexpected momentum: %+v / %+v", report.Emerging, report.Declining)
+	}
+}
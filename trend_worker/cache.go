@@ -0,0 +1,162 @@
+package trend_worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// Cache defaults.
+const (
+	// DefaultCacheMaxAge bounds how long a result is served when no write
+	// invalidates it, e.g. because a process that doesn't share the cache
+	// changed postings.
+	DefaultCacheMaxAge = 15 * time.Minute
+	// CacheRecheck is how long a result held in memory is served before the
+	// store is checked again, so that clears from other processes are seen.
+	CacheRecheck = 30 * time.Second
+	// MaxCacheEntries bounds the results held in memory.
+	MaxCacheEntries = 1000
+	// CacheComputeTimeout bounds a computation, which outlives the requests
+	// waiting on it.
+	CacheComputeTimeout = 2 * time.Minute
+)
+
+// Cache keeps trend results, encoded as served, until crawls change the
+// postings they were computed from. Results are held in memory and, with a
+// store, shared through it with every other process serving them.
+type Cache struct {
+	store  pkg.TrendResultStore // nil holds results in memory only
+	maxAge time.Duration
+	now    func() time.Time
+
+	group      singleflight.Group
+	mu         sync.Mutex
+	entries    map[string]cacheEntry
+	generation int // bumped by Invalidate, so results computed before it aren't kept
+}
+
+type cacheEntry struct {
+	result  pkg.TrendResult
+	checked time.Time // when it was computed or last found in the store
+}
+
+// NewCache returns a cache sharing results through store, or holding them in
+// memory only if store is nil. A maxAge of 0 means DefaultCacheMaxAge.
+func NewCache(store pkg.TrendResultStore, maxAge time.Duration) *Cache {
+	if maxAge <= 0 {
+		maxAge = DefaultCacheMaxAge
+	}
+	return &Cache{store: store, maxAge: maxAge, now: time.Now, entries: make(map[string]cacheEntry)}
+}
+
+// Get returns the result cached under key. On a miss it computes the result
+// with compute and caches its JSON; concurrent misses on a key share one
+// computation, which carries on if the caller that started it goes away.
+// Errors of compute are returned as they are and not cached.
+func (c *Cache) Get(ctx context.Context, key string, compute func(ctx context.Context) (interface{}, error)) (pkg.TrendResult, error) {
+	now := c.now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && c.fresh(entry, now) {
+		return entry.result, nil
+	}
+
+	flight := c.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), CacheComputeTimeout)
+		defer cancel()
+
+		if c.store != nil {
+			result, err := c.store.GetTrendResult(ctx, key)
+			if err == nil && result.ExpireAt.After(now) {
+				c.keep(*result, generation, now)
+				return *result, nil
+			}
+			if err != nil && !errors.Is(err, pkg.ErrTrendResultNotFound) {
+				log.Printf("[trends] Failed to read cached result %s: %v", key, err)
+			}
+		}
+
+		value, err := compute(ctx)
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, '\n')
+		result := pkg.TrendResult{
+			Key:        key,
+			Body:       body,
+			ETag:       `"` + pkg.GenerateHash(string(body))[:32] + `"`,
+			ComputedAt: now.UTC().Truncate(time.Second), // as precise as Last-Modified
+			ExpireAt:   now.Add(c.maxAge),
+		}
+		if c.keep(result, generation, now) && c.store != nil {
+			if err := c.store.SaveTrendResult(ctx, result); err != nil {
+				log.Printf("[trends] Failed to store cached result %s: %v", key, err)
+			}
+		}
+		return result, nil
+	})
+	select {
+	case <-ctx.Done():
+		return pkg.TrendResult{}, ctx.Err()
+	case res := <-flight:
+		if res.Err != nil {
+			return pkg.TrendResult{}, res.Err
+		}
+		return res.Val.(pkg.TrendResult), nil
+	}
+}
+
+// Invalidate drops every cached result, in memory and in the store, after
+// postings changed.
+func (c *Cache) Invalidate(ctx context.Context) error {
+	c.mu.Lock()
+	clear(c.entries)
+	c.generation++
+	c.mu.Unlock()
+
+	if c.store == nil {
+		return nil
+	}
+	return c.store.ClearTrendResults(ctx)
+}
+
+func (c *Cache) fresh(entry cacheEntry, now time.Time) bool {
+	if !entry.result.ExpireAt.After(now) {
+		return false
+	}
+	return c.store == nil || now.Sub(entry.checked) < CacheRecheck
+}
+
+// keep holds result in memory unless the cache was invalidated since
+// generation, and reports whether it did.
+func (c *Cache) keep(result pkg.TrendResult, generation int, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return false
+	}
+	if _, ok := c.entries[result.Key]; !ok && len(c.entries) >= MaxCacheEntries {
+		for key, entry := range c.entries {
+			if !c.fresh(entry, now) || len(c.entries) >= MaxCacheEntries {
+				delete(c.entries, key)
+			}
+		}
+	}
+	c.entries[result.Key] = cacheEntry{result: result, checked: now}
+	return true
+}
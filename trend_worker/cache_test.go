@@ -0,0 +1,114 @@
+package trend_worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	store := pkg.NewMemoryStore()
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	computed := 0
+	compute := func(context.Context) (interface{}, error) {
+		computed++
+		return map[string]int{"total": computed}, nil
+	}
+
+	// Two processes sharing the store
+	a, b := NewCache(store, time.Hour), NewCache(store, time.Hour)
+	a.now, b.now = clock, clock
+
+	first, err := a.Get(ctx, "/api/trends?role=backend", compute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first.Body) != "{\"total\":1}\n" || first.ETag == "" || !first.ComputedAt.Equal(now.UTC().Truncate(time.Second)) {
+		t.Fatalf("unexpected result: %+v", first)
+	}
+	for _, c := range []*Cache{a, b} {
+		if again, err := c.Get(ctx, "/api/trends?role=backend", compute); err != nil || again.ETag != first.ETag {
+			t.Errorf("expected the cached result, got %+v (%v)", again, err)
+		}
+	}
+	if computed != 1 {
+		t.Errorf("expected one computation, got %d", computed)
+	}
+
+	// A crawl in another process clears the store; b sees it once it rechecks
+	if err := store.ClearTrendResults(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Get(ctx, "/api/trends?role=backend", compute); err != nil || computed != 1 {
+		t.Errorf("expected b to serve from memory until it rechecks, computed %d (%v)", computed, err)
+	}
+	now = now.Add(CacheRecheck)
+	if _, err := b.Get(ctx, "/api/trends?role=backend", compute); err != nil || computed != 2 {
+		t.Errorf("expected b to recompute after the clear, computed %d (%v)", computed, err)
+	}
+
+	if err := a.Invalidate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if third, err := a.Get(ctx, "/api/trends?role=backend", compute); err != nil || computed != 3 || third.ETag == first.ETag {
+		t.Errorf("expected a to recompute after invalidating, computed %d: %+v (%v)", computed, third, err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := a.Get(ctx, "/api/trends?role=backend", compute); err != nil || computed != 4 {
+		t.Errorf("expected an expired result to be recomputed, computed %d (%v)", computed, err)
+	}
+
+	failure := errors.New("store down")
+	fail := func(context.Context) (interface{}, error) { return nil, failure }
+	if _, err := a.Get(ctx, "/api/trends?role=frontend", fail); !errors.Is(err, failure) {
+		t.Errorf("expected the compute error, got %v", err)
+	}
+	if _, err := a.Get(ctx, "/api/trends?role=frontend", compute); err != nil || computed != 5 {
+		t.Errorf("expected errors not to be cached, computed %d (%v)", computed, err)
+	}
+}
+
+func TestCacheOutlivesCaller(t *testing.T) {
+	cache := NewCache(pkg.NewMemoryStore(), time.Hour)
+	started, release := make(chan struct{}), make(chan struct{})
+	computed := 0
+	compute := func(ctx context.Context) (interface{}, error) {
+		computed++
+		close(started)
+		<-release
+		return map[string]bool{"cancelled": ctx.Err() != nil}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := cache.Get(ctx, "/api/trends", compute)
+		done <- err
+	}()
+	<-started
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the caller to give up, got %v", err)
+	}
+	close(release)
+
+	// The computation finishes and is cached for the next caller
+	for range 100 {
+		result, err := cache.Get(context.Background(), "/api/trends", compute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if computed == 1 && string(result.Body) == "{\"cancelled\":false}\n" {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("expected the computation to finish uncancelled, computed %d", computed)
+}
@@ -0,0 +1,232 @@
+package trend_worker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// MaxCompareRoles is how many roles one comparison may cover.
+const MaxCompareRoles = 5
+
+// compareDimensions are the dimensions reported for each compared role.
+var compareDimensions = []string{"skills", "countries", "remote_policy", "experience"}
+
+// RoleComparison sets the trend reports of several roles side by side.
+type RoleComparison struct {
+	Query        TrendQuery      `json:"query"` // the window, filters and top-N shared by every role
+	Roles        []RoleBreakdown `json:"roles"`
+	SharedSkills []string        `json:"shared_skills"` // in the top skills of every role
+}
+
+// RoleBreakdown is what a comparison reports for one role.
+type RoleBreakdown struct {
+	Role         string        `json:"role"` // slug
+	Name         string        `json:"name"`
+	Total        int           `json:"total"`
+	Skills       []TrendCount  `json:"skills"`        // percent is the share of the role's postings
+	UniqueSkills []string      `json:"unique_skills"` // in the role's top skills only
+	Countries    []TrendCount  `json:"countries"`
+	RemotePolicy []TrendCount  `json:"remote_policy"`
+	Experience   []TrendCount  `json:"experience"`
+	Salary       SalarySummary `json:"salary"`
+}
+
+// SalarySummary describes the parsed, annualized salary ranges of a set of
+// postings. Amounts are compared as they are, whatever their currency.
+type SalarySummary struct {
+	Postings   int          `json:"postings"`   // with a salary range, even if open-ended
+	Percent    float64      `json:"percent"`    // of all the postings
+	Min        int          `json:"min"`        // lowest start of a range
+	MedianMin  int          `json:"median_min"` // of the ranges with a start
+	MedianMax  int          `json:"median_max"` // of the ranges with an end
+	Max        int          `json:"max"`        // highest end of a range
+	Currencies []TrendCount `json:"currencies"`
+}
+
+// CompareRoles reports each of roles over the postings q selects, ignoring
+// q's role and dimensions, and which of their top skills they share.
+func (a *Analyzer) CompareRoles(ctx context.Context, roles []string, q TrendQuery) (*RoleComparison, error) {
+	if len(roles) < 2 || len(roles) > MaxCompareRoles {
+		return nil, fmt.Errorf("%w: compare 2 to %d roles", ErrInvalidTrendQuery, MaxCompareRoles)
+	}
+	seen := map[string]bool{}
+	for _, role := range roles {
+		slug := pkg.RoleSlug(role)
+		if seen[slug] {
+			return nil, fmt.Errorf("%w: role %q is compared twice", ErrInvalidTrendQuery, role)
+		}
+		seen[slug] = true
+	}
+	q.Role, q.Dimensions = "", compareDimensions
+	q = q.withDefaults()
+
+	comparison := &RoleComparison{Query: q, Roles: make([]RoleBreakdown, len(roles))}
+	g, ctx := errgroup.WithContext(ctx)
+	for i, role := range roles {
+		g.Go(func() error {
+			rq := q
+			rq.Role = role
+			breakdown, err := a.breakDownRole(ctx, rq)
+			if err != nil {
+				return err
+			}
+			comparison.Roles[i] = breakdown
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	// A skill is shared if every role has it among its top skills, and unique
+	// to a role if no other role has it there
+	holders := map[string]int{}
+	for _, r := range comparison.Roles {
+		for _, s := range r.Skills {
+			holders[s.Value]++
+		}
+	}
+	comparison.SharedSkills = []string{}
+	for i, r := range comparison.Roles {
+		comparison.Roles[i].UniqueSkills = []string{}
+		for _, s := range r.Skills {
+			switch holders[s.Value] {
+			case len(roles):
+				if i == 0 {
+					comparison.SharedSkills = append(comparison.SharedSkills, s.Value)
+				}
+			case 1:
+				comparison.Roles[i].UniqueSkills = append(comparison.Roles[i].UniqueSkills, s.Value)
+			}
+		}
+	}
+	return comparison, nil
+}
+
+func (a *Analyzer) breakDownRole(ctx context.Context, q TrendQuery) (RoleBreakdown, error) {
+	breakdown := RoleBreakdown{Role: pkg.RoleSlug(q.Role), Name: q.Role}
+	if role, ok := pkg.ResolveRole(q.Role); ok {
+		breakdown.Name = role.Name
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	var report *TrendReport
+	g.Go(func() error {
+		var err error
+		report, err = a.AnalyzeTrends(ctx, q)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		breakdown.Salary, err = a.SalarySummary(ctx, q)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return RoleBreakdown{}, err
+	}
+
+	breakdown.Total = report.Total
+	breakdown.Skills = report.Dimensions["skills"]
+	breakdown.Countries = report.Dimensions["countries"]
+	breakdown.RemotePolicy = report.Dimensions["remote_policy"]
+	breakdown.Experience = report.Dimensions["experience"]
+	return breakdown, nil
+}
+
+// SalarySummary summarizes the salary ranges of the postings matching q,
+// from the counts of every distinct range start and end.
+func (a *Analyzer) SalarySummary(ctx context.Context, q TrendQuery) (SalarySummary, error) {
+	q = q.withDefaults()
+	jq := q.JobQuery()
+	var total, disclosed int
+	var mins, maxes, currencies []CountResult
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		total, err = a.store.CountJobs(ctx, jq)
+		return err
+	})
+	g.Go(func() (err error) {
+		known := jq
+		known.SalaryKnown = true
+		disclosed, err = a.store.CountJobs(ctx, known)
+		return err
+	})
+	g.Go(func() (err error) {
+		mins, err = a.store.CountBy(ctx, "salaryMin", jq, 0)
+		return err
+	})
+	g.Go(func() (err error) {
+		maxes, err = a.store.CountBy(ctx, "salaryMax", jq, 0)
+		return err
+	})
+	g.Go(func() (err error) {
+		currencies, err = a.store.CountBy(ctx, "salaryCurrency", jq, q.Limit)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return SalarySummary{}, err
+	}
+
+	summary := SalarySummary{Postings: disclosed, Currencies: make([]TrendCount, len(currencies))}
+	lows, highs := sortAmounts(mins), sortAmounts(maxes)
+	summary.Percent = percent(summary.Postings, total)
+	if len(lows) > 0 {
+		summary.Min, summary.MedianMin = lows[0].amount, median(lows)
+	}
+	if len(highs) > 0 {
+		summary.MedianMax, summary.Max = median(highs), highs[len(highs)-1].amount
+	}
+	for i, c := range currencies {
+		summary.Currencies[i] = TrendCount{Value: c.Value, Count: c.Count, Percent: percent(c.Count, summary.Postings)}
+	}
+	return summary, nil
+}
+
+// amountCount is how many postings have an amount.
+type amountCount struct {
+	amount, count int
+}
+
+// sortAmounts parses counted amounts, in ascending order.
+func sortAmounts(counts []CountResult) []amountCount {
+	amounts := make([]amountCount, 0, len(counts))
+	for _, c := range counts {
+		if n, err := strconv.Atoi(c.Value); err == nil {
+			amounts = append(amounts, amountCount{n, c.Count})
+		}
+	}
+	slices.SortFunc(amounts, func(a, b amountCount) int { return a.amount - b.amount })
+	return amounts
+}
+
+func postings(amounts []amountCount) int {
+	n := 0
+	for _, a := range amounts {
+		n += a.count
+	}
+	return n
+}
+
+// median of sorted, non-empty amounts, weighted by their counts.
+func median(amounts []amountCount) int {
+	n := postings(amounts)
+	return (nth(amounts, (n-1)/2) + nth(amounts, n/2)) / 2
+}
+
+// nth returns the amount of the i-th posting in ascending order.
+func nth(amounts []amountCount, i int) int {
+	for _, a := range amounts {
+		if i < a.count {
+			return a.amount
+		}
+		i -= a.count
+	}
+	return amounts[len(amounts)-1].amount
+}
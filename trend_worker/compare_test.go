@@ -0,0 +1,85 @@
+package trend_worker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestCompareRoles(t *testing.T) {
+	now := time.Now()
+	store := seedStore(t,
+		pkg.JobPosting{Title: "data engineer", Company: "Acme", Location: "Berlin, Germany", PostedOn: now,
+			Skills: []string{"python", "spark", "airflow"}, Salary: "$100k - $140k"},
+		pkg.JobPosting{Title: "senior data engineer", Company: "Globex", Location: "Remote, USA", PostedOn: now,
+			Skills: []string{"python", "sql"}, Salary: "$120,000 - $160,000"},
+		pkg.JobPosting{Title: "data engineer", Company: "Initech", Location: "Paris, France", PostedOn: now,
+			Skills: []string{"spark"}},
+		pkg.JobPosting{Title: "machine learning engineer", Company: "Umbrella", Location: "Remote, USA", PostedOn: now,
+			Skills: []string{"python", "pytorch"}, Salary: "$150k - $200k"},
+		pkg.JobPosting{Title: "product designer", Company: "Acme", Location: "Berlin, Germany", PostedOn: now,
+			Skills: []string{"figma"}, Salary: "$90k"},
+	)
+
+	comparison, err := NewAnalyzer(store).CompareRoles(context.Background(), []string{"Data engineer", "ml"}, TrendQuery{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparison.Roles) != 2 || !reflect.DeepEqual(comparison.SharedSkills, []string{"python"}) {
+		t.Fatalf("unexpected comparison: %+v", comparison)
+	}
+
+	data, ml := comparison.Roles[0], comparison.Roles[1]
+	if data.Role != "data-engineer" || data.Total != 3 || ml.Role != "machine-learning-engineer" || ml.Name != "Machine learning engineer" || ml.Total != 1 {
+		t.Errorf("unexpected roles: %s %d, %s %q %d", data.Role, data.Total, ml.Role, ml.Name, ml.Total)
+	}
+	if data.Skills[0] != (TrendCount{Value: "python", Count: 2, Percent: 66.7}) {
+		t.Errorf("unexpected top skill: %+v", data.Skills[0])
+	}
+	if !reflect.DeepEqual(data.UniqueSkills, []string{"spark", "airflow", "sql"}) || !reflect.DeepEqual(ml.UniqueSkills, []string{"pytorch"}) {
+		t.Errorf("unexpected unique skills: %v and %v", data.UniqueSkills, ml.UniqueSkills)
+	}
+	if len(data.Countries) != 3 || len(ml.Countries) != 1 || ml.Countries[0].Value != "United States" {
+		t.Errorf("unexpected countries: %+v and %+v", data.Countries, ml.Countries)
+	}
+
+	want := SalarySummary{Postings: 2, Percent: 66.7, Min: 100000, MedianMin: 110000, MedianMax: 150000, Max: 160000,
+		Currencies: []TrendCount{{Value: "USD", Count: 2, Percent: 100}}}
+	if !reflect.DeepEqual(data.Salary, want) {
+		t.Errorf("unexpected salary summary:\n got %+v\nwant %+v", data.Salary, want)
+	}
+	if ml.Salary.Postings != 1 || ml.Salary.MedianMin != 150000 || ml.Salary.Max != 200000 {
+		t.Errorf("unexpected salary summary: %+v", ml.Salary)
+	}
+
+	for _, roles := range [][]string{{"backend"}, {"backend", "Backend developer"}} {
+		if _, err := NewAnalyzer(store).CompareRoles(context.Background(), roles, TrendQuery{}); !errors.Is(err, ErrInvalidTrendQuery) {
+			t.Errorf("%v: expected ErrInvalidTrendQuery, got %v", roles, err)
+		}
+	}
+}
+
+func TestSalarySummary(t *testing.T) {
+	now := time.Now()
+	store := seedStore(t,
+		pkg.JobPosting{Title: "backend engineer", Company: "Acme", PostedOn: now, Salary: "$100k - $140k"},
+		pkg.JobPosting{Title: "backend engineer", Company: "Globex", PostedOn: now, Salary: "up to $120k"},
+		pkg.JobPosting{Title: "backend engineer", Company: "Initech", PostedOn: now, Salary: "$150k+"},
+		pkg.JobPosting{Title: "backend engineer", Company: "Umbrella", PostedOn: now},
+	)
+
+	summary, err := NewAnalyzer(store).SalarySummary(context.Background(), TrendQuery{Role: "backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The max-only and min-only ranges both count as disclosed
+	want := SalarySummary{Postings: 3, Percent: 75, Min: 100000, MedianMin: 125000, MedianMax: 130000, Max: 140000,
+		Currencies: []TrendCount{{Value: "USD", Count: 3, Percent: 100}}}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("unexpected salary summary:\n got %+v\nwant %+v", summary, want)
+	}
+}
@@ -0,0 +1,134 @@
+package trend_worker
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats of WriteReport.
+const (
+	FormatTable    = "table"
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// Formats lists the output formats of WriteReport.
+var Formats = []string{FormatTable, FormatJSON, FormatCSV, FormatMarkdown}
+
+// CheckFormat returns an error unless format is one of Formats.
+func CheckFormat(format string) error {
+	for _, f := range Formats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Section is one dimension of a trend report.
+type Section struct {
+	Name   string // key of Dimensions
+	Title  string
+	Values []TrendCount
+}
+
+var dimensionTitles = map[string]string{
+	"skills":          "Skills",
+	"roles":           "Roles",
+	"locations":       "Locations",
+	"countries":       "Countries",
+	"regions":         "Regions",
+	"remote_policy":   "Remote policy",
+	"companies":       "Companies",
+	"sources":         "Sources",
+	"experience":      "Experience",
+	"seniority":       "Seniority",
+	"employment_type": "Employment type",
+	"timezones":       "Timezones",
+}
+
+// Sections returns the dimensions of the report in the order of its query.
+func (r *TrendReport) Sections() []Section {
+	sections := make([]Section, 0, len(r.Query.Dimensions))
+	for _, dim := range r.Query.Dimensions {
+		title := dimensionTitles[dim]
+		if title == "" {
+			title = dim
+		}
+		sections = append(sections, Section{Name: dim, Title: title, Values: r.Dimensions[dim]})
+	}
+	return sections
+}
+
+// WriteReport writes report to w in format: an aligned table per dimension,
+// the JSON of the API, CSV rows of dimension, value, count and percent, or a
+// Markdown table per dimension.
+func WriteReport(w io.Writer, report *TrendReport, format string) error {
+	switch format {
+	case FormatTable:
+		return writeTable(w, report)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case FormatCSV:
+		return writeCSV(w, report)
+	case FormatMarkdown:
+		return writeMarkdown(w, report)
+	}
+	return CheckFormat(format)
+}
+
+func writeTable(w io.Writer, report *TrendReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d postings\n", report.Total)
+	for _, section := range report.Sections() {
+		fmt.Fprintf(tw, "\n%s\tCOUNT\tPERCENT\n", strings.ToUpper(section.Title))
+		if len(section.Values) == 0 {
+			fmt.Fprintln(tw, "(none)\t\t")
+		}
+		for _, c := range section.Values {
+			fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", c.Value, c.Count, c.Percent)
+		}
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, report *TrendReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"dimension", "value", "count", "percent"})
+	for _, section := range report.Sections() {
+		for _, c := range section.Values {
+			cw.Write([]string{section.Name, c.Value, strconv.Itoa(c.Count), strconv.FormatFloat(c.Percent, 'f', 1, 64)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// markdownCell escapes what would break a Markdown table cell.
+var markdownCell = strings.NewReplacer("|", `\|`, "\n", " ", "\r", "")
+
+func writeMarkdown(w io.Writer, report *TrendReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d postings\n", report.Total)
+	for _, section := range report.Sections() {
+		fmt.Fprintf(&b, "\n## %s\n\n", section.Title)
+		if len(section.Values) == 0 {
+			b.WriteString("_No postings._\n")
+			continue
+		}
+		b.WriteString("| Value | Count | Percent |\n| --- | ---: | ---: |\n")
+		for _, c := range section.Values {
+			fmt.Fprintf(&b, "| %s | %d | %.1f%% |\n", markdownCell.Replace(c.Value), c.Count, c.Percent)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
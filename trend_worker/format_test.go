@@ -0,0 +1,62 @@
+package trend_worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testReport() *TrendReport {
+	return &TrendReport{
+		Query: TrendQuery{Limit: 20, Dimensions: []string{"skills", "companies", "seniority"}},
+		Total: 16,
+		Dimensions: map[string][]TrendCount{
+			"skills":    {{Value: "go", Count: 12, Percent: 75}, {Value: "aws", Count: 3, Percent: 18.8}},
+			"companies": {{Value: "Acme | Co", Count: 2, Percent: 12.5}},
+			"seniority": {},
+		},
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteReport(&b, testReport(), FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "dimension,value,count,percent\nskills,go,12,75.0\nskills,aws,3,18.8\ncompanies,Acme | Co,2,12.5\n"
+	if b.String() != want {
+		t.Errorf("unexpected CSV:\n%s", b.String())
+	}
+
+	b.Reset()
+	if err := WriteReport(&b, testReport(), FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"16 postings\n\n## Skills\n\n| Value | Count | Percent |\n| --- | ---: | ---: |\n| go | 12 | 75.0% |\n", `| Acme \| Co | 2 | 12.5% |`, "## Seniority\n\n_No postings._\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected %q in Markdown:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	if err := WriteReport(&b, testReport(), FormatTable); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "SKILLS  COUNT  PERCENT\ngo      12     75.0%\n") {
+		t.Errorf("unexpected table:\n%s", b.String())
+	}
+
+	b.Reset()
+	if err := WriteReport(&b, testReport(), FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded TrendReport
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil || decoded.Total != 16 || len(decoded.Dimensions["skills"]) != 2 {
+		t.Errorf("unexpected JSON: %s (%v)", b.String(), err)
+	}
+
+	if err := WriteReport(&b, testReport(), "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,190 @@
+package trend_worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// VelocityWindow is the length of each of the two windows posting velocity
+// compares.
+const VelocityWindow = 30 * 24 * time.Hour
+
+// TimelineMonths is how many months the timeline covers when the query has
+// no since.
+const TimelineMonths = 12
+
+// insightDimensions are the dimensions reported for a company.
+var insightDimensions = []string{"roles", "skills", "locations", "countries", "remote_policy"}
+
+// CompanyInsights is the hiring activity of one company.
+type CompanyInsights struct {
+	Query        TrendQuery      `json:"query"`
+	Total        int             `json:"total"` // postings matching the query
+	Open         int             `json:"open"`  // postings open now, whenever they were posted
+	Timeline     []OpenPeriod    `json:"timeline"`
+	Roles        []TrendCount    `json:"roles"`
+	Skills       []TrendCount    `json:"skills"`
+	Locations    []TrendCount    `json:"locations"`
+	Countries    []TrendCount    `json:"countries"`
+	RemotePolicy []TrendCount    `json:"remote_policy"`
+	Salary       SalarySummary   `json:"salary"` // its percent is the salary disclosure rate
+	Velocity     PostingVelocity `json:"velocity"`
+}
+
+// OpenPeriod is a month of a company's hiring.
+type OpenPeriod struct {
+	Period string `json:"period"` // YYYY-MM
+	Posted int    `json:"posted"`
+	Closed int    `json:"closed"`
+	Open   int    `json:"open"` // at the end of the month, or now for the current one
+}
+
+// PostingVelocity compares how fast a company posted over the last
+// VelocityWindow with the window before it.
+type PostingVelocity struct {
+	CurrentWindow  TimeWindow `json:"current_window"`
+	PreviousWindow TimeWindow `json:"previous_window"`
+	PerWeek        float64    `json:"per_week"`       // postings per 7 days of the current window
+	ChangePercent  float64    `json:"change_percent"` // of the current window over the previous one
+}
+
+// CompanyInsights reports the hiring of the company q selects: what it
+// posted and asks for within q's window, how many of its postings were open
+// month by month since q.Since, or over the last TimelineMonths, and its
+// posting velocity up to q.Until or now. q's dimensions are ignored.
+func (a *Analyzer) CompanyInsights(ctx context.Context, q TrendQuery, now time.Time) (*CompanyInsights, error) {
+	if q.Company == "" {
+		return nil, fmt.Errorf("%w: no company", ErrInvalidTrendQuery)
+	}
+	q.Dimensions = insightDimensions
+	q = q.withDefaults()
+
+	end := now
+	if !q.Until.IsZero() && q.Until.Before(now) {
+		end = q.Until
+	}
+	start := q.Since
+	if start.IsZero() {
+		start = startOfMonth(end).AddDate(0, 1-TimelineMonths, 0)
+	}
+	// Open postings and the timeline look past the window; the timeline only
+	// at the postings that were still open when it starts
+	all := q.JobQuery()
+	all.PostedSince, all.PostedUntil = time.Time{}, time.Time{}
+	timeline := all
+	timeline.PostedUntil, timeline.OpenAt = end, start
+
+	insights := &CompanyInsights{Query: q}
+	velocity := &insights.Velocity
+	velocity.CurrentWindow = TimeWindow{Since: end.Add(-VelocityWindow), Until: end}
+	velocity.PreviousWindow = TimeWindow{Since: end.Add(-2 * VelocityWindow), Until: end.Add(-VelocityWindow)}
+
+	var report *TrendReport
+	var jobs []pkg.JobPosting
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		report, err = a.AnalyzeTrends(ctx, q)
+		return err
+	})
+	g.Go(func() (err error) {
+		insights.Salary, err = a.SalarySummary(ctx, q)
+		return err
+	})
+	g.Go(func() (err error) {
+		open := all
+		open.OpenOnly = true
+		insights.Open, err = a.store.CountJobs(ctx, open)
+		return err
+	})
+	g.Go(func() (err error) {
+		jobs, err = a.store.QueryJobs(ctx, timeline)
+		return err
+	})
+	for _, w := range []*TimeWindow{&velocity.CurrentWindow, &velocity.PreviousWindow} {
+		g.Go(func() (err error) {
+			wq := all
+			wq.PostedSince, wq.PostedUntil = w.Since, w.Until
+			w.Postings, err = a.store.CountJobs(ctx, wq)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	insights.Total = report.Total
+	insights.Roles = report.Dimensions["roles"]
+	insights.Skills = report.Dimensions["skills"]
+	insights.Locations = report.Dimensions["locations"]
+	insights.Countries = report.Dimensions["countries"]
+	insights.RemotePolicy = report.Dimensions["remote_policy"]
+	insights.Timeline = openTimeline(jobs, start, end)
+
+	current, previous := velocity.CurrentWindow.Postings, velocity.PreviousWindow.Postings
+	velocity.PerWeek = round1(float64(current) * float64(7*24*time.Hour) / float64(VelocityWindow))
+	velocity.ChangePercent = percent(current-previous, max(previous, 1))
+	return insights, nil
+}
+
+// openTimeline buckets jobs by month from the first posting, or since if
+// later, to end: how many were posted and closed in each month and how many
+// were open at its end.
+func openTimeline(jobs []pkg.JobPosting, since, end time.Time) []OpenPeriod {
+	timeline := []OpenPeriod{}
+	var first time.Time
+	for _, job := range jobs {
+		if posted := postedAt(job); first.IsZero() || posted.Before(first) {
+			first = posted
+		}
+	}
+	if first.IsZero() {
+		return timeline
+	}
+	if since.After(first) {
+		first = since
+	}
+
+	for month := startOfMonth(first); month.Before(end); month = month.AddDate(0, 1, 0) {
+		next := month.AddDate(0, 1, 0)
+		if next.After(end) {
+			next = end
+		}
+		period := OpenPeriod{Period: month.Format("2006-01")}
+		for _, job := range jobs {
+			posted := postedAt(job)
+			if !posted.Before(next) {
+				continue
+			}
+			if !posted.Before(month) {
+				period.Posted++
+			}
+			switch {
+			case job.ClosedAt == nil || !job.ClosedAt.Before(next):
+				period.Open++
+			case !job.ClosedAt.Before(month):
+				period.Closed++
+			}
+		}
+		timeline = append(timeline, period)
+	}
+	return timeline
+}
+
+// postedAt falls back to when a posting was first stored if it has no posted
+// date.
+func postedAt(job pkg.JobPosting) time.Time {
+	if job.PostedOn.IsZero() {
+		return job.CreatedAt
+	}
+	return job.PostedOn
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
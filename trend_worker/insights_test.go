@@ -0,0 +1,102 @@
+package trend_worker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestCompanyInsights(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC) }
+
+	store := seedStore(t,
+		pkg.JobPosting{Title: "backend engineer", Company: "Acme", Location: "Berlin, Germany", PostedOn: day(1, 10),
+			Description: "payments", URL: "https://example.com/jobs/a", Skills: []string{"go", "postgres"}, Salary: "$100k - $140k"},
+		pkg.JobPosting{Title: "data engineer", Company: "Acme", Location: "Remote, USA", PostedOn: day(2, 20),
+			Description: "warehouse", URL: "https://example.com/jobs/b", Skills: []string{"python", "sql"}},
+		pkg.JobPosting{Title: "backend engineer", Company: "Acme", Location: "Berlin, Germany", PostedOn: day(3, 1),
+			Description: "billing", URL: "https://example.com/jobs/c", Skills: []string{"go"}, Salary: "$120k"},
+		pkg.JobPosting{Title: "backend engineer", Company: "Acme", Location: "Paris, France", PostedOn: day(3, 10),
+			Description: "platform", URL: "https://example.com/jobs/d", Skills: []string{"go", "kubernetes"}},
+		pkg.JobPosting{Title: "backend engineer", Company: "Globex", Location: "Paris, France", PostedOn: day(3, 5),
+			Description: "search", URL: "https://example.com/jobs/e", Skills: []string{"java"}},
+	)
+	if _, err := store.CloseJobs(ctx, []string{"https://example.com/jobs/a"}, pkg.ClosedGone, day(2, 5)); err != nil {
+		t.Fatal(err)
+	}
+	acme, err := store.GetCompany(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insights, err := NewAnalyzer(store).CompanyInsights(ctx, TrendQuery{Company: acme.ID}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insights.Total != 4 || insights.Open != 3 {
+		t.Errorf("expected 4 postings, 3 open, got %d, %d", insights.Total, insights.Open)
+	}
+	timeline := []OpenPeriod{
+		{Period: "2026-01", Posted: 1, Open: 1},
+		{Period: "2026-02", Posted: 1, Closed: 1, Open: 1},
+		{Period: "2026-03", Posted: 2, Open: 3},
+	}
+	if !reflect.DeepEqual(insights.Timeline, timeline) {
+		t.Errorf("unexpected timeline: %+v", insights.Timeline)
+	}
+	if roles := insights.Roles; len(roles) != 2 || roles[0] != (TrendCount{Value: "backend", Count: 3, Percent: 75}) {
+		t.Errorf("unexpected roles: %+v", roles)
+	}
+	if skills := insights.Skills; len(skills) != 5 || skills[0] != (TrendCount{Value: "go", Count: 3, Percent: 75}) {
+		t.Errorf("unexpected skills: %+v", skills)
+	}
+	if countries := insights.Countries; len(countries) != 3 || countries[0].Value != "Germany" || countries[0].Count != 2 {
+		t.Errorf("unexpected countries: %+v", countries)
+	}
+	if insights.Salary.Postings != 2 || insights.Salary.Percent != 50 {
+		t.Errorf("unexpected salary disclosure: %+v", insights.Salary)
+	}
+	velocity := insights.Velocity
+	if velocity.CurrentWindow.Postings != 3 || velocity.PreviousWindow.Postings != 0 || velocity.PerWeek != 0.7 || velocity.ChangePercent != 300 {
+		t.Errorf("unexpected velocity: %+v", velocity)
+	}
+
+	// The window narrows the counts and the timeline, but not what is open now
+	since, err := NewAnalyzer(store).CompanyInsights(ctx, TrendQuery{Company: acme.ID, Since: day(2, 1)}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since.Total != 3 || since.Open != 3 || len(since.Timeline) != 2 || since.Timeline[0] != timeline[1] {
+		t.Errorf("unexpected windowed insights: %d postings, %d open, timeline %+v", since.Total, since.Open, since.Timeline)
+	}
+
+	// Postings closed before the window started are left out
+	march, err := NewAnalyzer(store).CompanyInsights(ctx, TrendQuery{Company: acme.ID, Since: day(3, 1)}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(march.Timeline) != 1 || march.Timeline[0] != timeline[2] {
+		t.Errorf("unexpected timeline since March: %+v", march.Timeline)
+	}
+
+	// Without a window the timeline covers the last TimelineMonths
+	old := seedStore(t, pkg.JobPosting{Title: "backend engineer", Company: "Acme", Location: "Berlin, Germany",
+		PostedOn: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Description: "legacy", URL: "https://example.com/jobs/f"})
+	long, err := NewAnalyzer(old).CompanyInsights(ctx, TrendQuery{Company: acme.ID}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(long.Timeline) != TimelineMonths || long.Timeline[0] != (OpenPeriod{Period: "2025-04", Open: 1}) {
+		t.Errorf("unexpected timeline: %+v", long.Timeline)
+	}
+
+	if _, err := NewAnalyzer(store).CompanyInsights(ctx, TrendQuery{}, now); !errors.Is(err, ErrInvalidTrendQuery) {
+		t.Errorf("expected ErrInvalidTrendQuery without a company, got %v", err)
+	}
+}
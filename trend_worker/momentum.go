@@ -0,0 +1,174 @@
+package trend_worker
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// MomentumOptions controls how skill momentum is measured.
+type MomentumOptions struct {
+	Window     time.Duration // length of each of the two compared windows
+	MinSupport int           // minimum postings a skill needs in either window to be ranked
+	Limit      int           // maximum entries per list; 0 means no limit
+	Now        time.Time     // end of the current window; zero means time.Now()
+}
+
+// DefaultMomentumOptions compares the last 14 days against the 14 days before.
+func DefaultMomentumOptions() MomentumOptions {
+	return MomentumOptions{
+		Window:     14 * 24 * time.Hour,
+		MinSupport: 3,
+		Limit:      20,
+	}
+}
+
+type TimeWindow struct {
+	Since    time.Time `json:"since"`
+	Until    time.Time `json:"until"`
+	Postings int       `json:"postings"`
+}
+
+type SkillMomentum struct {
+	Skill          string  `json:"skill"`
+	Current        int     `json:"current"`
+	Previous       int     `json:"previous"`
+	AbsoluteChange int     `json:"absolute_change"`
+	RelativeChange float64 `json:"relative_change"` // (current - previous) / max(previous, 1)
+	CurrentShare   float64 `json:"current_share"`   // fraction of current-window postings mentioning the skill
+	PreviousShare  float64 `json:"previous_share"`
+}
+
+type MomentumReport struct {
+	Role           string          `json:"role"`
+	CurrentWindow  TimeWindow      `json:"current_window"`
+	PreviousWindow TimeWindow      `json:"previous_window"`
+	Emerging       []SkillMomentum `json:"emerging"`
+	Declining      []SkillMomentum `json:"declining"`
+}
+
+// AnalyzeSkillMomentum ranks skills for a role by how much their posting counts
+// moved between the current window and the window immediately before it,
+// bucketing postings by postedOn.
+func (a *Analyzer) AnalyzeSkillMomentum(ctx context.Context, role string, opts MomentumOptions) (*MomentumReport, error) {
+	if opts.Window <= 0 {
+		opts.Window = DefaultMomentumOptions().Window
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	current := TimeWindow{Since: opts.Now.Add(-opts.Window), Until: opts.Now}
+	previous := TimeWindow{Since: current.Since.Add(-opts.Window), Until: current.Since}
+
+	currentCounts, err := a.windowSkillCounts(ctx, role, &current)
+	if err != nil {
+		return nil, err
+	}
+	previousCounts, err := a.windowSkillCounts(ctx, role, &previous)
+	if err != nil {
+		return nil, err
+	}
+
+	emerging, declining := rankMomentum(currentCounts, previousCounts, current.Postings, previous.Postings, opts.MinSupport, opts.Limit)
+
+	return &MomentumReport{
+		Role:           role,
+		CurrentWindow:  current,
+		PreviousWindow: previous,
+		Emerging:       emerging,
+		Declining:      declining,
+	}, nil
+}
+
+// windowSkillCounts counts skills for postings inside w and records the window's posting total.
+func (a *Analyzer) windowSkillCounts(ctx context.Context, role string, w *TimeWindow) ([]CountResult, error) {
+	q := pkg.JobQuery{Role: role, PostedSince: w.Since, PostedUntil: w.Until}
+
+	total, err := a.store.CountJobs(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	w.Postings = total
+
+	return a.store.CountBy(ctx, "skills", q, 0)
+}
+
+// rankMomentum compares per-skill counts of two windows. Skills seen fewer than
+// minSupport times in both windows are ignored. Emerging skills are sorted by
+// largest relative gain, declining skills by largest relative loss.
+func rankMomentum(current, previous []CountResult, currentTotal, previousTotal, minSupport, limit int) (emerging, declining []SkillMomentum) {
+	counts := make(map[string]*SkillMomentum)
+	get := func(skill string) *SkillMomentum {
+		m, ok := counts[skill]
+		if !ok {
+			m = &SkillMomentum{Skill: skill}
+			counts[skill] = m
+		}
+		return m
+	}
+	for _, c := range current {
+		get(c.Value).Current += c.Count
+	}
+	for _, c := range previous {
+		get(c.Value).Previous += c.Count
+	}
+
+	for _, m := range counts {
+		if m.Current < minSupport && m.Previous < minSupport {
+			continue
+		}
+		m.AbsoluteChange = m.Current - m.Previous
+		m.RelativeChange = float64(m.AbsoluteChange) / math.Max(float64(m.Previous), 1)
+		m.CurrentShare = share(m.Current, currentTotal)
+		m.PreviousShare = share(m.Previous, previousTotal)
+
+		switch {
+		case m.AbsoluteChange > 0:
+			emerging = append(emerging, *m)
+		case m.AbsoluteChange < 0:
+			declining = append(declining, *m)
+		}
+	}
+
+	sort.Slice(emerging, func(i, j int) bool {
+		a, b := emerging[i], emerging[j]
+		if a.RelativeChange != b.RelativeChange {
+			return a.RelativeChange > b.RelativeChange
+		}
+		if a.AbsoluteChange != b.AbsoluteChange {
+			return a.AbsoluteChange > b.AbsoluteChange
+		}
+		return a.Skill < b.Skill
+	})
+	sort.Slice(declining, func(i, j int) bool {
+		a, b := declining[i], declining[j]
+		if a.RelativeChange != b.RelativeChange {
+			return a.RelativeChange < b.RelativeChange
+		}
+		if a.AbsoluteChange != b.AbsoluteChange {
+			return a.AbsoluteChange < b.AbsoluteChange
+		}
+		return a.Skill < b.Skill
+	})
+
+	if limit > 0 {
+		if len(emerging) > limit {
+			emerging = emerging[:limit]
+		}
+		if len(declining) > limit {
+			declining = declining[:limit]
+		}
+	}
+	return emerging, declining
+}
+
+func share(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
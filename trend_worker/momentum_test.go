@@ -0,0 +1,60 @@
+package trend_worker
+
+import "testing"
+
+func TestRankMomentum(t *testing.T) {
+	current := []CountResult{
+		{Value: "go", Count: 12},
+		{Value: "rust", Count: 6},
+		{Value: "java", Count: 3},
+		{Value: "cobol", Count: 1},
+	}
+	previous := []CountResult{
+		{Value: "go", Count: 6},
+		{Value: "java", Count: 9},
+		{Value: "perl", Count: 4},
+		{Value: "cobol", Count: 2},
+	}
+
+	emerging, declining := rankMomentum(current, previous, 20, 20, 3, 0)
+
+	if len(emerging) != 2 {
+		t.Fatalf("expected 2 emerging skills, got %+v", emerging)
+	}
+	// rust is new (6 vs 0 => +6.0) and beats go (12 vs 6 => +1.0)
+	if emerging[0].Skill != "rust" || emerging[1].Skill != "go" {
+		t.Errorf("unexpected emerging order: %+v", emerging)
+	}
+	if emerging[1].AbsoluteChange != 6 || emerging[1].RelativeChange != 1 {
+		t.Errorf("unexpected go momentum: %+v", emerging[1])
+	}
+	if emerging[1].CurrentShare != 0.6 {
+		t.Errorf("expected go current share 0.6, got %v", emerging[1].CurrentShare)
+	}
+
+	if len(declining) != 2 {
+		t.Fatalf("expected 2 declining skills, got %+v", declining)
+	}
+	// perl vanished (-1.0) and java fell from 9 to 3 (-0.67)
+	if declining[0].Skill != "perl" || declining[1].Skill != "java" {
+		t.Errorf("unexpected declining order: %+v", declining)
+	}
+
+	for _, m := range append(emerging, declining...) {
+		if m.Skill == "cobol" {
+			t.Errorf("cobol is below min support and should be ignored")
+		}
+	}
+}
+
+func TestRankMomentumLimit(t *testing.T) {
+	current := []CountResult{{Value: "a", Count: 5}, {Value: "b", Count: 4}, {Value: "c", Count: 3}}
+
+	emerging, declining := rankMomentum(current, nil, 10, 0, 1, 2)
+	if len(emerging) != 2 || len(declining) != 0 {
+		t.Fatalf("expected 2 emerging and no declining, got %+v / %+v", emerging, declining)
+	}
+	if emerging[0].Skill != "a" {
+		t.Errorf("expected a first, got %s", emerging[0].Skill)
+	}
+}
@@ -0,0 +1,161 @@
+package trend_worker
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// ErrInvalidTrendQuery is wrapped by the errors of ParseTrendQuery.
+var ErrInvalidTrendQuery = errors.New("invalid trend query")
+
+// Dimensions are what a trend report can break postings down by, mapped to
+// the store field counted (see pkg.CountableFields).
+var Dimensions = map[string]string{
+	"skills":          "skills",
+	"roles":           "roles",
+	"locations":       "locations.name",
+	"countries":       "locations.country",
+	"regions":         "locations.region",
+	"remote_policy":   "remotePolicy",
+	"companies":       "company",
+	"sources":         "source",
+	"experience":      "experience",
+	"seniority":       "seniority",
+	"employment_type": "employmentType",
+	"timezones":       "timezones",
+}
+
+// DefaultDimensions are reported when a query names none.
+var DefaultDimensions = []string{
+	"skills", "locations", "countries", "remote_policy",
+	"companies", "experience", "seniority", "employment_type",
+}
+
+// Bounds of TrendQuery.Limit.
+const (
+	DefaultTopN = 20
+	MaxTopN     = 100
+)
+
+// TrendQuery selects the postings of a trend report and how to break them
+// down.
+type TrendQuery struct {
+	Role       string    `json:"role,omitempty"`
+	Company    string    `json:"company,omitempty"` // company ID, see pkg.Company
+	Source     string    `json:"source,omitempty"`
+	Location   string    `json:"location,omitempty"` // city, region, country or country code
+	Since      time.Time `json:"since,omitzero"`     // posted on or after
+	Until      time.Time `json:"until,omitzero"`     // posted before
+	Limit      int       `json:"limit"`              // values per dimension; 0 means DefaultTopN
+	Dimensions []string  `json:"dimensions"`         // keys of Dimensions; empty means DefaultDimensions
+}
+
+func (q TrendQuery) withDefaults() TrendQuery {
+	if q.Limit <= 0 {
+		q.Limit = DefaultTopN
+	}
+	if len(q.Dimensions) == 0 {
+		q.Dimensions = DefaultDimensions
+	}
+	return q
+}
+
+// JobQuery returns the postings q reports on.
+func (q TrendQuery) JobQuery() pkg.JobQuery {
+	return pkg.JobQuery{
+		Role:        q.Role,
+		CompanyID:   q.Company,
+		Source:      q.Source,
+		Location:    q.Location,
+		PostedSince: q.Since,
+		PostedUntil: q.Until,
+	}
+}
+
+// ParseTrendQuery reads a trend query from the parameters role, source,
+// location, since, until (see ParseTime), limit and dimensions (comma
+// separated, or repeated).
+func ParseTrendQuery(params url.Values, now time.Time) (TrendQuery, error) {
+	q := TrendQuery{
+		Role:     strings.TrimSpace(params.Get("role")),
+		Source:   strings.TrimSpace(params.Get("source")),
+		Location: strings.TrimSpace(params.Get("location")),
+	}
+
+	var err error
+	if q.Since, err = ParseTime(params.Get("since"), now); err != nil {
+		return TrendQuery{}, fmt.Errorf("%w: since %v", ErrInvalidTrendQuery, err)
+	}
+	if q.Until, err = ParseTime(params.Get("until"), now); err != nil {
+		return TrendQuery{}, fmt.Errorf("%w: until %v", ErrInvalidTrendQuery, err)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return TrendQuery{}, fmt.Errorf("%w: since must be before until", ErrInvalidTrendQuery)
+	}
+
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > MaxTopN {
+			return TrendQuery{}, fmt.Errorf("%w: limit must be an integer from 1 to %d", ErrInvalidTrendQuery, MaxTopN)
+		}
+		q.Limit = n
+	}
+
+	seen := map[string]bool{}
+	for _, v := range params["dimensions"] {
+		for _, dim := range strings.Split(v, ",") {
+			dim = strings.TrimSpace(dim)
+			if dim == "" || seen[dim] {
+				continue
+			}
+			if _, ok := Dimensions[dim]; !ok {
+				return TrendQuery{}, fmt.Errorf("%w: unknown dimension %q, expected some of %s",
+					ErrInvalidTrendQuery, dim, strings.Join(DimensionNames(), ", "))
+			}
+			seen[dim] = true
+			q.Dimensions = append(q.Dimensions, dim)
+		}
+	}
+	return q.withDefaults(), nil
+}
+
+// DimensionNames returns the keys of Dimensions, sorted.
+func DimensionNames() []string {
+	names := make([]string, 0, len(Dimensions))
+	for name := range Dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseTime reads a time bound: a date (2006-01-02), an RFC 3339 timestamp,
+// or an age before now such as 30d or 12h. Empty gives the zero time.
+func ParseTime(v string, now time.Time) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, errors.New("must be a date (2006-01-02), an RFC 3339 timestamp or an age such as 30d or 12h")
+}
@@ -0,0 +1,146 @@
+package trend_worker
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+// TimeToFillOptions controls the time-to-fill report.
+type TimeToFillOptions struct {
+	CompanyID string        // restrict to one company; empty means all
+	Window    time.Duration // only postings closed within this long before Now
+	MinClosed int           // minimum closed postings for a company to be listed
+	Limit     int           // maximum companies listed; 0 means no limit
+	Now       time.Time     // zero means time.Now()
+}
+
+func DefaultTimeToFillOptions() TimeToFillOptions {
+	return TimeToFillOptions{
+		Window:    pkg.JobRetention,
+		MinClosed: 2,
+		Limit:     20,
+	}
+}
+
+// DurationStats summarizes how many days postings stayed open.
+type DurationStats struct {
+	Closed     int     `json:"closed"`
+	MeanDays   float64 `json:"mean_days"`
+	MedianDays float64 `json:"median_days"`
+	P90Days    float64 `json:"p90_days"`
+}
+
+type CompanyTimeToFill struct {
+	Company   string `json:"company"`
+	CompanyID string `json:"company_id"`
+	DurationStats
+}
+
+type TimeToFillReport struct {
+	Role      string              `json:"role"`
+	Since     time.Time           `json:"since"`
+	StillOpen int                 `json:"still_open"` // postings not closed yet, excluded from the stats
+	Overall   DurationStats       `json:"overall"`
+	Companies []CompanyTimeToFill `json:"companies"` // slowest to fill first
+}
+
+// AnalyzeTimeToFill reports how long a role's postings stayed open, from
+// postedOn to closedAt, overall and per company.
+func (a *Analyzer) AnalyzeTimeToFill(ctx context.Context, role string, opts TimeToFillOptions) (*TimeToFillReport, error) {
+	if opts.Window <= 0 {
+		opts.Window = DefaultTimeToFillOptions().Window
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	since := opts.Now.Add(-opts.Window)
+
+	stillOpen, err := a.store.CountJobs(ctx, pkg.JobQuery{Role: role, CompanyID: opts.CompanyID, OpenOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	closed, err := a.store.QueryJobs(ctx, pkg.JobQuery{Role: role, CompanyID: opts.CompanyID, Status: pkg.StatusClosed, ClosedSince: since})
+	if err != nil {
+		return nil, err
+	}
+
+	var all []float64
+	byCompany := make(map[string][]float64)
+	names := make(map[string]string)
+	for _, job := range closed {
+		days := openDays(job)
+		all = append(all, days)
+		byCompany[job.CompanyID] = append(byCompany[job.CompanyID], days)
+		names[job.CompanyID] = job.Company
+	}
+
+	companies := make([]CompanyTimeToFill, 0, len(byCompany))
+	for id, days := range byCompany {
+		if len(days) < opts.MinClosed {
+			continue
+		}
+		companies = append(companies, CompanyTimeToFill{Company: names[id], CompanyID: id, DurationStats: durationStats(days)})
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		if companies[i].MedianDays != companies[j].MedianDays {
+			return companies[i].MedianDays > companies[j].MedianDays
+		}
+		return companies[i].CompanyID < companies[j].CompanyID
+	})
+	if opts.Limit > 0 && len(companies) > opts.Limit {
+		companies = companies[:opts.Limit]
+	}
+
+	return &TimeToFillReport{
+		Role:      role,
+		Since:     since,
+		StillOpen: stillOpen,
+		Overall:   durationStats(all),
+		Companies: companies,
+	}, nil
+}
+
+// openDays is how long a closed posting was up. Postings without a posted date
+// count from when they were first stored.
+func openDays(job pkg.JobPosting) float64 {
+	d := job.ClosedAt.Sub(postedAt(job))
+	if d < 0 {
+		d = 0
+	}
+	return d.Hours() / 24
+}
+
+func durationStats(days []float64) DurationStats {
+	if len(days) == 0 {
+		return DurationStats{}
+	}
+	sorted := append([]float64(nil), days...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, d := range sorted {
+		sum += d
+	}
+	return DurationStats{
+		Closed:     len(sorted),
+		MeanDays:   round1(sum / float64(len(sorted))),
+		MedianDays: round1(percentile(sorted, 0.5)),
+		P90Days:    round1(percentile(sorted, 0.9)),
+	}
+}
+
+// percentile interpolates linearly between the closest ranks of sorted.
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,60 @@
+package trend_worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vx6fid/job-crawler/pkg"
+)
+
+func TestAnalyzeTimeToFill(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	posted := now.AddDate(0, 0, -40)
+
+	var jobs []pkg.JobPosting
+	for i, company := range []string{"Acme", "Acme", "Acme", "Globex", "Initech"} {
+		jobs = append(jobs, pkg.JobPosting{
+			Title: "backend engineer", Company: company, PostedOn: posted.AddDate(0, 0, i),
+			Description: "posting " + string(rune('a'+i)), URL: "https://example.com/jobs/" + string(rune('a'+i)),
+		})
+	}
+	store := seedStore(t, jobs...)
+
+	// Acme fills in 10, 20 and 30 days, Globex in 5; Initech is still open
+	closeAfter := map[string]int{"a": 10, "b": 19, "c": 28, "d": 2}
+	for suffix, days := range closeAfter {
+		at := posted.AddDate(0, 0, days+int(suffix[0]-'a'))
+		if _, err := store.CloseJobs(ctx, []string{"https://example.com/jobs/" + suffix}, pkg.ClosedGone, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := DefaultTimeToFillOptions()
+	opts.Now = now
+	report, err := NewAnalyzer(store).AnalyzeTimeToFill(ctx, "backend", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.StillOpen != 1 {
+		t.Errorf("expected 1 open posting, got %d", report.StillOpen)
+	}
+	if report.Overall.Closed != 4 || report.Overall.MedianDays != 14.5 {
+		t.Errorf("unexpected overall stats: %+v", report.Overall)
+	}
+	if len(report.Companies) != 1 || report.Companies[0].CompanyID != "acme" || report.Companies[0].MedianDays != 19 {
+		t.Errorf("expected only Acme to meet min_closed, got %+v", report.Companies)
+	}
+}
+
+func TestDurationStats(t *testing.T) {
+	stats := durationStats([]float64{30, 10, 20, 40})
+	if stats != (DurationStats{Closed: 4, MeanDays: 25, MedianDays: 25, P90Days: 37}) {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if durationStats(nil) != (DurationStats{}) {
+		t.Error("expected zero stats for no postings")
+	}
+}